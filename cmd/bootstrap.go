@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/bwise1/your_care_api/config"
+	deps "github.com/bwise1/your_care_api/internal/debs"
+	api "github.com/bwise1/your_care_api/internal/http/rest"
+	"github.com/bwise1/your_care_api/internal/model"
+)
+
+const bootstrapTimeout = 30 * time.Second
+
+// bootstrapPasswordEnv holds the password for the first admin so it never
+// shows up in shell history or the process list.
+const bootstrapPasswordEnv = "BOOTSTRAP_ADMIN_PASSWORD"
+
+// runBootstrapAdmin handles `main bootstrap-admin`, which creates the very
+// first super admin. Every later admin is invited from the admin API.
+func runBootstrapAdmin(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("bootstrap-admin", flag.ExitOnError)
+	email := fs.String("email", "", "email address of the admin")
+	firstName := fs.String("first-name", "", "first name of the admin")
+	lastName := fs.String("last-name", "", "last name of the admin")
+	dateOfBirth := fs.String("dob", "", "date of birth in YYYY-MM-DD format")
+	sex := fs.String("sex", "Other", "Male, Female or Other")
+	fs.Parse(args)
+
+	password := os.Getenv(bootstrapPasswordEnv)
+	if *email == "" || password == "" {
+		log.Fatalf("usage: %s=... main bootstrap-admin -email EMAIL -first-name NAME -last-name NAME -dob YYYY-MM-DD [-sex Male|Female|Other]", bootstrapPasswordEnv)
+	}
+
+	a := &api.API{
+		Config: cfg,
+		Deps:   deps.New(cfg),
+	}
+	defer a.Deps.DB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), bootstrapTimeout)
+	defer cancel()
+
+	userID, err := a.BootstrapAdmin(ctx, model.BootstrapAdminRequest{
+		Email:       *email,
+		FirstName:   *firstName,
+		LastName:    *lastName,
+		Password:    password,
+		DateOfBirth: *dateOfBirth,
+		Sex:         *sex,
+	})
+	if errors.Is(err, model.ErrSuperAdminExists) {
+		log.Fatalln("a super admin already exists, invite further admins from the admin API")
+	}
+	if err != nil {
+		log.Fatalln("failed to create admin:", err)
+	}
+	log.Printf("created super admin %s (user %d)", *email, userID)
+}
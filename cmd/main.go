@@ -1,43 +1,172 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/bwise1/your_care_api/config"
 	deps "github.com/bwise1/your_care_api/internal/debs"
 	api "github.com/bwise1/your_care_api/internal/http/rest"
-)
-
-const (
-	allowConnectionsAfterShutdown = 5 * time.Second
+	"github.com/bwise1/your_care_api/internal/outbox"
+	"github.com/bwise1/your_care_api/internal/scheduler"
+	"github.com/bwise1/your_care_api/util/push"
+	"github.com/bwise1/your_care_api/util/sms"
 )
 
 func main() {
 	cfg := config.New()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(cfg, os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "bootstrap-admin" {
+		runBootstrapAdmin(cfg, os.Args[2:])
+		return
+	}
+
+	os.Exit(run(cfg))
+}
+
+// run starts the API and its background workers and blocks until a shutdown
+// signal arrives or the server fails. It returns the process exit code.
+func run(cfg *config.Config) int {
 	deps := deps.New(cfg)
+	logger := deps.Logger
+	lc := deps.Lifecycle
 
 	a := &api.API{
 		Config: cfg,
 		Deps:   deps,
 	}
+
+	pollInterval, err := time.ParseDuration(cfg.NotificationPollInterval)
+	if err != nil {
+		fatal(logger, "invalid NOTIFICATION_POLL_INTERVAL", err)
+	}
+	notifiers := map[string]outbox.Notifier{
+		outbox.ChannelEmail: deps.Mailer,
+	}
+	if cfg.SmsProviderURL != "" {
+		notifiers[outbox.ChannelSMS] = sms.NewClient(cfg.SmsProviderURL, cfg.SmsAPIKey, cfg.SmsSender)
+	}
+	if cfg.PushProviderURL != "" {
+		notifiers[outbox.ChannelPush] = push.NewClient(cfg.PushProviderURL, cfg.PushAPIKey)
+	}
+	worker := outbox.NewWorker(deps.Outbox, notifiers, pollInterval, cfg.NotificationMaxAttempts, logger)
+
+	reminderInterval, err := time.ParseDuration(cfg.ReminderInterval)
+	if err != nil {
+		fatal(logger, "invalid REMINDER_INTERVAL", err)
+	}
+	reminderLeads, err := parseLeadTimes(cfg.ReminderLeadTimes)
+	if err != nil {
+		fatal(logger, "invalid REMINDER_LEAD_TIMES", err)
+	}
+	jobs := scheduler.New(deps.DB, logger)
+	jobs.Add("appointment_reminders", reminderInterval, func(ctx context.Context) error {
+		return a.SendAppointmentReminders(ctx, reminderLeads)
+	})
+	sweeperInterval, err := time.ParseDuration(cfg.SweeperInterval)
+	if err != nil {
+		fatal(logger, "invalid SWEEPER_INTERVAL", err)
+	}
+	jobs.Add("appointment_sweeper", sweeperInterval, a.SweepAppointments)
+
+	drainPeriod, err := time.ParseDuration(cfg.ShutdownDrainPeriod)
+	if err != nil {
+		fatal(logger, "invalid SHUTDOWN_DRAIN_PERIOD", err)
+	}
+	shutdownTimeout, err := time.ParseDuration(cfg.ShutdownTimeout)
+	if err != nil {
+		fatal(logger, "invalid SHUTDOWN_TIMEOUT", err)
+	}
+
+	lc.Go("outbox_worker", worker.Run)
+	lc.Go("scheduler", jobs.Run)
+
+	serveErr := make(chan error, 1)
 	go func() {
-		log.Printf("Server running on port %v ...", cfg.Port)
-		log.Fatal(a.Serve())
+		logger.Info("server running", "port", cfg.Port)
+		serveErr <- a.Serve()
 	}()
 
-	stopChan := make(chan os.Signal, 1)
-	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
-	<-stopChan
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	exitCode := 0
+	select {
+	case <-signalCtx.Done():
+		// Fail readiness first so load balancers drain traffic before the server closes
+		a.StartDraining()
+		logger.Info("shutdown requested, draining connections", "wait", drainPeriod.String())
+		select {
+		case <-time.After(drainPeriod):
+		case err := <-serveErr:
+			logger.Error("server stopped while draining", "error", err)
+			exitCode = 1
+		}
+	case err := <-serveErr:
+		logger.Error("server stopped", "error", err)
+		exitCode = 1
+	}
+	stopSignals()
 
-	log.Println("Request to shutdown server. Doing nothing for ", allowConnectionsAfterShutdown)
-	waitTimer := time.NewTimer(allowConnectionsAfterShutdown)
-	<-waitTimer.C
+	// In-flight requests and background work share one deadline
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
 
-	log.Println("Shutting down server...")
-	//logger.Log.Sugar().Fatal(a.Deps.DAL.DB.Close())
-	log.Fatal(a.Shutdown())
+	logger.Info("shutting down server", "timeout", shutdownTimeout.String())
+	if err := a.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("error shutting down server", "error", err)
+		exitCode = 1
+	}
+	if err := lc.Stop(ctx); err != nil {
+		logger.Error("error stopping background tasks", "error", err)
+		exitCode = 1
+	}
+	if err := deps.DB.Close(); err != nil {
+		logger.Error("error closing database", "error", err)
+		exitCode = 1
+	}
+
+	logger.Info("shutdown complete", "exit_code", exitCode)
+	return exitCode
+}
+
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// parseLeadTimes parses a comma separated list of reminder lead times and
+// returns them longest first.
+func parseLeadTimes(value string) ([]time.Duration, error) {
+	var leads []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lead, err := time.ParseDuration(part)
+		if err != nil {
+			return nil, err
+		}
+		if lead < time.Minute {
+			return nil, fmt.Errorf("lead time %s is shorter than a minute", lead)
+		}
+		leads = append(leads, lead)
+	}
+	sort.Slice(leads, func(i, j int) bool { return leads[i] > leads[j] })
+	return leads, nil
 }
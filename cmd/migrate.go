@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bwise1/your_care_api/config"
+	"github.com/bwise1/your_care_api/internal/db"
+)
+
+const migrateTimeout = 5 * time.Minute
+
+const migrateUsage = "usage: main migrate up|down|status|baseline [version]"
+
+// runMigrate handles `main migrate <up|down|status|baseline [version]>`.
+// baseline adopts a database created by hand before migrations existed: it
+// records migrations up to version, 1 by default, as applied without running
+// them, so `migrate up` only runs the later ones.
+func runMigrate(cfg *config.Config, args []string) {
+	if len(args) == 0 || len(args) > 2 || (len(args) == 2 && args[0] != "baseline") {
+		log.Fatal(migrateUsage)
+	}
+
+	database, err := db.Connect(cfg.Dsn)
+	if err != nil {
+		log.Fatalln("failed to connect to database:", err)
+	}
+	defer database.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		ran, err := database.MigrateUp(ctx)
+		for _, m := range ran {
+			log.Printf("applied %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if len(ran) == 0 {
+			log.Println("schema is up to date")
+		}
+
+	case "down":
+		m, err := database.MigrateDown(ctx)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if m == nil {
+			log.Println("no migrations to roll back")
+			return
+		}
+		log.Printf("rolled back %04d_%s", m.Version, m.Name)
+
+	case "baseline":
+		version := 1
+		if len(args) == 2 {
+			v, err := strconv.Atoi(args[1])
+			if err != nil {
+				log.Fatal(migrateUsage)
+			}
+			version = v
+		}
+		marked, err := database.Baseline(ctx, version)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, m := range marked {
+			log.Printf("marked %04d_%s as applied", m.Version, m.Name)
+		}
+
+	case "status":
+		statuses, err := database.MigrationStatuses(ctx)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			} else if s.Dirty {
+				state = fmt.Sprintf("dirty, failed after statement %d", s.StatementsApplied)
+			}
+			fmt.Fprintf(os.Stdout, "%04d_%-40s %s\n", s.Version, s.Name, state)
+		}
+
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
@@ -16,23 +16,91 @@ type Config struct {
 	RefreshSecret string `env:"REFRESH_SECRET"`
 	RefreshExpiry string `env:"REFRESH_EXPIRY"`
 
+	// TrustedProxies lists the addresses or CIDR ranges of the load balancers
+	// and reverse proxies in front of the API. X-Forwarded-For is only used to
+	// find the client IP on requests that come through one of them.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:","`
+
+	// MetricsToken is the bearer token scrapers send to read /metrics. The
+	// endpoint answers 404 while it is empty.
+	MetricsToken string `env:"METRICS_TOKEN"`
+
+	// LogLevel is one of debug, info, warn or error
+	LogLevel string `env:"LOG_LEVEL" envDefault:"info"`
+
+	// On shutdown the API first fails readiness for ShutdownDrainPeriod so load
+	// balancers stop sending traffic, then has ShutdownTimeout to finish
+	// in-flight requests and background work
+	ShutdownDrainPeriod string `env:"SHUTDOWN_DRAIN_PERIOD" envDefault:"5s"`
+	ShutdownTimeout     string `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
+
+	// ClientURL is the frontend base URL used to build links in emails
+	ClientURL        string `env:"CLIENT_URL" envDefault:"http://localhost:3000"`
+	PasswordResetTTL string `env:"PASSWORD_RESET_TTL" envDefault:"1h"`
+	AdminInviteTTL   string `env:"ADMIN_INVITE_TTL" envDefault:"72h"`
+
+	// Email verification. A new code may be sent once VerificationResendCooldown
+	// has passed since the last, at most MaxVerificationResends times before a
+	// day goes by without one. ResendVerificationRateLimit caps resend requests
+	// per client IP per hour.
+	RequireEmailVerification    bool   `env:"REQUIRE_EMAIL_VERIFICATION" envDefault:"true"`
+	MaxVerificationAttempts     int    `env:"MAX_VERIFICATION_ATTEMPTS" envDefault:"5"`
+	VerificationResendCooldown  string `env:"VERIFICATION_RESEND_COOLDOWN" envDefault:"1m"`
+	MaxVerificationResends      int    `env:"MAX_VERIFICATION_RESENDS" envDefault:"5"`
+	ResendVerificationRateLimit int    `env:"RESEND_VERIFICATION_RATE_LIMIT" envDefault:"10"`
+
+	// Notification outbox
+	NotificationPollInterval string `env:"NOTIFICATION_POLL_INTERVAL" envDefault:"5s"`
+	NotificationMaxAttempts  int    `env:"NOTIFICATION_MAX_ATTEMPTS" envDefault:"8"`
+
+	// Appointment reminders. Lead times are a comma separated list of how long
+	// before an appointment a reminder goes out.
+	ReminderLeadTimes string `env:"REMINDER_LEAD_TIMES" envDefault:"24h,2h"`
+	ReminderInterval  string `env:"REMINDER_INTERVAL" envDefault:"1m"`
+
+	// Appointment sweeper. Confirmed appointments with no check-in become
+	// no-shows after NoShowGracePeriod. Pending ones past their time, and
+	// reschedule requests whose original and proposed times have all passed,
+	// are canceled after StalePendingGracePeriod. RescheduleOfferTTL is how
+	// long a reschedule offer stays open when the admin sets no deadline.
+	SweeperInterval         string `env:"SWEEPER_INTERVAL" envDefault:"5m"`
+	NoShowGracePeriod       string `env:"NO_SHOW_GRACE_PERIOD" envDefault:"2h"`
+	StalePendingGracePeriod string `env:"STALE_PENDING_GRACE_PERIOD" envDefault:"0s"`
+	RescheduleOfferTTL      string `env:"RESCHEDULE_OFFER_TTL" envDefault:"48h"`
+
+	// SMS and push providers. A channel is disabled while its URL is empty.
+	SmsProviderURL  string `env:"SMS_PROVIDER_URL"`
+	SmsAPIKey       string `env:"SMS_API_KEY"`
+	SmsSender       string `env:"SMS_SENDER" envDefault:"YourCare"`
+	PushProviderURL string `env:"PUSH_PROVIDER_URL"`
+	PushAPIKey      string `env:"PUSH_API_KEY"`
+
 	// SMTP
 	SmtpHost     string `env:"SMTP_HOST"`
 	SmtpPort     int    `env:"SMTP_PORT"`
 	SmtpUser     string `env:"SMTP_USER"`
 	SmtpPassword string `env:"SMTP_PASSWORD"`
 	SmtpFrom     string `env:"SMTP_FROM"`
+
+	// Readiness checks. The SMTP check is off by default so a mail outage does
+	// not take the API out of the load balancer.
+	HealthCheckTimeout string `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s"`
+	HealthCheckSMTP    bool   `env:"HEALTH_CHECK_SMTP" envDefault:"false"`
+
+	// StatsCacheTTL is how long admin dashboard statistics are served from
+	// memory before they are recomputed
+	StatsCacheTTL string `env:"STATS_CACHE_TTL" envDefault:"1m"`
 }
 
 func New() *Config {
 	if loadErr := godotenv.Load(".env"); loadErr != nil {
-		log.Println("[Env]: unable to load .env file %v", loadErr)
+		log.Printf("[Env]: unable to load .env file %v", loadErr)
 	}
 
 	var cfg Config
 
 	if parseErr := env.Parse(&cfg); parseErr != nil {
-		log.Println("[Env]: failed to parse environment variables: %v", parseErr)
+		log.Printf("[Env]: failed to parse environment variables: %v", parseErr)
 	}
 
 	return &cfg
@@ -14,7 +14,28 @@ type DB struct {
 	*sqlx.DB
 }
 
+// New connects to the database and refuses to hand back a connection when the
+// schema is behind the migrations embedded in this binary.
 func New(dsn string) (*DB, error) {
+	database, err := Connect(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if err := database.CheckSchema(ctx); err != nil {
+		_ = database.Close()
+		return nil, err
+	}
+
+	return database, nil
+}
+
+// Connect opens a connection pool without checking the schema version. It is
+// used by the migrate command, which needs to run against an outdated schema.
+func Connect(dsn string) (*DB, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
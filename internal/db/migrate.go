@@ -0,0 +1,505 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/db/migrations"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+const migrationsTable = "schema_migrations"
+
+// progressTable holds the migration being applied, if any, and how many of
+// its statements have run. MySQL commits DDL implicitly, so a migration that
+// fails halfway cannot be rolled back; recording each statement lets
+// `migrate up` resume after the failed one instead of rerunning the rest.
+const progressTable = "schema_migration_progress"
+
+// MySQL error numbers for a table or column that does not exist
+const (
+	mysqlNoSuchTable  = 1146
+	mysqlNoSuchColumn = 1054
+)
+
+// ErrSchemaOutdated is returned when the database has not been migrated up to
+// the version the running binary was built against.
+var ErrSchemaOutdated = errors.New("database schema is behind the application, run `migrate up`")
+
+// ErrSchemaDirty is returned when a migration failed partway. Once the cause
+// is fixed, `migrate up` resumes it from the statement that failed.
+var ErrSchemaDirty = errors.New("a migration failed partway, fix the cause and rerun `migrate up`")
+
+// ErrAlreadyMigrated is returned by Baseline when migrations have been
+// recorded before.
+var ErrAlreadyMigrated = errors.New("the database already records applied migrations")
+
+var migrationFileRgx = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Dirty is set for a migration that failed after StatementsApplied
+	// statements and is resumed by the next `migrate up`
+	Dirty             bool
+	StatementsApplied int
+}
+
+// LoadMigrations reads every embedded migration and returns them ordered by version.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrations.EmbeddedFiles, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileRgx.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(migrations.EmbeddedFiles, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has mismatched names %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+
+	return list, nil
+}
+
+// LatestVersion returns the highest migration version embedded in the binary.
+func LatestVersion() (int, error) {
+	list, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(list) == 0 {
+		return 0, nil
+	}
+	return list[len(list)-1].Version, nil
+}
+
+// ensureMigrationsTable creates the bookkeeping tables. Only the migrate
+// command calls it; the API itself never runs DDL.
+func (db *DB) ensureMigrationsTable(ctx context.Context) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS ` + migrationsTable + ` (
+			version INT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS ` + progressTable + ` (
+			version INT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			statements_applied INT NOT NULL DEFAULT 0,
+			started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isMissing reports whether err is MySQL saying a table or column does not
+// exist, as on a database the migrate command has never run against.
+func isMissing(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == mysqlNoSuchTable || mysqlErr.Number == mysqlNoSuchColumn)
+}
+
+// SchemaVersion returns the most recently applied migration version, or 0 if
+// nothing has been applied yet. It only reads, so it is safe at start up.
+func (db *DB) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM `+migrationsTable).Scan(&version)
+	if isMissing(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// dirtyMigration returns the migration left partway by a failed run, or nil.
+func (db *DB) dirtyMigration(ctx context.Context) (*migrationProgress, error) {
+	var progress migrationProgress
+	err := db.QueryRowxContext(ctx, `SELECT version, name, statements_applied FROM `+progressTable+` ORDER BY version LIMIT 1`).StructScan(&progress)
+	if errors.Is(err, sql.ErrNoRows) || isMissing(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+type migrationProgress struct {
+	Version           int    `db:"version"`
+	Name              string `db:"name"`
+	StatementsApplied int    `db:"statements_applied"`
+}
+
+// CheckSchema makes sure every embedded migration has been applied and none
+// was left partway.
+func (db *DB) CheckSchema(ctx context.Context) error {
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+
+	dirty, err := db.dirtyMigration(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty != nil {
+		return fmt.Errorf("%w (%04d_%s stopped after statement %d)", ErrSchemaDirty, dirty.Version, dirty.Name, dirty.StatementsApplied)
+	}
+
+	current, err := db.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if current < latest {
+		return fmt.Errorf("%w (database at %d, binary expects %d)", ErrSchemaOutdated, current, latest)
+	}
+	return nil
+}
+
+func (db *DB) appliedMigrations(ctx context.Context) (map[int]time.Time, error) {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version, applied_at FROM `+migrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// MigrateUp applies every pending migration in order and returns the ones it ran.
+func (db *DB) MigrateUp(ctx context.Context) ([]Migration, error) {
+	list, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dirty, err := db.dirtyMigration(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, m := range list {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		// A dirty migration is resumed, and nothing after it runs first
+		skip := 0
+		if dirty != nil {
+			if dirty.Version != m.Version || dirty.Name != m.Name {
+				return ran, fmt.Errorf("%w: %04d_%s is recorded as in progress but %04d_%s is next", ErrSchemaDirty, dirty.Version, dirty.Name, m.Version, m.Name)
+			}
+			skip = dirty.StatementsApplied
+			dirty = nil
+		} else {
+			_, err = db.ExecContext(ctx, `INSERT INTO `+progressTable+` (version, name) VALUES (?, ?)`, m.Version, m.Name)
+			if err != nil {
+				return ran, err
+			}
+		}
+
+		if err := db.execMigration(ctx, m, skip); err != nil {
+			return ran, fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+
+		err = db.RunInTx(ctx, func(tx *sqlx.Tx) error {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO `+migrationsTable+` (version, name) VALUES (?, ?)`, m.Version, m.Name); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `DELETE FROM `+progressTable+` WHERE version = ?`, m.Version)
+			return err
+		})
+		if err != nil {
+			return ran, err
+		}
+		ran = append(ran, m)
+	}
+
+	return ran, nil
+}
+
+// execMigration runs the up script of m from statement skip onwards,
+// recording each statement as it completes. The script runs on a single
+// connection so SET statements apply to the statements after them; when
+// resuming, skipped SET statements are run again to restore that session.
+func (db *DB) execMigration(ctx context.Context, m Migration, skip int) error {
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	statements := splitStatements(m.Up)
+	for i := 0; i < skip && i < len(statements); i++ {
+		if !isSessionStatement(statements[i]) {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, statements[i]); err != nil {
+			return fmt.Errorf("statement %d of %d: %w\n%s", i+1, len(statements), err, statements[i])
+		}
+	}
+	for i := skip; i < len(statements); i++ {
+		if _, err := conn.ExecContext(ctx, statements[i]); err != nil {
+			return fmt.Errorf("statement %d of %d: %w\n%s", i+1, len(statements), err, statements[i])
+		}
+		_, err := conn.ExecContext(ctx, `UPDATE `+progressTable+` SET statements_applied = ? WHERE version = ?`, i+1, m.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isSessionStatement reports whether stmt only changes session variables.
+func isSessionStatement(stmt string) bool {
+	fields := strings.Fields(stmt)
+	return len(fields) > 0 && strings.EqualFold(fields[0], "SET")
+}
+
+// Baseline records every migration up to version as applied without running
+// it, for a database whose schema was created by hand before migrations were
+// introduced. It refuses to run once any migration has been recorded.
+func (db *DB) Baseline(ctx context.Context, version int) ([]Migration, error) {
+	list, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dirty, err := db.dirtyMigration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) > 0 || dirty != nil {
+		return nil, ErrAlreadyMigrated
+	}
+
+	var marked []Migration
+	for _, m := range list {
+		if m.Version <= version {
+			marked = append(marked, m)
+		}
+	}
+	if len(marked) == 0 || marked[len(marked)-1].Version != version {
+		return nil, fmt.Errorf("migration %d is not known to this binary", version)
+	}
+
+	err = db.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		for _, m := range marked {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO `+migrationsTable+` (version, name) VALUES (?, ?)`, m.Version, m.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return marked, nil
+}
+
+// MigrateDown rolls back the most recently applied migration. It returns nil
+// when there is nothing left to roll back.
+func (db *DB) MigrateDown(ctx context.Context) (*Migration, error) {
+	list, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	dirty, err := db.dirtyMigration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if dirty != nil {
+		return nil, fmt.Errorf("%w (%04d_%s stopped after statement %d)", ErrSchemaDirty, dirty.Version, dirty.Name, dirty.StatementsApplied)
+	}
+
+	current, err := db.SchemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current == 0 {
+		return nil, nil
+	}
+
+	for i := range list {
+		m := list[i]
+		if m.Version != current {
+			continue
+		}
+		if m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+		}
+
+		if err := db.execScript(ctx, m.Down); err != nil {
+			return nil, fmt.Errorf("rollback of %d_%s failed: %w", m.Version, m.Name, err)
+		}
+
+		_, err = db.ExecContext(ctx, `DELETE FROM `+migrationsTable+` WHERE version = ?`, m.Version)
+		if err != nil {
+			return nil, err
+		}
+		return &m, nil
+	}
+
+	return nil, fmt.Errorf("applied migration %d is not known to this binary", current)
+}
+
+// MigrationStatuses lists every embedded migration alongside whether it has been applied.
+func (db *DB) MigrationStatuses(ctx context.Context) ([]MigrationStatus, error) {
+	list, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dirty, err := db.dirtyMigration(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(list))
+	for i, m := range list {
+		statuses[i] = MigrationStatus{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := applied[m.Version]; ok {
+			statuses[i].Applied = true
+			statuses[i].AppliedAt = &appliedAt
+		}
+		if dirty != nil && dirty.Version == m.Version {
+			statuses[i].Dirty = true
+			statuses[i].StatementsApplied = dirty.StatementsApplied
+		}
+	}
+	return statuses, nil
+}
+
+// execScript runs each statement of a migration script in turn. MySQL commits
+// DDL implicitly, so statements are executed one by one rather than in a
+// transaction, on one connection so SET statements carry over. Up scripts go through execMigration, which records progress.
+func (db *DB) execScript(ctx context.Context, script string) error {
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, stmt := range splitStatements(script) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%w\n%s", err, stmt)
+		}
+	}
+	return nil
+}
+
+// splitStatements breaks a script on semicolons that end a line, skipping
+// full-line `--` comments. Migration scripts must not put a semicolon at the
+// end of a line inside a string literal.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			stmt := strings.TrimSuffix(strings.TrimSpace(current.String()), ";")
+			statements = append(statements, stmt)
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
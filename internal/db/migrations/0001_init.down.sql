@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS appointment_status_history;
+DROP TABLE IF EXISTS reschedule_offers;
+DROP TABLE IF EXISTS ivf_appointment_details;
+DROP TABLE IF EXISTS doctor_appointment_details;
+DROP TABLE IF EXISTS lab_test_appointment_details;
+DROP TABLE IF EXISTS appointments;
+DROP TABLE IF EXISTS doctors;
+DROP TABLE IF EXISTS hospital_lab_tests;
+DROP TABLE IF EXISTS lab_tests;
+DROP TABLE IF EXISTS hospitals;
+DROP TABLE IF EXISTS social_logins;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,205 @@
+-- Base schema for YourCare. Consolidates the original query.sql together with
+-- the appointment enhancement and lab test clean-up scripts.
+
+CREATE TABLE roles (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(50) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE users (
+    id INT NOT NULL AUTO_INCREMENT,
+    firstName VARCHAR(50) COLLATE utf8mb4_unicode_ci NOT NULL,
+    lastName VARCHAR(50) COLLATE utf8mb4_unicode_ci NOT NULL,
+    email VARCHAR(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+    dateOfBirth DATE NOT NULL,
+    sex ENUM('Male', 'Female', 'Other') COLLATE utf8mb4_unicode_ci NOT NULL,
+    height DECIMAL(5,2), -- Nullable, allowing for null values
+    password VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+    role_id INT NOT NULL DEFAULT 1, -- Foreign key to roles table
+    isActive TINYINT(1) NULL DEFAULT 1,
+    lastLogin DATETIME,
+    refreshToken VARCHAR(512) COLLATE utf8mb4_unicode_ci,
+    tokenExpiration DATETIME,
+    isEmailVerified TINYINT(1) NULL DEFAULT 0,
+    emailVerificationCode VARCHAR(100) COLLATE utf8mb4_unicode_ci,
+    emailVerificationCodeExpires DATETIME,
+    created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY (email),
+    FOREIGN KEY (role_id) REFERENCES roles(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE social_logins (
+    id INT NOT NULL AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    provider ENUM('Google', 'Facebook', 'Twitter', 'Apple', 'GitHub') NOT NULL,
+    provider_user_id VARCHAR(255) NOT NULL,
+    provider_token TEXT,
+    token_expires_at DATETIME,
+    email VARCHAR(100),
+    name VARCHAR(100),
+    avatar_url VARCHAR(255),
+    created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE hospitals (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    address TEXT,
+    phone VARCHAR(20),
+    email VARCHAR(100)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Hospital specific offerings live in hospital_lab_tests
+CREATE TABLE lab_tests (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(100),
+    description TEXT,
+    price DECIMAL(10, 2)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE hospital_lab_tests (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    hospital_id INT NOT NULL,
+    lab_test_id INT NOT NULL,
+    name VARCHAR(100), -- hospital-specific name
+    price DECIMAL(10,2),
+    details TEXT,
+    FOREIGN KEY (hospital_id) REFERENCES hospitals(id),
+    FOREIGN KEY (lab_test_id) REFERENCES lab_tests(id),
+    UNIQUE (hospital_id, lab_test_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE doctors (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    hospital_id INT,
+    name VARCHAR(100) NOT NULL,
+    specialization VARCHAR(100),
+    email VARCHAR(100),
+    phone VARCHAR(20),
+    available_from TIME, -- Available start time for appointments
+    available_to TIME, -- Available end time for appointments
+    FOREIGN KEY (hospital_id) REFERENCES hospitals(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE appointments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    doctor_id INT, -- NULL if lab test
+    lab_test_id INT, -- NULL if doctor appointment
+    provider_id INT,
+    appointment_type ENUM('doctor', 'lab_test', 'ivf') NOT NULL,
+    appointment_datetime DATETIME NOT NULL,
+    status ENUM(
+        'pending',
+        'admin_review',
+        'confirmed',
+        'scheduled',
+        'reschedule_offered',
+        'reschedule_accepted',
+        'in_progress',
+        'completed',
+        'canceled',
+        'rejected',
+        'no_show'
+    ) DEFAULT 'pending',
+    admin_notes TEXT,
+    user_notes TEXT,
+    rejection_reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (doctor_id) REFERENCES doctors(id),
+    FOREIGN KEY (lab_test_id) REFERENCES lab_tests(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE lab_test_appointment_details (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    appointment_id INT NOT NULL,
+    pickup_type ENUM('home', 'hospital') NOT NULL,
+    home_location TEXT, -- Address details for home pickup
+    test_type_id INT NOT NULL,
+    hospital_id INT, -- hospital id for hospital type of tests
+    additional_instructions TEXT,
+    FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE CASCADE,
+    FOREIGN KEY (test_type_id) REFERENCES lab_tests(id),
+    FOREIGN KEY (hospital_id) REFERENCES hospitals(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE doctor_appointment_details (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    appointment_id INT NOT NULL,
+    doctor_id INT,
+    reason_for_visit TEXT,
+    symptoms TEXT,
+    additional_notes TEXT,
+    FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE CASCADE,
+    FOREIGN KEY (doctor_id) REFERENCES doctors(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE ivf_appointment_details (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    appointment_id INT NOT NULL,
+    treatment_type VARCHAR(100),
+    cycle_day INT,
+    special_instructions TEXT,
+    preparation_notes TEXT,
+    FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE reschedule_offers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    appointment_id INT NOT NULL,
+    proposed_date DATE NOT NULL,
+    proposed_time TIME NOT NULL,
+    admin_notes TEXT,
+    status ENUM('pending', 'accepted', 'rejected') DEFAULT 'pending',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE appointment_status_history (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    appointment_id INT NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    notes TEXT,
+    changed_by_user_id INT, -- Who made the change (admin or user)
+    changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE CASCADE,
+    FOREIGN KEY (changed_by_user_id) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE INDEX idx_appointments_status ON appointments(status);
+CREATE INDEX idx_appointments_type ON appointments(appointment_type);
+CREATE INDEX idx_appointments_datetime ON appointments(appointment_datetime);
+CREATE INDEX idx_appointments_user_id ON appointments(user_id);
+CREATE INDEX idx_appointments_provider_id ON appointments(provider_id);
+CREATE INDEX idx_reschedule_offers_appointment_id ON reschedule_offers(appointment_id);
+CREATE INDEX idx_status_history_appointment_id ON appointment_status_history(appointment_id);
+
+INSERT INTO roles (name, description) VALUES
+('user', 'Regular user with standard privileges'),
+('admin', 'Administrator with full system access'),
+('doctor', 'Medical professional with access to patient data');
+
+INSERT INTO hospitals (name, address, phone, email) VALUES
+('Miracle Hospital', 'Prof. Hilmi Forward Street, No: 24, NICOSIA', '0392 444 67 25', 'info@wellcarelaborators.com');
+
+INSERT INTO lab_tests (name, description, price) VALUES
+('Complete Blood Count (CBC)', 'Measures different components of blood including red and white blood cells, hemoglobin, and platelets.', 50.00),
+('Lipid Panel', 'Measures cholesterol levels to assess risk of cardiovascular disease.', 65.00),
+('Thyroid Function Test', 'Checks the function of the thyroid gland by measuring hormone levels.', 80.00),
+('Urinalysis', 'Analyzes urine sample for various health indicators.', 30.00),
+('Hemoglobin A1C', 'Measures average blood sugar levels over the past 2-3 months.', 70.00),
+('Vitamin D Test', 'Measures the level of Vitamin D in the blood.', 90.00),
+('Liver Function Test', 'Assesses the health and function of the liver.', 75.00),
+('COVID-19 PCR Test', 'Detects genetic material of the SARS-CoV-2 virus.', 120.00);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS appointment_slots;
+DROP TABLE IF EXISTS slot_reservations;
+DROP TABLE IF EXISTS provider_schedules;
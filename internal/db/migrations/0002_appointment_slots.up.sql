@@ -0,0 +1,32 @@
+-- Working hours per provider. A provider is either a doctor or a hospital lab bench.
+CREATE TABLE provider_schedules (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    provider_type ENUM('doctor', 'hospital_lab') NOT NULL,
+    provider_id INT NOT NULL,
+    weekday TINYINT NOT NULL, -- 0 = Sunday ... 6 = Saturday
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    slot_minutes INT NOT NULL DEFAULT 30,
+    capacity INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_provider_schedule (provider_type, provider_id, weekday)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- One row per slot that has ever been booked. booked never exceeds capacity.
+CREATE TABLE slot_reservations (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    provider_type ENUM('doctor', 'hospital_lab') NOT NULL,
+    provider_id INT NOT NULL,
+    slot_start DATETIME NOT NULL,
+    capacity INT NOT NULL,
+    booked INT NOT NULL DEFAULT 0,
+    UNIQUE KEY uq_slot (provider_type, provider_id, slot_start)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE appointment_slots (
+    appointment_id INT PRIMARY KEY,
+    reservation_id INT NOT NULL,
+    FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE CASCADE,
+    FOREIGN KEY (reservation_id) REFERENCES slot_reservations(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN emailVerificationAttempts INT NOT NULL DEFAULT 0 AFTER emailVerificationCodeExpires;
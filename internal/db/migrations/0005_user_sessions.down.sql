@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN refreshToken VARCHAR(512) AFTER lastLogin;
+DROP TABLE IF EXISTS user_sessions;
@@ -0,0 +1,21 @@
+-- One row per signed-in device. family_id is the session identifier carried in
+-- tokens; token_hash is the SHA-256 of the only refresh token currently valid
+-- for the family. Presenting any older token from the family revokes it.
+CREATE TABLE user_sessions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    family_id VARCHAR(64) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    user_agent VARCHAR(512),
+    ip_address VARCHAR(64),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME NULL,
+    UNIQUE KEY uq_user_sessions_family (family_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE INDEX idx_user_sessions_user ON user_sessions(user_id, revoked_at);
+
+ALTER TABLE users DROP COLUMN refreshToken;
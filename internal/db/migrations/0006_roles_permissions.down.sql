@@ -0,0 +1,14 @@
+UPDATE users SET role_id = (SELECT id FROM (SELECT id FROM roles WHERE name = 'admin') AS r)
+WHERE role_id IN (SELECT id FROM (SELECT id FROM roles WHERE name = 'super_admin') AS r);
+
+UPDATE users SET role_id = (SELECT id FROM (SELECT id FROM roles WHERE name = 'user') AS r)
+WHERE role_id IN (SELECT id FROM (SELECT id FROM roles WHERE name IN ('hospital_staff', 'lab_technician')) AS r);
+
+ALTER TABLE users DROP FOREIGN KEY fk_users_hospital;
+ALTER TABLE users DROP COLUMN hospital_id;
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+
+DELETE FROM roles WHERE name IN ('super_admin', 'hospital_staff', 'lab_technician');
+ALTER TABLE roles DROP COLUMN hospital_scoped;
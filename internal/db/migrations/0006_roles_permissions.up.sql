@@ -0,0 +1,78 @@
+-- Hospital scoped roles only see and act on their own hospital's data. Users
+-- holding one must have users.hospital_id set.
+ALTER TABLE roles ADD COLUMN hospital_scoped TINYINT(1) NOT NULL DEFAULT 0 AFTER description;
+
+INSERT INTO roles (name, description, hospital_scoped) VALUES
+('super_admin', 'Full system access including granting admin roles', 0),
+('hospital_staff', 'Hospital staff managing their hospital''s appointments and schedules', 1),
+('lab_technician', 'Lab technician handling their hospital''s lab appointments and prices', 1);
+
+UPDATE roles SET hospital_scoped = 1 WHERE name = 'doctor';
+
+CREATE TABLE permissions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(255),
+    UNIQUE KEY uq_permissions_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE role_permissions (
+    role_id INT NOT NULL,
+    permission_id INT NOT NULL,
+    PRIMARY KEY (role_id, permission_id),
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE users
+    ADD COLUMN hospital_id INT NULL AFTER role_id,
+    ADD CONSTRAINT fk_users_hospital FOREIGN KEY (hospital_id) REFERENCES hospitals(id) ON DELETE SET NULL;
+
+INSERT INTO permissions (name, description) VALUES
+('appointments:read', 'View appointments in the admin area'),
+('appointments:confirm', 'Confirm pending appointments'),
+('appointments:reject', 'Reject appointments'),
+('appointments:reschedule', 'Offer new times for appointments'),
+('appointments:cancel', 'Cancel appointments'),
+('appointments:update_status', 'Move appointments through their lifecycle'),
+('appointments:notes', 'Edit admin notes on appointments'),
+('hospitals:write', 'Create and delete hospitals'),
+('lab-tests:write', 'Manage the lab test catalogue'),
+('lab-tests:price', 'Set hospital lab test prices'),
+('doctors:write', 'Manage the doctor directory'),
+('schedules:write', 'Manage provider working hours'),
+('roles:assign', 'Assign roles to users');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name IN ('super_admin', 'admin');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.name IN (
+    'appointments:read',
+    'appointments:confirm',
+    'appointments:reject',
+    'appointments:reschedule',
+    'appointments:cancel',
+    'appointments:update_status',
+    'appointments:notes',
+    'schedules:write'
+)
+WHERE r.name = 'hospital_staff';
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.name IN (
+    'appointments:read',
+    'appointments:update_status',
+    'appointments:notes',
+    'lab-tests:price'
+)
+WHERE r.name = 'lab_technician';
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.name IN (
+    'appointments:read',
+    'appointments:update_status',
+    'appointments:notes'
+)
+WHERE r.name = 'doctor';
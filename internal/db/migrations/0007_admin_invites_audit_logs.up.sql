@@ -0,0 +1,38 @@
+-- Staff accounts are created by accepting an invite issued by an existing
+-- admin. Only the SHA-256 hash of the emailed token is stored.
+CREATE TABLE admin_invites (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    email VARCHAR(100) NOT NULL,
+    role_id INT NOT NULL,
+    hospital_id INT NULL,
+    token_hash CHAR(64) NOT NULL,
+    invited_by INT NULL,
+    expires_at DATETIME NOT NULL,
+    accepted_at DATETIME NULL,
+    accepted_user_id INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_admin_invites_token (token_hash),
+    FOREIGN KEY (role_id) REFERENCES roles(id),
+    FOREIGN KEY (hospital_id) REFERENCES hospitals(id) ON DELETE CASCADE,
+    FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (accepted_user_id) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE INDEX idx_admin_invites_email ON admin_invites(email);
+
+-- Append-only record of privileged actions. actor_user_id is NULL for actions
+-- run from the command line.
+CREATE TABLE audit_logs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor_user_id INT NULL,
+    action VARCHAR(100) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id VARCHAR(64) NOT NULL,
+    metadata JSON NULL,
+    ip_address VARCHAR(64),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (actor_user_id) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE INDEX idx_audit_logs_target ON audit_logs(target_type, target_id);
+CREATE INDEX idx_audit_logs_actor ON audit_logs(actor_user_id, created_at);
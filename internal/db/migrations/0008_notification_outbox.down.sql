@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE name = 'notifications:manage';
+
+DROP TABLE IF EXISTS notification_outbox;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS user_push_tokens;
+
+ALTER TABLE users
+    DROP COLUMN notify_push,
+    DROP COLUMN notify_sms,
+    DROP COLUMN notify_email,
+    DROP COLUMN phone;
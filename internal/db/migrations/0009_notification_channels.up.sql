@@ -0,0 +1,22 @@
+-- Per-user notification channel preferences. Email stays on by default so
+-- existing users keep receiving what they did before.
+ALTER TABLE users
+    ADD COLUMN phone VARCHAR(20) NULL AFTER sex,
+    ADD COLUMN notify_email TINYINT(1) NOT NULL DEFAULT 1,
+    ADD COLUMN notify_sms TINYINT(1) NOT NULL DEFAULT 0,
+    ADD COLUMN notify_push TINYINT(1) NOT NULL DEFAULT 0;
+
+-- Devices registered for push notifications. A token belongs to one user at a
+-- time; registering it again moves it to the new user.
+CREATE TABLE user_push_tokens (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    token VARCHAR(512) NOT NULL,
+    platform ENUM('ios', 'android', 'web') NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_user_push_tokens_token (token),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE INDEX idx_user_push_tokens_user ON user_push_tokens(user_id);
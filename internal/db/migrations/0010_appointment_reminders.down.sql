@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS scheduler_leases;
+DROP TABLE IF EXISTS appointment_reminders;
@@ -0,0 +1,19 @@
+-- One row per reminder sent, so a reminder for the same appointment and lead
+-- time is never queued twice.
+CREATE TABLE appointment_reminders (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    appointment_id INT NOT NULL,
+    lead_minutes INT NOT NULL,
+    sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_appointment_reminders (appointment_id, lead_minutes),
+    FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Background jobs take a lease here before running so only one replica runs
+-- each job at a time. A lease whose holder stops renewing it can be taken
+-- over once expires_at passes.
+CREATE TABLE scheduler_leases (
+    name VARCHAR(100) PRIMARY KEY,
+    holder VARCHAR(100) NOT NULL,
+    expires_at DATETIME NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,8 @@
+DROP INDEX idx_appointments_status_datetime ON appointments;
+
+UPDATE reschedule_offers SET status = 'rejected' WHERE status = 'expired';
+
+ALTER TABLE reschedule_offers
+    MODIFY COLUMN status ENUM('pending', 'accepted', 'rejected') DEFAULT 'pending';
+
+ALTER TABLE appointment_status_history DROP COLUMN changed_by_system;
@@ -0,0 +1,10 @@
+-- Status changes made by background jobs rather than a person are flagged so
+-- the history can show them as system actions.
+ALTER TABLE appointment_status_history
+    ADD COLUMN changed_by_system TINYINT(1) NOT NULL DEFAULT 0 AFTER changed_by_user_id;
+
+-- Offers the patient never answered are marked expired by the sweeper
+ALTER TABLE reschedule_offers
+    MODIFY COLUMN status ENUM('pending', 'accepted', 'rejected', 'expired') DEFAULT 'pending';
+
+CREATE INDEX idx_appointments_status_datetime ON appointments(status, appointment_datetime);
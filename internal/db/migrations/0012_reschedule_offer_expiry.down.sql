@@ -0,0 +1,7 @@
+DROP INDEX idx_reschedule_offers_appointment ON reschedule_offers;
+
+UPDATE reschedule_offers SET status = 'expired' WHERE status = 'superseded';
+
+ALTER TABLE reschedule_offers
+    MODIFY COLUMN status ENUM('pending', 'accepted', 'rejected', 'expired') DEFAULT 'pending',
+    DROP COLUMN expires_at;
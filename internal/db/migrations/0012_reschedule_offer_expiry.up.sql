@@ -0,0 +1,11 @@
+-- Offers carry their own deadline, and offers made alongside one the patient
+-- accepted, or replaced by a newer set of offers, are marked superseded.
+ALTER TABLE reschedule_offers
+    ADD COLUMN expires_at DATETIME NULL AFTER status,
+    MODIFY COLUMN status ENUM('pending', 'accepted', 'rejected', 'expired', 'superseded') DEFAULT 'pending';
+
+UPDATE reschedule_offers SET expires_at = DATE_ADD(created_at, INTERVAL 48 HOUR);
+
+ALTER TABLE reschedule_offers MODIFY COLUMN expires_at DATETIME NOT NULL;
+
+CREATE INDEX idx_reschedule_offers_appointment ON reschedule_offers(appointment_id, status);
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS reschedule_requests;
+
+UPDATE appointments SET status = 'pending' WHERE status = 'reschedule_requested';
+
+ALTER TABLE appointments MODIFY COLUMN status ENUM(
+    'pending',
+    'admin_review',
+    'confirmed',
+    'scheduled',
+    'reschedule_offered',
+    'reschedule_accepted',
+    'in_progress',
+    'completed',
+    'canceled',
+    'rejected',
+    'no_show'
+) DEFAULT 'pending';
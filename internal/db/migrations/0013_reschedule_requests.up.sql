@@ -0,0 +1,34 @@
+ALTER TABLE appointments MODIFY COLUMN status ENUM(
+    'pending',
+    'admin_review',
+    'confirmed',
+    'scheduled',
+    'reschedule_offered',
+    'reschedule_accepted',
+    'reschedule_requested',
+    'in_progress',
+    'completed',
+    'canceled',
+    'rejected',
+    'no_show'
+) DEFAULT 'pending';
+
+-- New times proposed by the patient, one row per option. Admins approve one
+-- of them or answer with a reschedule offer of their own.
+CREATE TABLE reschedule_requests (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    appointment_id INT NOT NULL,
+    requested_by_user_id INT NOT NULL,
+    proposed_date DATE NOT NULL,
+    proposed_time TIME NOT NULL,
+    reason TEXT NOT NULL,
+    status ENUM('pending', 'approved', 'declined', 'withdrawn', 'superseded') NOT NULL DEFAULT 'pending',
+    reviewed_by_user_id INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE CASCADE,
+    FOREIGN KEY (requested_by_user_id) REFERENCES users(id),
+    FOREIGN KEY (reviewed_by_user_id) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE INDEX idx_reschedule_requests_appointment ON reschedule_requests(appointment_id, status);
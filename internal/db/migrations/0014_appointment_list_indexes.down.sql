@@ -0,0 +1,3 @@
+DROP INDEX idx_appointments_created ON appointments;
+DROP INDEX idx_appointments_user_created ON appointments;
+DROP INDEX idx_appointments_user_datetime ON appointments;
@@ -0,0 +1,6 @@
+-- Appointment lists page with a seek on (sort column, id) rather than OFFSET.
+-- InnoDB appends the primary key to every secondary index, so these cover the
+-- id tie-breaker as well.
+CREATE INDEX idx_appointments_user_datetime ON appointments(user_id, appointment_datetime);
+CREATE INDEX idx_appointments_user_created ON appointments(user_id, created_at);
+CREATE INDEX idx_appointments_created ON appointments(created_at);
@@ -0,0 +1,7 @@
+ALTER TABLE appointments DROP INDEX ft_appointments_notes;
+
+ALTER TABLE lab_tests DROP INDEX ft_lab_tests_search;
+
+ALTER TABLE hospitals DROP INDEX ft_hospitals_search;
+
+ALTER TABLE users DROP INDEX ft_users_search;
@@ -0,0 +1,26 @@
+-- Full-text indexes behind the admin appointment search. The n-gram parser
+-- matches partial words, such as "mala" in "Malaria", and names in scripts
+-- without spaces between words.
+--
+-- The default stopword list holds single letters such as "a" and "i", and the
+-- n-gram parser drops every token containing a stopword, so bigrams like "ma"
+-- and "al" would never be indexed. Stopwords are turned off for this session,
+-- which fixes the setting into the indexes created below.
+--
+-- Server settings this depends on:
+--   ngram_token_size = 2 (the default), matching the two-character minimum
+--     search term. It can only be set at server start-up.
+--   innodb_ft_enable_stopword = OFF globally as well, so indexes rebuilt
+--     outside this migration (ALTER TABLE ... FORCE, restored dumps) also
+--     keep every bigram.
+SET SESSION innodb_ft_enable_stopword = OFF;
+
+ALTER TABLE users ADD FULLTEXT INDEX ft_users_search (firstName, lastName, email) WITH PARSER ngram;
+
+ALTER TABLE hospitals ADD FULLTEXT INDEX ft_hospitals_search (name) WITH PARSER ngram;
+
+ALTER TABLE lab_tests ADD FULLTEXT INDEX ft_lab_tests_search (name) WITH PARSER ngram;
+
+ALTER TABLE appointments ADD FULLTEXT INDEX ft_appointments_notes (admin_notes, user_notes) WITH PARSER ngram;
+
+SET SESSION innodb_ft_enable_stopword = DEFAULT;
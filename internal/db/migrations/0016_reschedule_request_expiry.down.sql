@@ -0,0 +1,4 @@
+UPDATE reschedule_requests SET status = 'declined' WHERE status = 'expired';
+
+ALTER TABLE reschedule_requests
+    MODIFY COLUMN status ENUM('pending', 'approved', 'declined', 'withdrawn', 'superseded') NOT NULL DEFAULT 'pending';
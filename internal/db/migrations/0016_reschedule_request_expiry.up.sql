@@ -0,0 +1,4 @@
+-- Reschedule requests left unanswered until both the original and every
+-- proposed time have passed are closed by the sweeper and marked expired.
+ALTER TABLE reschedule_requests
+    MODIFY COLUMN status ENUM('pending', 'approved', 'declined', 'withdrawn', 'superseded', 'expired') NOT NULL DEFAULT 'pending';
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN emailVerificationResends,
+    DROP COLUMN emailVerificationSentAt;
@@ -0,0 +1,6 @@
+-- When the last verification code was sent and how many resends followed it
+-- without a day's break, so resending can be throttled. Each resend unlocks a
+-- fresh set of attempts, so without a limit it would allow unlimited guesses.
+ALTER TABLE users
+    ADD COLUMN emailVerificationSentAt DATETIME NULL AFTER emailVerificationAttempts,
+    ADD COLUMN emailVerificationResends INT NOT NULL DEFAULT 0 AFTER emailVerificationSentAt;
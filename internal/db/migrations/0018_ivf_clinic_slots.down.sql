@@ -0,0 +1,13 @@
+DELETE s FROM appointment_slots s
+    JOIN slot_reservations r ON r.id = s.reservation_id
+    WHERE r.provider_type = 'ivf_clinic';
+
+DELETE FROM slot_reservations WHERE provider_type = 'ivf_clinic';
+
+DELETE FROM provider_schedules WHERE provider_type = 'ivf_clinic';
+
+ALTER TABLE slot_reservations
+    MODIFY COLUMN provider_type ENUM('doctor', 'hospital_lab') NOT NULL;
+
+ALTER TABLE provider_schedules
+    MODIFY COLUMN provider_type ENUM('doctor', 'hospital_lab') NOT NULL;
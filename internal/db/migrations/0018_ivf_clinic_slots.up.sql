@@ -0,0 +1,7 @@
+-- The IVF clinic becomes a slot provider, so IVF bookings reserve capacity
+-- once the clinic has a schedule.
+ALTER TABLE provider_schedules
+    MODIFY COLUMN provider_type ENUM('doctor', 'hospital_lab', 'ivf_clinic') NOT NULL;
+
+ALTER TABLE slot_reservations
+    MODIFY COLUMN provider_type ENUM('doctor', 'hospital_lab', 'ivf_clinic') NOT NULL;
@@ -0,0 +1,13 @@
+-- Keep only the latest reminder for each appointment and lead time
+DELETE ar FROM appointment_reminders ar
+JOIN appointment_reminders newer
+    ON newer.appointment_id = ar.appointment_id
+    AND newer.lead_minutes = ar.lead_minutes
+    AND newer.id > ar.id;
+
+ALTER TABLE appointment_reminders
+    ADD UNIQUE KEY uq_appointment_reminders (appointment_id, lead_minutes);
+
+ALTER TABLE appointment_reminders DROP INDEX uq_appointment_reminders_time;
+
+ALTER TABLE appointment_reminders DROP COLUMN appointment_datetime;
@@ -0,0 +1,18 @@
+-- Reminders are recorded against the appointment time they were sent for, so
+-- a rescheduled appointment is reminded again for its new time.
+ALTER TABLE appointment_reminders ADD COLUMN appointment_datetime DATETIME NULL AFTER lead_minutes;
+
+UPDATE appointment_reminders ar
+JOIN appointments a ON a.id = ar.appointment_id
+SET ar.appointment_datetime = a.appointment_datetime;
+
+DELETE FROM appointment_reminders WHERE appointment_datetime IS NULL;
+
+ALTER TABLE appointment_reminders MODIFY COLUMN appointment_datetime DATETIME NOT NULL;
+
+-- The new key is added first, since the foreign key on appointment_id needs an
+-- index at all times
+ALTER TABLE appointment_reminders
+    ADD UNIQUE KEY uq_appointment_reminders_time (appointment_id, lead_minutes, appointment_datetime);
+
+ALTER TABLE appointment_reminders DROP INDEX uq_appointment_reminders;
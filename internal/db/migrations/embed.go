@@ -0,0 +1,8 @@
+package migrations
+
+import (
+	"embed"
+)
+
+//go:embed "*.sql"
+var EmbeddedFiles embed.FS
@@ -1,28 +1,52 @@
 package deps
 
 import (
-	"log"
+	"log/slog"
+	"os"
 
 	"github.com/bwise1/your_care_api/config"
 	"github.com/bwise1/your_care_api/internal/db"
+	"github.com/bwise1/your_care_api/internal/lifecycle"
+	"github.com/bwise1/your_care_api/internal/metrics"
+	"github.com/bwise1/your_care_api/internal/outbox"
 	smtp "github.com/bwise1/your_care_api/util/email"
+	"github.com/bwise1/your_care_api/util/logger"
 )
 
 type Dependencies struct {
-	DB     *db.DB
-	Mailer *smtp.Mailer
+	DB        *db.DB
+	Mailer    *smtp.Mailer
+	Outbox    *outbox.Store
+	Logger    *slog.Logger
+	Metrics   *metrics.Metrics
+	Lifecycle *lifecycle.Manager
 }
 
 func New(cfg *config.Config) *Dependencies {
+	log := logger.New(cfg.LogLevel)
+	// Anything still written through the standard library logger ends up in
+	// the same structured stream
+	slog.SetDefault(log)
+
 	database, err := db.New(cfg.Dsn)
 	mailer := smtp.NewMailer(cfg.SmtpHost, cfg.SmtpPort, cfg.SmtpUser, cfg.SmtpPassword, cfg.SmtpFrom)
 
 	if err != nil {
-		log.Panicln("failed to connect to database", "error", err)
+		log.Error("failed to connect to database", "error", err)
+		os.Exit(1)
 	}
+
+	m := metrics.New()
+	m.RegisterDBStats(database.Stats)
+	mailer.OnSend(m.ObserveEmail)
+
 	deps := Dependencies{
-		DB:     database,
-		Mailer: mailer,
+		DB:        database,
+		Mailer:    mailer,
+		Outbox:    outbox.NewStore(database),
+		Logger:    log,
+		Metrics:   m,
+		Lifecycle: lifecycle.New(log),
 	}
 	return &deps
 }
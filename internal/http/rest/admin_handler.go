@@ -3,47 +3,101 @@ package rest
 import (
 	"net/http"
 
+	"github.com/bwise1/your_care_api/internal/model"
 	"github.com/go-chi/chi/v5"
 )
 
 func (api *API) AdminRoutes() chi.Router {
 	mux := chi.NewRouter()
 
-	// Admin appointment routes
+	// Admin appointment routes. Hospital scoped staff only reach their hospital's appointments.
 	mux.Route("/appointments", func(r chi.Router) {
 		r.Use(api.RequireLogin)
-		r.Use(api.RequireAdmin)
-		r.Method(http.MethodGet, "/", Handler(api.AdminFetchAllAppointments))
-		r.Method(http.MethodGet, "/{id}", Handler(api.AdminGetAppointmentDetails))
-		r.Method(http.MethodGet, "/{id}/history", Handler(api.AdminGetAppointmentHistory))
-		r.Method(http.MethodPost, "/{id}/confirm", Handler(api.AdminConfirmAppointment))
-		r.Method(http.MethodPost, "/{id}/reject", Handler(api.AdminRejectAppointment))
-		r.Method(http.MethodPost, "/{id}/reschedule", Handler(api.AdminRescheduleAppointment))
-		r.Method(http.MethodPost, "/{id}/cancel", Handler(api.AdminCancelAppointment))
-		r.Method(http.MethodPut, "/{id}/notes", Handler(api.AdminUpdateNotes))
-		r.Method(http.MethodPut, "/{id}/status", Handler(api.AdminUpdateAppointmentStatus))
+		r.With(api.RequirePermission(model.PermAppointmentsRead)).Method(http.MethodGet, "/", Handler(api.AdminFetchAllAppointments))
+
+		r.Route("/{id}", func(r chi.Router) {
+			r.Use(api.RequireAppointmentScope)
+			r.With(api.RequirePermission(model.PermAppointmentsRead)).Method(http.MethodGet, "/", Handler(api.AdminGetAppointmentDetails))
+			r.With(api.RequirePermission(model.PermAppointmentsRead)).Method(http.MethodGet, "/history", Handler(api.AdminGetAppointmentHistory))
+			r.With(api.RequirePermission(model.PermAppointmentsConfirm)).Method(http.MethodPost, "/confirm", Handler(api.AdminConfirmAppointment))
+			r.With(api.RequirePermission(model.PermAppointmentsReject)).Method(http.MethodPost, "/reject", Handler(api.AdminRejectAppointment))
+			r.With(api.RequirePermission(model.PermAppointmentsReschedule)).Method(http.MethodPost, "/reschedule", Handler(api.AdminRescheduleAppointment))
+			r.With(api.RequirePermission(model.PermAppointmentsReschedule)).Method(http.MethodPost, "/reschedule-request/approve", Handler(api.AdminApproveRescheduleRequest))
+			r.With(api.RequirePermission(model.PermAppointmentsCancel)).Method(http.MethodPost, "/cancel", Handler(api.AdminCancelAppointment))
+			r.With(api.RequirePermission(model.PermAppointmentsNotes)).Method(http.MethodPut, "/notes", Handler(api.AdminUpdateNotes))
+			r.With(api.RequirePermission(model.PermAppointmentsUpdateStatus)).Method(http.MethodPut, "/status", Handler(api.AdminUpdateAppointmentStatus))
+		})
+	})
+
+	// Admin dashboard statistics. Hospital scoped staff only see their hospital.
+	mux.Route("/stats", func(r chi.Router) {
+		r.Use(api.RequireLogin)
+		r.With(api.RequirePermission(model.PermAppointmentsRead)).Method(http.MethodGet, "/", Handler(api.GetAdminStatsHandler))
 	})
 
 	// Admin lab test routes
 	mux.Route("/tests", func(r chi.Router) {
 		r.Use(api.RequireLogin)
-		r.Use(api.RequireAdmin)
-		r.Method(http.MethodGet, "/", Handler(api.GetAllLabTestsHandler))
-		r.Method(http.MethodGet, "/available", Handler(api.GetAvailableTestsForSelectionHandler))
-		r.Method(http.MethodPost, "/", Handler(api.CreateLabTestHandler))
-		r.Method(http.MethodPut, "/{labTestID}", Handler(api.UpdateLabTestHandler))
-		r.Method(http.MethodDelete, "/{labTestID}", Handler(api.DeleteLabTestHandler))
+		r.With(api.RequirePermission(model.PermLabTestsPrice)).Method(http.MethodGet, "/", Handler(api.GetAllLabTestsHandler))
+		r.With(api.RequirePermission(model.PermLabTestsPrice)).Method(http.MethodGet, "/available", Handler(api.GetAvailableTestsForSelectionHandler))
+		r.With(api.RequirePermission(model.PermLabTestsWrite)).Method(http.MethodPost, "/", Handler(api.CreateLabTestHandler))
+		r.With(api.RequirePermission(model.PermLabTestsWrite)).Method(http.MethodPut, "/{labTestID}", Handler(api.UpdateLabTestHandler))
+		r.With(api.RequirePermission(model.PermLabTestsWrite)).Method(http.MethodDelete, "/{labTestID}", Handler(api.DeleteLabTestHandler))
 	})
 
 	// Admin hospital routes
 	mux.Route("/hospitals", func(r chi.Router) {
 		r.Use(api.RequireLogin)
-		r.Use(api.RequireAdmin)
-		r.Method(http.MethodGet, "/", Handler(api.GetHospitals))
-		r.Method(http.MethodPost, "/", Handler(api.CreateHospital))
-		r.Method(http.MethodDelete, "/{hospitalID}", Handler(api.DeleteHospital))
-		r.Method(http.MethodGet, "/{hospitalID}/lab-tests", Handler(api.GetHospitalLabTests))
-		r.Method(http.MethodPost, "/{hospitalID}/lab-tests", Handler(api.CreateHospitalLabTest))
+		r.With(api.RequirePermission(model.PermHospitalsWrite)).Method(http.MethodGet, "/", Handler(api.GetHospitals))
+		r.With(api.RequirePermission(model.PermHospitalsWrite)).Method(http.MethodPost, "/", Handler(api.CreateHospital))
+		r.With(api.RequirePermission(model.PermHospitalsWrite)).Method(http.MethodDelete, "/{hospitalID}", Handler(api.DeleteHospital))
+
+		r.Group(func(r chi.Router) {
+			r.Use(api.RequirePermission(model.PermLabTestsPrice))
+			r.Use(api.RequireHospitalScope)
+			r.Method(http.MethodGet, "/{hospitalID}/lab-tests", Handler(api.GetHospitalLabTests))
+			r.Method(http.MethodPost, "/{hospitalID}/lab-tests", Handler(api.CreateHospitalLabTest))
+		})
+	})
+
+	// Admin doctor routes
+	mux.Route("/doctors", func(r chi.Router) {
+		r.Use(api.RequireLogin)
+		r.Use(api.RequirePermission(model.PermDoctorsWrite))
+		r.Method(http.MethodGet, "/", Handler(api.GetDoctors))
+		r.Method(http.MethodPost, "/", Handler(api.CreateDoctor))
+		r.Method(http.MethodGet, "/{doctorID}", Handler(api.GetDoctor))
+		r.Method(http.MethodPut, "/{doctorID}", Handler(api.UpdateDoctor))
+		r.Method(http.MethodDelete, "/{doctorID}", Handler(api.DeleteDoctor))
+	})
+
+	// Admin provider schedule routes
+	mux.Route("/schedules", func(r chi.Router) {
+		r.Use(api.RequireLogin)
+		r.Use(api.RequirePermission(model.PermSchedulesWrite))
+		r.Route("/{providerType}/{providerID}", func(r chi.Router) {
+			r.Use(api.RequireProviderScope)
+			r.Method(http.MethodGet, "/", Handler(api.GetProviderSchedules))
+			r.Method(http.MethodPut, "/", Handler(api.ReplaceProviderSchedules))
+		})
+	})
+
+	// Admin notification outbox routes
+	mux.Route("/notifications", func(r chi.Router) {
+		r.Use(api.RequireLogin)
+		r.Use(api.RequirePermission(model.PermNotificationsManage))
+		r.Method(http.MethodGet, "/", Handler(api.GetNotifications))
+		r.Method(http.MethodGet, "/{notificationID}", Handler(api.GetNotification))
+		r.Method(http.MethodPost, "/{notificationID}/replay", Handler(api.ReplayNotification))
+	})
+
+	// Admin role routes
+	mux.Group(func(r chi.Router) {
+		r.Use(api.RequireLogin)
+		r.Use(api.RequirePermission(model.PermRolesAssign))
+		r.Method(http.MethodGet, "/roles", Handler(api.GetRoles))
+		r.Method(http.MethodPut, "/users/{userID}/role", Handler(api.AssignUserRole))
+		r.Method(http.MethodPost, "/invites", Handler(api.CreateAdminInvite))
 	})
 
 	return mux
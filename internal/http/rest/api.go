@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -25,9 +27,12 @@ type Handler func(w http.ResponseWriter, r *http.Request) *ServerResponse
 
 func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	response := h(w, r)
+	if response.failed {
+		logFailure(r, response)
+	}
 	responseByte, err := json.Marshal(response)
 	if err != nil {
-		writeErrorResponse(w, err, values.Error, "unable to marshal server response")
+		writeErrorResponse(w, r, err, values.Error, "unable to marshal server response")
 		return
 	}
 	writeJSONResponse(w, responseByte, response.StatusCode)
@@ -37,6 +42,13 @@ type API struct {
 	Server *http.Server
 	Config *config.Config
 	Deps   *deps.Dependencies
+
+	// draining is set once shutdown starts so readiness checks fail and load
+	// balancers stop sending traffic before the server closes
+	draining atomic.Bool
+
+	statsCache     statsCache
+	trustedProxies trustedProxies
 }
 
 func (api *API) Serve() error {
@@ -46,6 +58,9 @@ func (api *API) Serve() error {
 		ReadTimeout:  defaultReadTimeout,
 		WriteTimeout: defaultWriteTimeout,
 		Handler:      api.setUpServerHandler(),
+		BaseContext: func(net.Listener) context.Context {
+			return api.rootContext()
+		},
 	}
 	return api.Server.ListenAndServe()
 }
@@ -53,8 +68,10 @@ func (api *API) Serve() error {
 func (api *API) setUpServerHandler() http.Handler {
 	mux := chi.NewRouter()
 
-	// Add the RequestTracing middleware to all routes
-	mux.Use(RequestTracing)
+	// Add the RequestTracing, access log and metrics middleware to all routes
+	mux.Use(api.RequestTracing)
+	mux.Use(api.AccessLog)
+	mux.Use(api.RecordMetrics)
 
 	mux.Get("/",
 		func(w http.ResponseWriter, r *http.Request) {
@@ -62,22 +79,43 @@ func (api *API) setUpServerHandler() http.Handler {
 		},
 	)
 
-	mux.Mount("/health", HealthRoutes())
+	mux.Mount("/health", api.HealthRoutes())
+	mux.With(api.RequireMetricsToken).Method(http.MethodGet, "/metrics", api.Deps.Metrics.Registry.Handler())
 	mux.Mount("/auth", api.AuthRoutes())
+	mux.Mount("/profile", api.ProfileRoutes())
 	mux.Mount("/hospitals", api.HospitalRoutes())
 	mux.Mount("/lab-tests", api.LabTestRoutes())
+	mux.Mount("/doctors", api.DoctorRoutes())
+	mux.Mount("/slots", api.SlotRoutes())
 	mux.Mount("/appointments", api.AppointmentRoutes())
 	mux.Mount("/admin", api.AdminRoutes())
 	return mux
 }
 
-func (a *API) Shutdown() error {
-	// err := a.Deps.DAL.DB.Close()
-	// if err != nil {
-	// 	return err
-	// }
+// rootContext is the parent of every request context and of the contexts
+// helpers open for their queries. It is canceled when shutdown stops the
+// background work, so queries still running past the deadline are abandoned.
+func (api *API) rootContext() context.Context {
+	return api.Deps.Lifecycle.Context()
+}
+
+// StartDraining marks the API as not ready. Requests are still served until
+// Shutdown is called.
+func (a *API) StartDraining() {
+	a.draining.Store(true)
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests to
+// finish, or for ctx to end. Without a deadline on ctx it waits at most
+// defaultShutdownPeriod.
+func (a *API) Shutdown(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultShutdownPeriod)
+		defer cancel()
+	}
 
-	err := a.Server.Shutdown(context.Background())
+	err := a.Server.Shutdown(ctx)
 	if err != nil {
 		return err
 	}
@@ -1,7 +1,6 @@
 package rest
 
 import (
-	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -20,13 +19,19 @@ func (api *API) AppointmentRoutes() chi.Router {
 	mux.Route("/", func(r chi.Router) {
 		r.Use(api.RequireLogin)
 		r.Method(http.MethodGet, "/", Handler(api.FetchAllAppointmentsHandler))
-		r.Method(http.MethodPost, "/lab-test-appointment", Handler(api.CreateLabTestAppointmentHandler))
-		r.Method(http.MethodPost, "/lab-test", Handler(api.LabAppointment))
+		r.Group(func(r chi.Router) {
+			r.Use(api.RequireVerifiedEmail)
+			r.Method(http.MethodPost, "/lab-test-appointment", Handler(api.CreateLabTestAppointmentHandler))
+			r.Method(http.MethodPost, "/lab-test", Handler(api.LabAppointment))
+			r.Method(http.MethodPost, "/doctor", Handler(api.CreateDoctorAppointmentHandler))
+			r.Method(http.MethodPost, "/ivf", Handler(api.CreateIVFAppointmentHandler))
+		})
 		r.Method(http.MethodGet, "/status-stages", Handler(api.GetAppointmentStatusStages))
 		r.Method(http.MethodGet, "/{id}", Handler(api.GetAppointmentDetails))
 		r.Method(http.MethodGet, "/{id}/history", Handler(api.GetAppointmentHistory))
 		r.Method(http.MethodPut, "/{id}/reschedule/accept", Handler(api.AcceptRescheduleOffer))
 		r.Method(http.MethodPut, "/{id}/reschedule/reject", Handler(api.RejectRescheduleOffer))
+		r.Method(http.MethodPut, "/{id}/reschedule-request", Handler(api.RequestReschedule))
 		r.Method(http.MethodDelete, "/{id}", Handler(api.CancelAppointment))
 	})
 	return mux
@@ -39,18 +44,10 @@ func (api *API) LabAppointment(_ http.ResponseWriter, r *http.Request) *ServerRe
 
 	var appointment model.LabAppointmentReq
 	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &appointment); decodeErr != nil {
-		log.Println(decodeErr)
 		return respondWithError(decodeErr, "unable to parse lab appointment request", values.BadRequestBody, &tc)
 	}
 
 	appointment.UserID = userID.(int)
-	log.Println("appointment", appointment)
-
-	// var details model.LabTestAppointmentDetails
-	// if decodeErr := util.DecodeJSONBody(&tc, r.Body, &details); decodeErr != nil {
-	// 	log.Println(decodeErr)
-	// 	return respondWithError(decodeErr, "unable to parse lab appointment details request", values.BadRequestBody, &tc)
-	// }
 
 	newAppointment, status, message, err := api.CreateLabTestAppointmentH(appointment)
 	if err != nil {
@@ -72,20 +69,15 @@ func (api *API) CreateLabTestAppointmentHandler(_ http.ResponseWriter, r *http.R
 
 	var req model.CreateLabTestAppointmentRequest
 	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
-		log.Println(decodeErr)
 		return respondWithError(decodeErr, "unable to parse lab appointment request", values.BadRequestBody, &tc)
 	}
-	log.Println("req", req)
 	appointmentDatetime, err := time.Parse("2006-01-02 15:04:05", req.AppointmentDate)
-
-	log.Println("appointmentDate", appointmentDatetime)
 	if err != nil {
 		return respondWithError(err, "invalid date format", values.BadRequestBody, &tc)
 	}
 
 	//add user id to the req object
 	req.UserID = userID.(int)
-	// log.Println("userID", req.UserID)
 	appointment := &model.AppointmentDetails{
 		UserID:              req.UserID,
 		AppointmentType:     "lab_test",
@@ -114,14 +106,58 @@ func (api *API) CreateLabTestAppointmentHandler(_ http.ResponseWriter, r *http.R
 	}
 }
 
+func (api *API) CreateDoctorAppointmentHandler(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	var req model.CreateDoctorAppointmentRequest
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse doctor appointment request", values.BadRequestBody, &tc)
+	}
+
+	req.UserID = r.Context().Value("user_id").(int)
+
+	newAppointment, status, message, err := api.CreateDoctorAppointmentHelper(req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       newAppointment,
+	}
+}
+
+func (api *API) CreateIVFAppointmentHandler(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	var req model.CreateIVFAppointmentRequest
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse ivf appointment request", values.BadRequestBody, &tc)
+	}
+
+	req.UserID = r.Context().Value("user_id").(int)
+
+	newAppointment, status, message, err := api.CreateIVFAppointmentHelper(req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       newAppointment,
+	}
+}
+
 func (api *API) FetchAllAppointmentsHandler(_ http.ResponseWriter, r *http.Request) *ServerResponse {
 
 	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
 
 	isAdmin, _ := r.Context().Value("is_admin").(bool)
 
-	log.Println("admin? ", isAdmin)
-
 	queryParams := r.URL.Query()
 	filter := model.AppointmentFilter{
 		Date:     queryParams.Get("date"),
@@ -129,7 +165,38 @@ func (api *API) FetchAllAppointmentsHandler(_ http.ResponseWriter, r *http.Reque
 		History:  queryParams.Get("history") == "true",
 	}
 
-	// var userIDPtr *int
+	// Upcoming appointments read soonest first, everything else latest first
+	defaultOrder := model.SortDesc
+	if filter.Upcoming {
+		defaultOrder = model.SortAsc
+	}
+	page, err := parsePageRequest(queryParams, model.SortAppointmentDatetime, defaultOrder, 20)
+	if err != nil {
+		return respondWithError(err, "Invalid pagination parameters", values.BadRequestBody, &tc)
+	}
+	filter.Page = page
+
+	if statuses := queryParams["status"]; len(statuses) > 0 {
+		filter.Status = statuses
+	}
+	if appointmentTypes := queryParams["appointment_type"]; len(appointmentTypes) > 0 {
+		filter.AppointmentType = appointmentTypes
+	}
+	if hospitalID := queryParams.Get("hospital_id"); hospitalID != "" {
+		hid, err := strconv.Atoi(hospitalID)
+		if err != nil {
+			return respondWithError(err, "hospital_id must be a number", values.BadRequestBody, &tc)
+		}
+		filter.HospitalID = &hid
+	}
+	if testTypeID := queryParams.Get("test_type_id"); testTypeID != "" {
+		tid, err := strconv.Atoi(testTypeID)
+		if err != nil {
+			return respondWithError(err, "test_type_id must be a number", values.BadRequestBody, &tc)
+		}
+		filter.TestTypeID = &tid
+	}
+
 	if !isAdmin {
 		userIDVal := r.Context().Value("user_id")
 		userID, ok := userIDVal.(int)
@@ -137,18 +204,8 @@ func (api *API) FetchAllAppointmentsHandler(_ http.ResponseWriter, r *http.Reque
 			return respondWithError(nil, "user_id not found in context", values.NotAuthorised, &tc)
 		}
 		filter.UserID = userID
-		// userIDPtr = &userID
 	}
 
-	// log.Println("userID", *userIDPtr)
-
-	// if !isAdmin && userIDPtr != nil {
-
-	// 	filter.UserID = *userIDPtr
-	// }
-
-	log.Println("Filters", filter.UserID)
-
 	appointments, status, message, err := api.FetchAllAppointments(filter)
 	if err != nil {
 		return respondWithError(err, message, status, &tc)
@@ -171,21 +228,21 @@ func (api *API) AdminFetchAllAppointments(_ http.ResponseWriter, r *http.Request
 
 	queryParams := r.URL.Query()
 	filter := model.AdminAppointmentFilter{
-		Page:  1,
-		Limit: 50,
+		HospitalID: scopedHospitalID(r),
 	}
 
 	// Parse query parameters
-	if page := queryParams.Get("page"); page != "" {
-		if p, err := strconv.Atoi(page); err == nil {
-			filter.Page = p
-		}
+	page, err := parsePageRequest(queryParams, model.SortCreatedAt, model.SortDesc, 50)
+	if err != nil {
+		return respondWithError(err, "Invalid pagination parameters", values.BadRequestBody, &tc)
 	}
-	if limit := queryParams.Get("limit"); limit != "" {
-		if l, err := strconv.Atoi(limit); err == nil {
-			filter.Limit = l
-		}
+	filter.Page = page
+
+	terms, err := parseSearchTerms(queryParams.Get("q"))
+	if err != nil {
+		return respondWithError(err, "Invalid search", values.BadRequestBody, &tc)
 	}
+	filter.SearchTerms = terms
 	if dateFrom := queryParams.Get("date_from"); dateFrom != "" {
 		filter.DateFrom = &dateFrom
 	}
@@ -197,6 +254,12 @@ func (api *API) AdminFetchAllAppointments(_ http.ResponseWriter, r *http.Request
 			filter.ProviderID = &pid
 		}
 	}
+	// Scoped staff are always pinned to their own hospital
+	if hospitalID := queryParams.Get("hospital_id"); hospitalID != "" && filter.HospitalID == nil {
+		if hid, err := strconv.Atoi(hospitalID); err == nil {
+			filter.HospitalID = &hid
+		}
+	}
 
 	// Parse status and appointment_type arrays
 	if statuses := queryParams["status"]; len(statuses) > 0 {
@@ -324,6 +387,34 @@ func (api *API) AdminRescheduleAppointment(_ http.ResponseWriter, r *http.Reques
 	}
 }
 
+func (api *API) AdminApproveRescheduleRequest(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	appointmentID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return respondWithError(err, "Invalid appointment ID", values.BadRequestBody, &tc)
+	}
+
+	var req model.ApproveRescheduleRequestReq
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse request", values.BadRequestBody, &tc)
+	}
+
+	adminID := r.Context().Value("user_id").(int)
+
+	status, message, err := api.AdminApproveRescheduleRequestHelper(appointmentID, adminID, req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       nil,
+	}
+}
+
 func (api *API) AdminCancelAppointment(_ http.ResponseWriter, r *http.Request) *ServerResponse {
 	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
 
@@ -412,6 +503,7 @@ func (api *API) GetAppointmentStatusStages(_ http.ResponseWriter, r *http.Reques
 		string(model.StatusScheduled),
 		string(model.StatusRescheduleOffered),
 		string(model.StatusRescheduleAccepted),
+		string(model.StatusRescheduleRequested),
 		string(model.StatusInProgress),
 		string(model.StatusCompleted),
 		string(model.StatusCanceled),
@@ -439,7 +531,6 @@ func (api *API) GetAppointmentDetails(_ http.ResponseWriter, r *http.Request) *S
 
 	appointment, status, message, err := api.GetAppointmentDetailsHelper(appointmentID, userID)
 	if err != nil {
-		log.Println(err)
 		return respondWithError(err, message, status, &tc)
 	}
 
@@ -479,6 +570,34 @@ func (api *API) AcceptRescheduleOffer(_ http.ResponseWriter, r *http.Request) *S
 	}
 }
 
+func (api *API) RequestReschedule(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	appointmentID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return respondWithError(err, "Invalid appointment ID", values.BadRequestBody, &tc)
+	}
+
+	var req model.RescheduleRequestReq
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse request", values.BadRequestBody, &tc)
+	}
+
+	userID := r.Context().Value("user_id").(int)
+
+	status, message, err := api.RequestRescheduleHelper(appointmentID, userID, req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       nil,
+	}
+}
+
 func (api *API) RejectRescheduleOffer(_ http.ResponseWriter, r *http.Request) *ServerResponse {
 	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
 
@@ -555,58 +674,22 @@ func (api *API) GetAppointmentHistory(_ http.ResponseWriter, r *http.Request) *S
 
 // Helper function to determine next actions for user
 func (api *API) getNextActionsForUser(status model.AppointmentStatus) []string {
-	switch status {
-	case model.StatusPending:
-		return []string{"cancel"}
-	case model.StatusConfirmed:
-		return []string{"cancel"}
-	case model.StatusScheduled:
-		return []string{"cancel"}
-	case model.StatusRescheduleOffered:
-		return []string{"accept_reschedule", "reject_reschedule", "cancel"}
-	case model.StatusRescheduleAccepted:
-		return []string{"cancel"}
-	case model.StatusInProgress:
-		return []string{}
-	case model.StatusCompleted:
-		return []string{}
-	case model.StatusCanceled:
-		return []string{}
-	case model.StatusRejected:
-		return []string{}
-	case model.StatusNoShow:
-		return []string{}
-	default:
-		return []string{}
-	}
+	return nextActions(model.UserAppointmentActions, status)
 }
 
 // Helper function to determine next actions for admin
 func (api *API) getNextActionsForAdmin(status model.AppointmentStatus) []string {
-	switch status {
-	case model.StatusPending:
-		return []string{"confirm", "reject", "reschedule", "cancel"}
-	case model.StatusConfirmed:
-		return []string{"reschedule", "cancel", "mark_in_progress"}
-	case model.StatusScheduled:
-		return []string{"reschedule", "cancel", "mark_in_progress"}
-	case model.StatusRescheduleOffered:
-		return []string{"cancel", "offer_new_reschedule"}
-	case model.StatusRescheduleAccepted:
-		return []string{"confirm", "cancel", "mark_in_progress"}
-	case model.StatusInProgress:
-		return []string{"mark_completed", "mark_no_show"}
-	case model.StatusCompleted:
-		return []string{}
-	case model.StatusCanceled:
-		return []string{}
-	case model.StatusRejected:
-		return []string{}
-	case model.StatusNoShow:
-		return []string{}
-	default:
-		return []string{}
+	return nextActions(model.AdminAppointmentActions, status)
+}
+
+func nextActions(actions []model.AppointmentAction, status model.AppointmentStatus) []string {
+	names := []string{}
+	for _, action := range actions {
+		if action.AvailableFrom(status) {
+			names = append(names, action.Name)
+		}
 	}
+	return names
 }
 
 func (api *API) AdminUpdateAppointmentStatus(_ http.ResponseWriter, r *http.Request) *ServerResponse {
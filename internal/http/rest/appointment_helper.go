@@ -2,61 +2,161 @@ package rest
 
 import (
 	"context"
-	"encoding/json"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
-	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util/apperr"
 	"github.com/bwise1/your_care_api/util/values"
 )
 
-func (api *API) CreateDoctorAppointment_H(w http.ResponseWriter, r *http.Request) {
-	var appointment model.Appointment
-	var details model.DoctorAppointmentDetails
+func (api *API) CreateDoctorAppointmentHelper(req model.CreateDoctorAppointmentRequest) (model.AppointmentDetails, string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
 
-	// Decode the request body into the appointment and details structs
-	if err := json.NewDecoder(r.Body).Decode(&appointment); err != nil {
-		http.Error(w, fmt.Sprintf("%s [CrDoAp]", values.BadRequestBody), http.StatusBadRequest)
-		return
+	if req.DoctorID == 0 {
+		return model.AppointmentDetails{}, values.BadRequestBody, "doctor_id is required", apperr.Validation(apperr.FieldError{Field: "doctor_id", Message: "is required"})
 	}
-	if err := json.NewDecoder(r.Body).Decode(&details); err != nil {
-		http.Error(w, fmt.Sprintf("%s [CrDoAp]", values.BadRequestBody), http.StatusBadRequest)
-		return
+
+	appointmentDatetime, err := time.ParseInLocation("2006-01-02 15:04", req.AppointmentDate+" "+req.AppointmentTime, time.Local)
+	if err != nil {
+		return model.AppointmentDetails{}, values.BadRequestBody, "invalid date or time format, expected YYYY-MM-DD and HH:MM", apperr.Validation(apperr.FieldError{Field: "appointment_time", Message: "must be a date and time in YYYY-MM-DD and HH:MM format"})
+	}
+	if !appointmentDatetime.After(time.Now()) {
+		return model.AppointmentDetails{}, values.BadRequestBody, "appointment must be in the future", apperr.Validation(apperr.FieldError{Field: "appointment_date", Message: "must be in the future"})
 	}
 
-	// Set context with a timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	doctor, err := api.GetDoctorByIDRepo(ctx, req.DoctorID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.AppointmentDetails{}, values.NotFound, "Doctor not found", apperr.Wrap(err, apperr.CodeNotFound, "Doctor not found")
+		}
+		return model.AppointmentDetails{}, values.Error, fmt.Sprintf("%s [CrDoAp]", values.SystemErr), err
+	}
+
+	if !doctorAvailableAt(doctor, appointmentDatetime) {
+		message := fmt.Sprintf("Doctor is only available between %s and %s", doctor.AvailableFrom, doctor.AvailableTo)
+		return model.AppointmentDetails{}, values.Unprocessable, message, apperr.New(apperr.CodeSlotUnavailable, message)
+	}
+
+	appointment := model.AppointmentDetails{
+		UserID:              req.UserID,
+		AppointmentType:     string(model.TypeDoctor),
+		AppointmentDatetime: &appointmentDatetime,
+		Status:              string(model.StatusPending),
+	}
+	details := model.DoctorAppointment{
+		DoctorID:        req.DoctorID,
+		ReasonForVisit:  optionalString(req.ReasonForVisit),
+		Symptoms:        optionalString(req.Symptoms),
+		AdditionalNotes: optionalString(req.AdditionalNotes),
+	}
 
-	// Create the doctor's appointment
 	appointmentID, err := api.CreateDoctorAppointment(ctx, appointment, details)
 	if err != nil {
-		log.Println("error creating doctor appointment", err)
-		http.Error(w, fmt.Sprintf("%s [CrDoAp]", values.SystemErr), http.StatusInternalServerError)
-		return
-	}
-
-	// Respond with the created appointment ID
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":  values.Success,
-		"message": "Doctor appointment created successfully",
-		"data":    appointmentID,
-	})
+		if errors.Is(err, model.ErrSlotUnavailable) {
+			message := fmt.Sprintf("Doctor %s has no slot at the selected time, check GET /slots for availability", doctor.Name)
+			return model.AppointmentDetails{}, values.Unprocessable, message, apperr.Wrap(err, apperr.CodeSlotUnavailable, message)
+		}
+		status, message, err := appointmentWriteError(err, "CrDoAp")
+		return model.AppointmentDetails{}, status, message, err
+	}
+
+	appointment.ID = appointmentID
+	details.AppointmentID = appointmentID
+	appointment.DoctorDetails = &details
+
+	return appointment, values.Created, "Doctor appointment created and is pending approval", nil
+}
+
+func (api *API) CreateIVFAppointmentHelper(req model.CreateIVFAppointmentRequest) (model.AppointmentDetails, string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	appointmentDatetime, err := time.ParseInLocation("2006-01-02 15:04", req.AppointmentDate+" "+req.AppointmentTime, time.Local)
+	if err != nil {
+		return model.AppointmentDetails{}, values.BadRequestBody, "invalid date or time format, expected YYYY-MM-DD and HH:MM", apperr.Validation(apperr.FieldError{Field: "appointment_time", Message: "must be a date and time in YYYY-MM-DD and HH:MM format"})
+	}
+	if !appointmentDatetime.After(time.Now()) {
+		return model.AppointmentDetails{}, values.BadRequestBody, "appointment must be in the future", apperr.Validation(apperr.FieldError{Field: "appointment_date", Message: "must be in the future"})
+	}
+
+	if message, err := validateIVFDetails(&req); err != nil {
+		return model.AppointmentDetails{}, values.BadRequestBody, message, err
+	}
+
+	appointment := model.AppointmentDetails{
+		UserID:              req.UserID,
+		AppointmentType:     string(model.TypeIVF),
+		AppointmentDatetime: &appointmentDatetime,
+		Status:              string(model.StatusPending),
+	}
+	details := model.IVFAppointmentDetails{
+		TreatmentType:       &req.TreatmentType,
+		CycleDay:            req.CycleDay,
+		SpecialInstructions: optionalString(req.SpecialInstructions),
+		PreparationNotes:    optionalString(req.PreparationNotes),
+	}
+
+	appointmentID, err := api.CreateIVFAppointment(ctx, appointment, details)
+	if err != nil {
+		status, message, err := appointmentWriteError(err, "CrIvAp")
+		return model.AppointmentDetails{}, status, message, err
+	}
+
+	appointment.ID = appointmentID
+	details.AppointmentID = appointmentID
+	appointment.IVFDetails = &details
+
+	return appointment, values.Created, "IVF appointment created and is pending approval", nil
+}
+
+// validateIVFDetails normalises the treatment type and checks the cycle day.
+// A cycle day is required for everything except an initial consultation.
+func validateIVFDetails(req *model.CreateIVFAppointmentRequest) (string, error) {
+	req.TreatmentType = strings.ToLower(strings.TrimSpace(req.TreatmentType))
+	if !slices.Contains(model.IVFTreatmentTypes, req.TreatmentType) {
+		message := fmt.Sprintf("treatment_type must be one of: %s", strings.Join(model.IVFTreatmentTypes, ", "))
+		return message, apperr.Validation(apperr.FieldError{Field: "treatment_type", Message: fmt.Sprintf("must be one of: %s", strings.Join(model.IVFTreatmentTypes, ", "))})
+	}
+
+	if req.CycleDay == nil {
+		if req.TreatmentType != model.IVFConsultation {
+			return "cycle_day is required for this treatment type", apperr.Validation(apperr.FieldError{Field: "cycle_day", Message: "is required for this treatment type"})
+		}
+		return "", nil
+	}
+	if *req.CycleDay < 1 || *req.CycleDay > model.MaxIVFCycleDay {
+		message := fmt.Sprintf("cycle_day must be between 1 and %d", model.MaxIVFCycleDay)
+		return message, apperr.Validation(apperr.FieldError{Field: "cycle_day", Message: fmt.Sprintf("must be between 1 and %d", model.MaxIVFCycleDay)})
+	}
+	return "", nil
 }
 
 func (api *API) CreateLabTestAppointmentH(appointment model.LabAppointmentReq) (model.Appointment, string, string, error) {
 
 	// Set context with a timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
+	// The notpast rule on the date lets through today at a time already gone
+	appointmentDatetime, err := time.ParseInLocation("2006-01-02 15:04", appointment.AppointmentDate+" "+appointment.AppointmentTime, time.Local)
+	if err != nil {
+		return model.Appointment{}, values.BadRequestBody, "invalid date or time format, expected YYYY-MM-DD and HH:MM", apperr.Validation(apperr.FieldError{Field: "appointment_time", Message: "must be a date and time in YYYY-MM-DD and HH:MM format"})
+	}
+	if !appointmentDatetime.After(time.Now()) {
+		return model.Appointment{}, values.BadRequestBody, "appointment must be in the future", apperr.Validation(apperr.FieldError{Field: "appointment_date", Message: "must be in the future"})
+	}
+
 	// Create the lab test appointment
 	appointmentID, err := api.CreateLabTestAppointment(ctx, appointment)
 	if err != nil {
-		return model.Appointment{}, values.Error, fmt.Sprintf("%s [CrLaAp]", values.SystemErr), err
+		status, message, err := appointmentWriteError(err, "CrLaAp")
+		return model.Appointment{}, status, message, err
 	}
 
 	newAppointment := model.Appointment{
@@ -72,13 +172,13 @@ func (api *API) CreateLabTestAppointmentH(appointment model.LabAppointmentReq) (
 func (api *API) CreateLabTestAppointmentHelper(appointment model.AppointmentDetails, labAppt model.LabTestAppointment) (model.AppointmentDetails, string, string, error) {
 
 	// Set context with a timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 	// Create the lab test appointment
 	appointmentID, err := api.CreateLabTestAppRepo(ctx, appointment, labAppt)
-	log.Println("appointmentID", appointmentID)
 	if err != nil {
-		return model.AppointmentDetails{}, values.Error, fmt.Sprintf("%s [CrLaAp]", values.SystemErr), err
+		status, message, err := appointmentWriteError(err, "CrLaAp")
+		return model.AppointmentDetails{}, status, message, err
 	}
 	newAppointment := model.AppointmentDetails{
 		ID:                  appointmentID,
@@ -98,40 +198,92 @@ func (api *API) CreateLabTestAppointmentHelper(appointment model.AppointmentDeta
 	return newAppointment, values.Success, "Lab test appointment created and is pending approval", nil
 }
 
-func (api *API) FetchAllAppointments(filter model.AppointmentFilter) ([]model.AppointmentDetails, string, string, error) {
+func (api *API) FetchAllAppointments(filter model.AppointmentFilter) (model.AppointmentPage, string, string, error) {
 	// Set context with a timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
-	log.Println("userID", filter.UserID)
-	// Fetch all appointments
-	// appointments, err := api.FetchAllAppointmentsRepo(ctx, &filter.UserID)
 	appointments, err := api.FetchFilteredAppointmentsRepo(ctx, filter)
-
 	if err != nil {
-		return nil, values.Error, fmt.Sprintf("%s [FtAlAp]", values.SystemErr), err
+		return model.AppointmentPage{}, values.Error, fmt.Sprintf("%s [FtAlAp]", values.SystemErr), err
 	}
 
-	return appointments, values.Success, "Appointments fetched successfully", nil
+	return newAppointmentPage(appointments, filter.Page), values.Success, "Appointments fetched successfully", nil
+
+}
 
+// appointmentWriteError maps an error from a status-changing repository call
+// to a response status and message, and a typed error carrying the code
+// clients can branch on.
+func appointmentWriteError(err error, code string) (string, string, error) {
+	var transitionErr *model.TransitionError
+	switch {
+	case errors.As(err, &transitionErr):
+		return values.Conflict, transitionErr.Error(), apperr.Wrap(err, apperr.CodeInvalidTransition, transitionErr.Error())
+	case errors.Is(err, model.ErrSlotFull):
+		return values.Conflict, err.Error(), apperr.Wrap(err, apperr.CodeSlotFull, err.Error())
+	case errors.Is(err, model.ErrSlotUnavailable):
+		return values.Unprocessable, err.Error(), apperr.Wrap(err, apperr.CodeSlotUnavailable, err.Error())
+	case errors.Is(err, model.ErrOfferUnavailable):
+		return values.Conflict, err.Error(), apperr.Wrap(err, apperr.CodeOfferUnavailable, err.Error())
+	case errors.Is(err, model.ErrOfferExpired):
+		return values.Conflict, err.Error(), apperr.Wrap(err, apperr.CodeOfferExpired, err.Error())
+	case errors.Is(err, model.ErrRequestUnavailable):
+		return values.Conflict, err.Error(), apperr.Wrap(err, apperr.CodeRequestUnavailable, err.Error())
+	case errors.Is(err, sql.ErrNoRows):
+		return values.NotFound, "Appointment not found", apperr.Wrap(err, apperr.CodeNotFound, "Appointment not found")
+	default:
+		return values.Error, fmt.Sprintf("%s [%s]", values.SystemErr, code), err
+	}
 }
 
 // Admin Helper Functions
 
-func (api *API) AdminFetchAllAppointmentsHelper(filter model.AdminAppointmentFilter) ([]model.AppointmentDetails, string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (api *API) AdminFetchAllAppointmentsHelper(filter model.AdminAppointmentFilter) (model.AppointmentPage, string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
 	appointments, err := api.AdminFetchAllAppointmentsRepo(ctx, filter)
 	if err != nil {
-		return nil, values.Error, fmt.Sprintf("%s [AdFtAlAp]", values.SystemErr), err
+		return model.AppointmentPage{}, values.Error, fmt.Sprintf("%s [AdFtAlAp]", values.SystemErr), err
+	}
+
+	page := newAppointmentPage(appointments, filter.Page)
+	if len(filter.SearchTerms) > 0 {
+		if err := api.highlightAppointments(ctx, page.Appointments, filter.SearchTerms); err != nil {
+			return model.AppointmentPage{}, values.Error, fmt.Sprintf("%s [AdFtAlAp]", values.SystemErr), err
+		}
+	}
+
+	return page, values.Success, "Appointments fetched successfully", nil
+}
+
+// highlightAppointments marks where each search term matched the appointments.
+func (api *API) highlightAppointments(ctx context.Context, appointments []model.AppointmentDetails, terms []string) error {
+	ids := make([]int, len(appointments))
+	for i, appointment := range appointments {
+		ids[i] = appointment.ID
+	}
+
+	texts, err := api.AppointmentSearchTextRepo(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byID := make(map[int]model.AppointmentSearchText, len(texts))
+	for _, text := range texts {
+		byID[text.AppointmentID] = text
 	}
 
-	return appointments, values.Success, "Appointments fetched successfully", nil
+	for i := range appointments {
+		if text, ok := byID[appointments[i].ID]; ok {
+			appointments[i].Highlights = searchHighlights(text, terms)
+		}
+	}
+	return nil
 }
 
 func (api *API) AdminGetAppointmentDetailsHelper(appointmentID int) (model.AppointmentDetails, string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
 	// Use the same simple query as the working list function
@@ -140,69 +292,73 @@ func (api *API) AdminGetAppointmentDetailsHelper(appointmentID int) (model.Appoi
 		return model.AppointmentDetails{}, values.Error, fmt.Sprintf("%s [AdGtApDt]", values.SystemErr), err
 	}
 
+	appointment.RescheduleRequests, err = api.GetRescheduleRequestsRepo(ctx, appointmentID)
+	if err != nil {
+		return model.AppointmentDetails{}, values.Error, fmt.Sprintf("%s [AdGtRsRq]", values.SystemErr), err
+	}
+
 	return appointment, values.Success, "Appointment details retrieved successfully", nil
 }
 
 func (api *API) AdminConfirmAppointmentHelper(appointmentID, adminID int, req model.AdminAppointmentAction) (string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
-	err := api.UpdateAppointmentStatus(ctx, appointmentID, string(model.StatusConfirmed), req.Notes, &adminID)
+	err := api.UpdateAppointmentStatus(ctx, appointmentID, string(model.StatusConfirmed), req.Notes, &adminID, confirmationNotice())
 	if err != nil {
-		return values.Error, fmt.Sprintf("%s [AdCfAp]", values.SystemErr), err
+		return appointmentWriteError(err, "AdCfAp")
 	}
 
-	go api.SendAppointmentConfirmationEmail(appointmentID)
-
 	return values.Success, "Appointment confirmed successfully", nil
 }
 
 func (api *API) AdminRejectAppointmentHelper(appointmentID, adminID int, req model.AdminAppointmentAction) (string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
-	err := api.RejectAppointment(ctx, appointmentID, req.RejectionReason, req.Notes, &adminID)
+	err := api.RejectAppointment(ctx, appointmentID, req.RejectionReason, req.Notes, &adminID, rejectionNotice(req.RejectionReason, req.Notes))
 	if err != nil {
-		return values.Error, fmt.Sprintf("%s [AdRjAp]", values.SystemErr), err
+		return appointmentWriteError(err, "AdRjAp")
 	}
 
-	go api.SendAppointmentRejectionEmail(appointmentID, req.RejectionReason, req.Notes)
-
 	return values.Success, "Appointment rejected", nil
 }
 
 func (api *API) AdminRescheduleAppointmentHelper(appointmentID, adminID int, req model.AdminAppointmentAction) (string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
-	if req.ProposedDate == nil || req.ProposedTime == nil {
-		return values.BadRequestBody, "Proposed date and time are required", fmt.Errorf("missing proposed date/time")
+	slots := req.ProposedSlots
+	if req.ProposedDate != nil && req.ProposedTime != nil {
+		slots = append([]model.RescheduleSlot{{Date: *req.ProposedDate, Time: *req.ProposedTime}}, slots...)
 	}
-
-	err := api.CreateRescheduleOffer(ctx, appointmentID, *req.ProposedDate, *req.ProposedTime, req.Notes, &adminID)
+	slots, deadline, status, message, err := api.prepareRescheduleOffer(slots, "proposed_slots", req.OfferDeadline)
 	if err != nil {
-		return values.Error, fmt.Sprintf("%s [AdRsAp]", values.SystemErr), err
+		return status, message, err
 	}
 
-	go api.SendRescheduleOfferEmail(appointmentID, *req.ProposedDate, *req.ProposedTime, req.Notes)
+	err = api.CreateRescheduleOffer(ctx, appointmentID, slots, deadline, req.Notes, &adminID, rescheduleOfferNotice(slots, deadline, req.Notes))
+	if err != nil {
+		return appointmentWriteError(err, "AdRsAp")
+	}
 
 	return values.Success, "Reschedule offer created", nil
 }
 
 func (api *API) AdminCancelAppointmentHelper(appointmentID, adminID int, req model.AdminAppointmentAction) (string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
-	err := api.UpdateAppointmentStatus(ctx, appointmentID, string(model.StatusCanceled), req.Notes, &adminID)
+	err := api.UpdateAppointmentStatus(ctx, appointmentID, string(model.StatusCanceled), req.Notes, &adminID, nil)
 	if err != nil {
-		return values.Error, fmt.Sprintf("%s [AdCnAp]", values.SystemErr), err
+		return appointmentWriteError(err, "AdCnAp")
 	}
 
 	return values.Success, "Appointment canceled", nil
 }
 
 func (api *API) AdminUpdateNotesHelper(appointmentID int, notes string) (string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
 	err := api.UpdateAppointmentAdminNotes(ctx, appointmentID, notes)
@@ -216,64 +372,103 @@ func (api *API) AdminUpdateNotesHelper(appointmentID int, notes string) (string,
 // User Helper Functions
 
 func (api *API) GetAppointmentDetailsHelper(appointmentID, userID int) (model.DetailedAppointment, string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
 	// Get detailed appointment with all populated fields
 	appointment, err := api.GetDetailedAppointmentRepo(ctx, appointmentID, &userID)
 	if err != nil {
-		log.Println(err)
 		return model.DetailedAppointment{}, values.Error, fmt.Sprintf("%s [GtApDt]", values.SystemErr), err
 	}
+	appointment.NextActions = api.getNextActionsForUser(model.AppointmentStatus(appointment.Status))
 
 	return appointment, values.Success, "Appointment details retrieved successfully", nil
 }
 
 func (api *API) AcceptRescheduleOfferHelper(appointmentID, userID, offerID int) (string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
 	err := api.AcceptRescheduleOfferRepo(ctx, appointmentID, userID, offerID)
 	if err != nil {
-		return values.Error, fmt.Sprintf("%s [AcRsOf]", values.SystemErr), err
+		return appointmentWriteError(err, "AcRsOf")
 	}
 
 	return values.Success, "Reschedule offer accepted", nil
 }
 
 func (api *API) RejectRescheduleOfferHelper(appointmentID, userID, offerID int, reason *string) (string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
 	err := api.RejectRescheduleOfferRepo(ctx, appointmentID, userID, offerID, reason)
 	if err != nil {
-		return values.Error, fmt.Sprintf("%s [RjRsOf]", values.SystemErr), err
+		return appointmentWriteError(err, "RjRsOf")
 	}
 
 	return values.Success, "Reschedule offer rejected", nil
 }
 
+// RequestRescheduleHelper lets a patient propose new times for their
+// appointment. Admins then approve one or answer with an offer.
+func (api *API) RequestRescheduleHelper(appointmentID, userID int, req model.RescheduleRequestReq) (string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return values.BadRequestBody, "A reason for the reschedule is required", apperr.Validation(apperr.FieldError{Field: "reason", Message: "is required"})
+	}
+	slots, message, err := normalizeRescheduleSlots(req.Options, "options")
+	if err != nil {
+		return values.BadRequestBody, message, err
+	}
+
+	err = api.CreateRescheduleRequestRepo(ctx, appointmentID, userID, slots, req.Reason)
+	if err != nil {
+		return appointmentWriteError(err, "RqRsAp")
+	}
+
+	return values.Success, "Reschedule request sent", nil
+}
+
+func (api *API) AdminApproveRescheduleRequestHelper(appointmentID, adminID int, req model.ApproveRescheduleRequestReq) (string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	if req.RequestID == 0 {
+		return values.BadRequestBody, "request_id is required", apperr.Validation(apperr.FieldError{Field: "request_id", Message: "is required"})
+	}
+
+	err := api.ApproveRescheduleRequestRepo(ctx, appointmentID, req.RequestID, req.Notes, adminID, confirmationNotice())
+	if err != nil {
+		return appointmentWriteError(err, "ApRsRq")
+	}
+
+	return values.Success, "Reschedule request approved", nil
+}
+
 func (api *API) CancelAppointmentHelper(appointmentID, userID int) (string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
-	err := api.UpdateAppointmentStatus(ctx, appointmentID, string(model.StatusCanceled), nil, &userID)
+	err := api.CancelUserAppointment(ctx, appointmentID, userID)
 	if err != nil {
-		return values.Error, fmt.Sprintf("%s [CnAp]", values.SystemErr), err
+		return appointmentWriteError(err, "CnAp")
 	}
 
 	return values.Success, "Appointment canceled successfully", nil
 }
 
 func (api *API) GetAppointmentStatusHistory(appointmentID int) ([]model.AppointmentStatusLog, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
 	return api.GetAppointmentStatusHistoryRepo(ctx, appointmentID)
 }
 
 func (api *API) AdminGetAppointmentHistoryHelper(appointmentID int) ([]model.AppointmentStatusLog, string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
 	history, err := api.GetAppointmentStatusHistoryRepo(ctx, appointmentID)
@@ -285,13 +480,13 @@ func (api *API) AdminGetAppointmentHistoryHelper(appointmentID int) ([]model.App
 }
 
 func (api *API) GetAppointmentHistoryHelper(appointmentID, userID int) ([]model.AppointmentStatusLog, string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
 	// First verify that the appointment belongs to the user
 	_, err := api.GetAppointmentDetailsRepo(ctx, appointmentID, userID)
 	if err != nil {
-		return nil, values.NotFound, "Appointment not found or access denied", err
+		return nil, values.NotFound, "Appointment not found or access denied", apperr.Wrap(err, apperr.CodeNotFound, "Appointment not found or access denied")
 	}
 
 	history, err := api.GetAppointmentStatusHistoryRepo(ctx, appointmentID)
@@ -302,180 +497,180 @@ func (api *API) GetAppointmentHistoryHelper(appointmentID, userID int) ([]model.
 	return history, values.Success, "Appointment history retrieved successfully", nil
 }
 
-// Email notification functions
+// Patient notifications. Each is queued in the outbox in the same transaction
+// as the appointment change and delivered by the outbox worker.
 
-func (api *API) SendAppointmentConfirmationEmail(appointmentID int) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// appointmentNotice is the email queued for the patient alongside an appointment
+// change. data builds the template data from the appointment as it stands in
+// that transaction.
+type appointmentNotice struct {
+	template string
+	data     func(appointment AppointmentEmailData) map[string]interface{}
+}
 
-	appointment, err := api.GetAppointmentEmailData(ctx, appointmentID)
-	if err != nil {
-		log.Printf("Error getting appointment data for email: %v", err)
-		return
+func confirmationNotice() *appointmentNotice {
+	return &appointmentNotice{
+		template: "appointmentConfirmed.tmpl",
+		data: func(appointment AppointmentEmailData) map[string]interface{} {
+			return map[string]interface{}{
+				"PatientName":     appointment.PatientName,
+				"AppointmentType": appointment.AppointmentType,
+				"AppointmentDate": appointment.AppointmentDate,
+				"AppointmentTime": appointment.AppointmentTime,
+				"TestName":        appointment.TestName,
+				"HospitalName":    appointment.HospitalName,
+				"PickupType":      appointment.PickupType,
+				"HomeLocation":    appointment.HomeLocation,
+				"AdminNotes":      appointment.AdminNotes,
+			}
+		},
 	}
+}
 
-	emailData := map[string]interface{}{
-		"PatientName":     appointment.PatientName,
-		"AppointmentType": appointment.AppointmentType,
-		"AppointmentDate": appointment.AppointmentDate,
-		"AppointmentTime": appointment.AppointmentTime,
-		"TestName":        appointment.TestName,
-		"HospitalName":    appointment.HospitalName,
-		"PickupType":      appointment.PickupType,
-		"HomeLocation":    appointment.HomeLocation,
-		"AdminNotes":      appointment.AdminNotes,
+func rejectionNotice(rejectionReason, adminNotes *string) *appointmentNotice {
+	return &appointmentNotice{
+		template: "appointmentRejected.tmpl",
+		data: func(appointment AppointmentEmailData) map[string]interface{} {
+			return map[string]interface{}{
+				"PatientName":     appointment.PatientName,
+				"AppointmentType": appointment.AppointmentType,
+				"AppointmentDate": appointment.AppointmentDate,
+				"AppointmentTime": appointment.AppointmentTime,
+				"RejectionReason": rejectionReason,
+				"AdminNotes":      adminNotes,
+			}
+		},
 	}
+}
 
-	if err := api.Deps.Mailer.Send(appointment.PatientEmail, emailData, "appointmentConfirmed.tmpl"); err != nil {
-		log.Printf("Error sending confirmation email: %v", err)
+func rescheduleOfferNotice(slots []model.RescheduleSlot, deadline time.Time, adminNotes *string) *appointmentNotice {
+	proposed := make([]map[string]string, len(slots))
+	for i, slot := range slots {
+		proposed[i] = map[string]string{"Date": slot.Date, "Time": slot.Time}
+	}
+	return &appointmentNotice{
+		template: "appointmentReschedule.tmpl",
+		data: func(appointment AppointmentEmailData) map[string]interface{} {
+			return map[string]interface{}{
+				"PatientName":     appointment.PatientName,
+				"AppointmentType": appointment.AppointmentType,
+				"OriginalDate":    appointment.AppointmentDate,
+				"OriginalTime":    appointment.AppointmentTime,
+				"ProposedSlots":   proposed,
+				"OfferDeadline":   deadline.Format("2006-01-02 15:04"),
+				"AdminNotes":      adminNotes,
+			}
+		},
 	}
 }
 
-func (api *API) SendAppointmentRejectionEmail(appointmentID int, rejectionReason, adminNotes *string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	appointment, err := api.GetAppointmentEmailData(ctx, appointmentID)
+// prepareRescheduleOffer checks the proposed slots, which came from
+// slotsField, and works out when the offer lapses.
+func (api *API) prepareRescheduleOffer(slots []model.RescheduleSlot, slotsField string, offerDeadline *string) ([]model.RescheduleSlot, time.Time, string, string, error) {
+	normalized, message, err := normalizeRescheduleSlots(slots, slotsField)
 	if err != nil {
-		log.Printf("Error getting appointment data for email: %v", err)
-		return
+		return nil, time.Time{}, values.BadRequestBody, message, err
 	}
 
-	emailData := map[string]interface{}{
-		"PatientName":     appointment.PatientName,
-		"AppointmentType": appointment.AppointmentType,
-		"AppointmentDate": appointment.AppointmentDate,
-		"AppointmentTime": appointment.AppointmentTime,
-		"RejectionReason": rejectionReason,
-		"AdminNotes":      adminNotes,
-	}
-
-	if rejectionReason != nil {
-		emailData["RejectionReason"] = *rejectionReason
-	}
-	if adminNotes != nil {
-		emailData["AdminNotes"] = *adminNotes
+	now := time.Now()
+	var deadline time.Time
+	if offerDeadline != nil {
+		parsed, err := time.ParseInLocation("2006-01-02T15:04", *offerDeadline, time.Local)
+		if err != nil {
+			return nil, time.Time{}, values.BadRequestBody, "offer_deadline must be in YYYY-MM-DDTHH:MM format", apperr.Validation(apperr.FieldError{Field: "offer_deadline", Message: "must be in YYYY-MM-DDTHH:MM format"})
+		}
+		if !parsed.After(now) {
+			return nil, time.Time{}, values.BadRequestBody, "offer_deadline must be in the future", apperr.Validation(apperr.FieldError{Field: "offer_deadline", Message: "must be in the future"})
+		}
+		deadline = parsed
+	} else {
+		ttl, err := time.ParseDuration(api.Config.RescheduleOfferTTL)
+		if err != nil {
+			return nil, time.Time{}, values.Error, fmt.Sprintf("%s [RsOfTl]", values.SystemErr), err
+		}
+		deadline = now.Add(ttl)
 	}
 
-	if err := api.Deps.Mailer.Send(appointment.PatientEmail, emailData, "appointmentRejected.tmpl"); err != nil {
-		log.Printf("Error sending rejection email: %v", err)
-	}
+	return normalized, deadline, values.Success, "", nil
 }
 
-func (api *API) SendRescheduleOfferEmail(appointmentID int, proposedDate, proposedTime string, adminNotes *string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	appointment, err := api.GetAppointmentEmailData(ctx, appointmentID)
-	if err != nil {
-		log.Printf("Error getting appointment data for email: %v", err)
-		return
-	}
-
-	emailData := map[string]interface{}{
-		"PatientName":     appointment.PatientName,
-		"AppointmentType": appointment.AppointmentType,
-		"OriginalDate":    appointment.AppointmentDate,
-		"OriginalTime":    appointment.AppointmentTime,
-		"ProposedDate":    proposedDate,
-		"ProposedTime":    proposedTime,
-		"AdminNotes":      adminNotes,
-	}
-
-	if adminNotes != nil {
-		emailData["AdminNotes"] = *adminNotes
+// normalizeRescheduleSlots checks that proposed slots are valid future times
+// and returns them as YYYY-MM-DD and HH:MM with duplicates removed. Errors
+// name field, the request field the slots came from.
+func normalizeRescheduleSlots(slots []model.RescheduleSlot, field string) ([]model.RescheduleSlot, string, error) {
+	if len(slots) == 0 {
+		return nil, "At least one proposed date and time is required", apperr.Validation(apperr.FieldError{Field: field, Message: "is required"})
 	}
-
-	if err := api.Deps.Mailer.Send(appointment.PatientEmail, emailData, "appointmentReschedule.tmpl"); err != nil {
-		log.Printf("Error sending reschedule email: %v", err)
+	if len(slots) > model.MaxRescheduleSlots {
+		return nil, fmt.Sprintf("At most %d alternative slots can be proposed", model.MaxRescheduleSlots), apperr.Validation(apperr.FieldError{Field: field, Message: fmt.Sprintf("must have at most %d slots", model.MaxRescheduleSlots)})
 	}
-}
-
-type AppointmentEmailData struct {
-	PatientName     string
-	PatientEmail    string
-	AppointmentType string
-	AppointmentDate string
-	AppointmentTime string
-	TestName        *string
-	HospitalName    *string
-	PickupType      *string
-	HomeLocation    *string
-	AdminNotes      *string
-}
 
-func (api *API) GetAppointmentEmailData(ctx context.Context, appointmentID int) (*AppointmentEmailData, error) {
-	query := `
-		SELECT
-			CONCAT(u.first_name, ' ', u.last_name) as patient_name,
-			u.email as patient_email,
-			a.appointment_type,
-			DATE_FORMAT(a.appointment_datetime, '%Y-%m-%d') as appointment_date,
-			DATE_FORMAT(a.appointment_datetime, '%H:%i') as appointment_time,
-			lt.name as test_name,
-			h.name as hospital_name,
-			ltad.pickup_type,
-			ltad.home_location,
-			a.admin_notes
-		FROM appointments a
-		JOIN users u ON a.user_id = u.id
-		LEFT JOIN lab_test_appointment_details ltad ON a.id = ltad.appointment_id
-		LEFT JOIN lab_tests lt ON ltad.test_type_id = lt.id
-		LEFT JOIN hospitals h ON ltad.hospital_id = h.id
-		WHERE a.id = ?`
-
-	var data AppointmentEmailData
-	err := api.Deps.DB.GetContext(ctx, &data, query, appointmentID)
-	if err != nil {
-		return nil, err
+	now := time.Now()
+	seen := make(map[string]bool, len(slots))
+	normalized := make([]model.RescheduleSlot, 0, len(slots))
+	for _, slot := range slots {
+		start, err := parseSlotTime(strings.TrimSpace(slot.Date) + " " + strings.TrimSpace(slot.Time))
+		if err != nil {
+			return nil, "Proposed slots must use YYYY-MM-DD dates and HH:MM times", apperr.Validation(apperr.FieldError{Field: field, Message: "must use YYYY-MM-DD dates and HH:MM times"})
+		}
+		if !start.After(now) {
+			return nil, "Proposed slots must be in the future", apperr.Validation(apperr.FieldError{Field: field, Message: "must be in the future"})
+		}
+		key := start.Format("2006-01-02 15:04")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, model.RescheduleSlot{Date: start.Format("2006-01-02"), Time: start.Format("15:04")})
 	}
-
-	return &data, nil
+	return normalized, "", nil
 }
 
 func (api *API) AdminUpdateAppointmentStatusHelper(appointmentID, adminID int, req model.AdminStatusUpdateRequest) (string, string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
 	defer cancel()
 
 	switch req.Status {
 	case "approved":
-		err := api.UpdateAppointmentStatus(ctx, appointmentID, string(model.StatusConfirmed), req.AdminNotes, &adminID)
+		err := api.UpdateAppointmentStatus(ctx, appointmentID, string(model.StatusConfirmed), req.AdminNotes, &adminID, confirmationNotice())
 		if err != nil {
-			return values.Error, fmt.Sprintf("%s [AdUpAp]", values.SystemErr), err
+			return appointmentWriteError(err, "AdUpAp")
 		}
-		go api.SendAppointmentConfirmationEmail(appointmentID)
 		return values.Success, "Appointment approved successfully", nil
 
 	case "rejected":
-		err := api.RejectAppointment(ctx, appointmentID, req.RejectionReason, req.AdminNotes, &adminID)
+		err := api.RejectAppointment(ctx, appointmentID, req.RejectionReason, req.AdminNotes, &adminID, rejectionNotice(req.RejectionReason, req.AdminNotes))
 		if err != nil {
-			return values.Error, fmt.Sprintf("%s [AdRjAp]", values.SystemErr), err
+			return appointmentWriteError(err, "AdRjAp")
 		}
-		go api.SendAppointmentRejectionEmail(appointmentID, req.RejectionReason, req.AdminNotes)
 		return values.Success, "Appointment rejected", nil
 
 	case "rescheduled":
 		if req.NewDateTime == nil {
-			return values.BadRequestBody, "New date and time are required for reschedule", fmt.Errorf("missing new date/time")
+			return values.BadRequestBody, "New date and time are required for reschedule", apperr.Validation(apperr.FieldError{Field: "newDateTime", Message: "is required for reschedule"})
 		}
 
 		// Parse the new datetime and extract date and time
 		newDateTime, err := time.Parse("2006-01-02T15:04", *req.NewDateTime)
 		if err != nil {
-			return values.BadRequestBody, "Invalid date/time format", err
+			return values.BadRequestBody, "Invalid date/time format", apperr.Validation(apperr.FieldError{Field: "newDateTime", Message: "must be in YYYY-MM-DDTHH:MM format"})
 		}
 
-		newDate := newDateTime.Format("2006-01-02")
-		newTime := newDateTime.Format("15:04")
+		slots, deadline, status, message, err := api.prepareRescheduleOffer([]model.RescheduleSlot{{
+			Date: newDateTime.Format("2006-01-02"),
+			Time: newDateTime.Format("15:04"),
+		}}, "newDateTime", nil)
+		if err != nil {
+			return status, message, err
+		}
 
-		err = api.CreateRescheduleOffer(ctx, appointmentID, newDate, newTime, req.AdminNotes, &adminID)
+		err = api.CreateRescheduleOffer(ctx, appointmentID, slots, deadline, req.AdminNotes, &adminID, rescheduleOfferNotice(slots, deadline, req.AdminNotes))
 		if err != nil {
-			return values.Error, fmt.Sprintf("%s [AdRsAp]", values.SystemErr), err
+			return appointmentWriteError(err, "AdRsAp")
 		}
-		go api.SendRescheduleOfferEmail(appointmentID, newDate, newTime, req.AdminNotes)
 		return values.Success, "Reschedule offer sent successfully", nil
 
 	default:
-		return values.BadRequestBody, "Invalid status", fmt.Errorf("unsupported status: %s", req.Status)
+		return values.BadRequestBody, "Invalid status", apperr.Validation(apperr.FieldError{Field: "status", Message: fmt.Sprintf("unsupported status %q", req.Status)})
 	}
 }
@@ -6,11 +6,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/internal/outbox"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -29,7 +29,11 @@ func (api *API) CreateLabTestAppointment(ctx context.Context, appointment model.
 
 		// Combine date and time for TIMESTAMP field
 		appointmentDateTime := appointment.AppointmentDate + " " + appointment.AppointmentTime
-		
+		slotStart, err := parseSlotTime(appointmentDateTime)
+		if err != nil {
+			return err
+		}
+
 		result, err := tx.ExecContext(ctx, appointmentStmt,
 			appointment.UserID,
 			appointment.LabTestID,
@@ -91,13 +95,17 @@ func (api *API) CreateLabTestAppointment(ctx context.Context, appointment model.
 			INSERT INTO appointment_status_history (appointment_id, status, notes, changed_by_user_id)
 			VALUES (?, ?, ?, ?)`
 		_, err = tx.ExecContext(ctx, historyStmt, appointmentID, "pending", "Appointment created", appointment.UserID)
-		return err
+		if err != nil {
+			return err
+		}
+
+		return reserveAppointmentSlot(ctx, tx, appointmentID, slotStart)
 	})
 
 	if err != nil {
-		log.Println("error creating lab test appointment", err)
 		return 0, err
 	}
+	api.Deps.Metrics.AppointmentCreated(string(model.TypeLab))
 	return appointmentID, nil
 }
 
@@ -138,7 +146,7 @@ func (api *API) CreateLabTestAppRepo(ctx context.Context, appointment model.Appo
 		}
 
 		detailsStmt := `
-			INSERT INTO lab_test_appointments (
+			INSERT INTO lab_test_appointment_details (
 				appointment_id,
 				test_type_id,
 				pickup_type,
@@ -172,15 +180,20 @@ func (api *API) CreateLabTestAppRepo(ctx context.Context, appointment model.Appo
 			hospitalID,
 			sql.NullString{String: *labApt.AdditionalInstructions, Valid: labApt.AdditionalInstructions != nil},
 		)
-		return err
+		if err != nil {
+			return err
+		}
+
+		if appointment.AppointmentDatetime == nil {
+			return nil
+		}
+		return reserveAppointmentSlot(ctx, tx, appointmentID, *appointment.AppointmentDatetime)
 	})
 
 	if err != nil {
-		log.Println(appointment)
-		log.Println("error creating lab test appointment", err)
 		return 0, err
 	}
-	log.Println("appointmentID from repo", appointmentID)
+	api.Deps.Metrics.AppointmentCreated(string(model.TypeLab))
 	return appointmentID, nil
 
 }
@@ -216,11 +229,23 @@ func (api *API) FetchAllAppointmentsRepo(ctx context.Context, userID *int) ([]mo
 						'hospital_id', la.hospital_id,
 						'additional_instructions', la.additional_instructions
 					)
-			END as lab_test_details
+			END as lab_test_details,
+			CASE
+				WHEN a.appointment_type = 'ivf' THEN
+					JSON_OBJECT(
+						'id', ia.id,
+						'appointment_id', ia.appointment_id,
+						'treatment_type', ia.treatment_type,
+						'cycle_day', ia.cycle_day,
+						'special_instructions', ia.special_instructions,
+						'preparation_notes', ia.preparation_notes
+					)
+			END as ivf_details
 		FROM
 			appointments a
-			LEFT JOIN doctor_appointments da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
-			LEFT JOIN lab_test_appointments la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'
+			LEFT JOIN doctor_appointment_details da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
+			LEFT JOIN lab_test_appointment_details la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'
+			LEFT JOIN ivf_appointment_details ia ON a.id = ia.appointment_id AND a.appointment_type = 'ivf'
 		WHERE
 			(? IS NULL OR a.user_id = ?)
 		ORDER BY
@@ -229,7 +254,6 @@ func (api *API) FetchAllAppointmentsRepo(ctx context.Context, userID *int) ([]mo
 	var rows []model.AppointmentRow
 	err := api.Deps.DB.SelectContext(ctx, &rows, query, userID, userID)
 	if err != nil {
-		log.Println("error fetching appointments", err)
 		return nil, fmt.Errorf("failed to fetch appointments: %w", err)
 	}
 
@@ -260,10 +284,22 @@ func (api *API) FetchAllAppointmentsRepo(ctx context.Context, userID *int) ([]mo
 			}
 			appointments[i].LabTestDetails = &labTestDetails
 		}
+
+		if row.AppointmentType == "ivf" && len(row.IVFDetailsJSON) > 0 {
+			var ivfDetails model.IVFAppointmentDetails
+			if err := json.Unmarshal(row.IVFDetailsJSON, &ivfDetails); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal ivf details: %w", err)
+			}
+			appointments[i].IVFDetails = &ivfDetails
+		}
 	}
 	return appointments, nil
 }
 
+// appointmentHospitalExpr resolves the hospital of an appointment in list
+// queries: the lab's hospital for lab tests, the doctor's otherwise
+const appointmentHospitalExpr = "COALESCE(la.hospital_id, (SELECT d.hospital_id FROM doctors d WHERE d.id = COALESCE(a.doctor_id, da.doctor_id)))"
+
 func (api *API) FetchFilteredAppointmentsRepo(ctx context.Context, filter model.AppointmentFilter) ([]model.AppointmentDetails, error) {
 	query := `
         SELECT
@@ -294,11 +330,23 @@ func (api *API) FetchFilteredAppointmentsRepo(ctx context.Context, filter model.
                         'hospital_id', la.hospital_id,
                         'additional_instructions', la.additional_instructions
                     )
-            END as lab_test_details
+            END as lab_test_details,
+            CASE
+                WHEN a.appointment_type = 'ivf' THEN
+                    JSON_OBJECT(
+                        'id', ia.id,
+                        'appointment_id', ia.appointment_id,
+                        'treatment_type', ia.treatment_type,
+                        'cycle_day', ia.cycle_day,
+                        'special_instructions', ia.special_instructions,
+                        'preparation_notes', ia.preparation_notes
+                    )
+            END as ivf_details
         FROM
             appointments a
-            LEFT JOIN doctor_appointments da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
-            LEFT JOIN lab_test_appointments la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'
+            LEFT JOIN doctor_appointment_details da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
+            LEFT JOIN lab_test_appointment_details la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'
+            LEFT JOIN ivf_appointment_details ia ON a.id = ia.appointment_id AND a.appointment_type = 'ivf'
         WHERE 1=1`
 
 	var args []interface{}
@@ -315,24 +363,41 @@ func (api *API) FetchFilteredAppointmentsRepo(ctx context.Context, filter model.
 
 	if filter.Upcoming {
 		query += " AND a.appointment_datetime > NOW()"
-		query += " ORDER BY a.appointment_datetime ASC"
 	} else if filter.History {
 		query += " AND a.appointment_datetime < NOW()"
-		query += " ORDER BY a.appointment_datetime DESC"
-	} else {
-		query += " ORDER BY a.appointment_datetime DESC"
 	}
 
-	// log.Println("query", query)
+	if len(filter.Status) > 0 {
+		placeholders := "?" + strings.Repeat(",?", len(filter.Status)-1)
+		query += " AND a.status IN (" + placeholders + ")"
+		for _, status := range filter.Status {
+			args = append(args, status)
+		}
+	}
+
+	if len(filter.AppointmentType) > 0 {
+		placeholders := "?" + strings.Repeat(",?", len(filter.AppointmentType)-1)
+		query += " AND a.appointment_type IN (" + placeholders + ")"
+		for _, appointmentType := range filter.AppointmentType {
+			args = append(args, appointmentType)
+		}
+	}
+
+	if filter.HospitalID != nil {
+		query += " AND " + appointmentHospitalExpr + " = ?"
+		args = append(args, *filter.HospitalID)
+	}
+
+	if filter.TestTypeID != nil {
+		query += " AND la.test_type_id = ?"
+		args = append(args, *filter.TestTypeID)
+	}
+
+	query, args = appendAppointmentPage(query, args, filter.Page)
 
-	// log.Println("Arguments:")
-	// for i, arg := range args {
-	// 	log.Printf("  Arg[%d]: %v (type: %T)", i, arg, arg)
-	// }
 	var rows []model.AppointmentRow
 	err := api.Deps.DB.SelectContext(ctx, &rows, query, args...)
 	if err != nil {
-		log.Println("error fetching appointments", err)
 		return nil, fmt.Errorf("failed to fetch appointments: %w", err)
 	}
 
@@ -363,11 +428,19 @@ func (api *API) FetchFilteredAppointmentsRepo(ctx context.Context, filter model.
 			}
 			appointments[i].LabTestDetails = &labTestDetails
 		}
+
+		if row.AppointmentType == "ivf" && len(row.IVFDetailsJSON) > 0 {
+			var ivfDetails model.IVFAppointmentDetails
+			if err := json.Unmarshal(row.IVFDetailsJSON, &ivfDetails); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal ivf details: %w", err)
+			}
+			appointments[i].IVFDetails = &ivfDetails
+		}
 	}
 	return appointments, nil
 }
 
-func (api *API) CreateDoctorAppointment(ctx context.Context, appointment model.Appointment, details model.DoctorAppointmentDetails) (int, error) {
+func (api *API) CreateDoctorAppointment(ctx context.Context, appointment model.AppointmentDetails, details model.DoctorAppointment) (int, error) {
 	var appointmentID int
 
 	err := api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
@@ -383,11 +456,10 @@ func (api *API) CreateDoctorAppointment(ctx context.Context, appointment model.A
 
 		result, err := tx.ExecContext(ctx, appointmentStmt,
 			appointment.UserID,
-			appointment.DoctorID,
-			"doctor", // Appointment type for doctor appointments
-			appointment.AppointmentDate,
-			appointment.AppointmentTime,
-			appointment.Status,
+			details.DoctorID,
+			string(model.TypeDoctor),
+			appointment.AppointmentDatetime,
+			string(model.StatusPending),
 		)
 		if err != nil {
 			return err
@@ -404,7 +476,7 @@ func (api *API) CreateDoctorAppointment(ctx context.Context, appointment model.A
 		historyStmt := `
 			INSERT INTO appointment_status_history (appointment_id, status, notes, changed_by_user_id)
 			VALUES (?, ?, ?, ?)`
-		_, err = tx.ExecContext(ctx, historyStmt, appointmentID, appointment.Status, "Appointment created", appointment.UserID)
+		_, err = tx.ExecContext(ctx, historyStmt, appointmentID, string(model.StatusPending), "Appointment created", appointment.UserID)
 		if err != nil {
 			return err
 		}
@@ -413,25 +485,99 @@ func (api *API) CreateDoctorAppointment(ctx context.Context, appointment model.A
 		detailsStmt := `
 			INSERT INTO doctor_appointment_details (
 				appointment_id,
+				doctor_id,
 				reason_for_visit,
 				symptoms,
 				additional_notes
-			) VALUES (?, ?, ?, ?)`
+			) VALUES (?, ?, ?, ?, ?)`
 
 		_, err = tx.ExecContext(ctx, detailsStmt,
 			appointmentID,
+			details.DoctorID,
 			details.ReasonForVisit,
 			details.Symptoms,
 			details.AdditionalNotes,
 		)
-		return err
+		if err != nil {
+			return err
+		}
+
+		return reserveAppointmentSlot(ctx, tx, appointmentID, *appointment.AppointmentDatetime)
 	})
 
 	if err != nil {
-		log.Println("error creating doctor appointment", err)
 		return 0, err
 	}
 
+	api.Deps.Metrics.AppointmentCreated(string(model.TypeDoctor))
+	return appointmentID, nil
+}
+
+func (api *API) CreateIVFAppointment(ctx context.Context, appointment model.AppointmentDetails, details model.IVFAppointmentDetails) (int, error) {
+	var appointmentID int
+
+	err := api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		appointmentStmt := `
+			INSERT INTO appointments (
+				user_id,
+				appointment_type,
+				appointment_datetime,
+				status
+			) VALUES (?, ?, ?, ?)`
+
+		result, err := tx.ExecContext(ctx, appointmentStmt,
+			appointment.UserID,
+			string(model.TypeIVF),
+			appointment.AppointmentDatetime,
+			string(model.StatusPending),
+		)
+		if err != nil {
+			return err
+		}
+
+		lastInsertID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		appointmentID = int(lastInsertID)
+
+		// Create initial status history entry
+		historyStmt := `
+			INSERT INTO appointment_status_history (appointment_id, status, notes, changed_by_user_id)
+			VALUES (?, ?, ?, ?)`
+		_, err = tx.ExecContext(ctx, historyStmt, appointmentID, string(model.StatusPending), "Appointment created", appointment.UserID)
+		if err != nil {
+			return err
+		}
+
+		detailsStmt := `
+			INSERT INTO ivf_appointment_details (
+				appointment_id,
+				treatment_type,
+				cycle_day,
+				special_instructions,
+				preparation_notes
+			) VALUES (?, ?, ?, ?, ?)`
+
+		_, err = tx.ExecContext(ctx, detailsStmt,
+			appointmentID,
+			details.TreatmentType,
+			details.CycleDay,
+			details.SpecialInstructions,
+			details.PreparationNotes,
+		)
+		if err != nil {
+			return err
+		}
+
+		return reserveAppointmentSlot(ctx, tx, appointmentID, *appointment.AppointmentDatetime)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	api.Deps.Metrics.AppointmentCreated(string(model.TypeIVF))
 	return appointmentID, nil
 }
 
@@ -454,7 +600,6 @@ func (api *API) GetLabTestAppointments(ctx context.Context, userID int) ([]model
 
 	err := api.Deps.DB.SelectContext(ctx, &appointments, query, userID)
 	if err != nil {
-		log.Println("error getting lab test appointments", err)
 		return nil, err
 	}
 
@@ -493,11 +638,23 @@ func (api *API) AdminFetchAllAppointmentsRepo(ctx context.Context, filter model.
 						'hospital_id', la.hospital_id,
 						'additional_instructions', la.additional_instructions
 					)
-			END as lab_test_details
+			END as lab_test_details,
+			CASE
+				WHEN a.appointment_type = 'ivf' THEN
+					JSON_OBJECT(
+						'id', ia.id,
+						'appointment_id', ia.appointment_id,
+						'treatment_type', ia.treatment_type,
+						'cycle_day', ia.cycle_day,
+						'special_instructions', ia.special_instructions,
+						'preparation_notes', ia.preparation_notes
+					)
+			END as ivf_details
 		FROM
 			appointments a
-			LEFT JOIN doctor_appointments da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
-			LEFT JOIN lab_test_appointments la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'
+			LEFT JOIN doctor_appointment_details da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
+			LEFT JOIN lab_test_appointment_details la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'
+			LEFT JOIN ivf_appointment_details ia ON a.id = ia.appointment_id AND a.appointment_type = 'ivf'
 		WHERE 1=1`
 
 	var args []interface{}
@@ -534,16 +691,17 @@ func (api *API) AdminFetchAllAppointmentsRepo(ctx context.Context, filter model.
 		args = append(args, *filter.ProviderID)
 	}
 
-	query += " ORDER BY a.created_at DESC"
+	if filter.HospitalID != nil {
+		query += " AND " + appointmentHospitalExpr + " = ?"
+		args = append(args, *filter.HospitalID)
+	}
 
-	// Add pagination
-	query += " LIMIT ? OFFSET ?"
-	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+	query, args = appendAppointmentSearch(query, args, filter.SearchTerms)
+	query, args = appendAppointmentPage(query, args, filter.Page)
 
 	var rows []model.AppointmentRow
 	err := api.Deps.DB.SelectContext(ctx, &rows, query, args...)
 	if err != nil {
-		log.Println("error fetching admin appointments", err)
 		return nil, fmt.Errorf("failed to fetch admin appointments: %w", err)
 	}
 
@@ -574,6 +732,14 @@ func (api *API) AdminFetchAllAppointmentsRepo(ctx context.Context, filter model.
 			}
 			appointments[i].LabTestDetails = &labTestDetails
 		}
+
+		if row.AppointmentType == "ivf" && len(row.IVFDetailsJSON) > 0 {
+			var ivfDetails model.IVFAppointmentDetails
+			if err := json.Unmarshal(row.IVFDetailsJSON, &ivfDetails); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal ivf details: %w", err)
+			}
+			appointments[i].IVFDetails = &ivfDetails
+		}
 	}
 
 	return appointments, nil
@@ -609,21 +775,31 @@ func (api *API) AdminGetAppointmentDetailsRepo(ctx context.Context, appointmentI
 						'hospital_id', la.hospital_id,
 						'additional_instructions', la.additional_instructions
 					)
-			END as lab_test_details
+			END as lab_test_details,
+			CASE
+				WHEN a.appointment_type = 'ivf' THEN
+					JSON_OBJECT(
+						'id', ia.id,
+						'appointment_id', ia.appointment_id,
+						'treatment_type', ia.treatment_type,
+						'cycle_day', ia.cycle_day,
+						'special_instructions', ia.special_instructions,
+						'preparation_notes', ia.preparation_notes
+					)
+			END as ivf_details
 		FROM
 			appointments a
-			LEFT JOIN doctor_appointments da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
-			LEFT JOIN lab_test_appointments la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'
+			LEFT JOIN doctor_appointment_details da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
+			LEFT JOIN lab_test_appointment_details la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'
+			LEFT JOIN ivf_appointment_details ia ON a.id = ia.appointment_id AND a.appointment_type = 'ivf'
 		WHERE a.id = ?`
 
 	var row model.AppointmentRow
 	err := api.Deps.DB.GetContext(ctx, &row, query, appointmentID)
 	if err != nil {
-		log.Println("error fetching appointment details", err)
 		return model.AppointmentDetails{}, fmt.Errorf("failed to fetch appointment details: %w", err)
 	}
 
-	
 	appointment := model.AppointmentDetails{
 		ID:                  row.ID,
 		UserID:              row.UserID,
@@ -650,6 +826,14 @@ func (api *API) AdminGetAppointmentDetailsRepo(ctx context.Context, appointmentI
 		appointment.LabTestDetails = &labTestDetails
 	}
 
+	if row.AppointmentType == "ivf" && len(row.IVFDetailsJSON) > 0 {
+		var ivfDetails model.IVFAppointmentDetails
+		if err := json.Unmarshal(row.IVFDetailsJSON, &ivfDetails); err != nil {
+			return model.AppointmentDetails{}, fmt.Errorf("failed to unmarshal ivf details: %w", err)
+		}
+		appointment.IVFDetails = &ivfDetails
+	}
+
 	return appointment, nil
 }
 
@@ -683,21 +867,31 @@ func (api *API) GetAppointmentDetailsRepo(ctx context.Context, appointmentID, us
 						'hospital_id', la.hospital_id,
 						'additional_instructions', la.additional_instructions
 					)
-			END as lab_test_details
+			END as lab_test_details,
+			CASE
+				WHEN a.appointment_type = 'ivf' THEN
+					JSON_OBJECT(
+						'id', ia.id,
+						'appointment_id', ia.appointment_id,
+						'treatment_type', ia.treatment_type,
+						'cycle_day', ia.cycle_day,
+						'special_instructions', ia.special_instructions,
+						'preparation_notes', ia.preparation_notes
+					)
+			END as ivf_details
 		FROM
 			appointments a
-			LEFT JOIN doctor_appointments da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
-			LEFT JOIN lab_test_appointments la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'
+			LEFT JOIN doctor_appointment_details da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
+			LEFT JOIN lab_test_appointment_details la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'
+			LEFT JOIN ivf_appointment_details ia ON a.id = ia.appointment_id AND a.appointment_type = 'ivf'
 		WHERE a.id = ? AND a.user_id = ?`
 
 	var row model.AppointmentRow
 	err := api.Deps.DB.GetContext(ctx, &row, query, appointmentID, userID)
 	if err != nil {
-		log.Println("error fetching user appointment details", err)
 		return model.AppointmentDetails{}, fmt.Errorf("failed to fetch appointment details: %w", err)
 	}
 
-	
 	appointment := model.AppointmentDetails{
 		ID:                  row.ID,
 		UserID:              row.UserID,
@@ -724,6 +918,14 @@ func (api *API) GetAppointmentDetailsRepo(ctx context.Context, appointmentID, us
 		appointment.LabTestDetails = &labTestDetails
 	}
 
+	if row.AppointmentType == "ivf" && len(row.IVFDetailsJSON) > 0 {
+		var ivfDetails model.IVFAppointmentDetails
+		if err := json.Unmarshal(row.IVFDetailsJSON, &ivfDetails); err != nil {
+			return model.AppointmentDetails{}, fmt.Errorf("failed to unmarshal ivf details: %w", err)
+		}
+		appointment.IVFDetails = &ivfDetails
+	}
+
 	return appointment, nil
 }
 
@@ -776,7 +978,14 @@ func (api *API) GetDetailedAppointmentRepo(ctx context.Context, appointmentID in
 			d.name as doctor_name,
 			d.specialization,
 			d.email as doctor_email,
-			d.phone as doctor_phone
+			d.phone as doctor_phone,
+
+			-- IVF appointment details (if IVF appointment)
+			ia.id as ivf_appointment_id,
+			ia.treatment_type,
+			ia.cycle_day,
+			ia.special_instructions,
+			ia.preparation_notes
 
 		FROM appointments a
 
@@ -784,13 +993,16 @@ func (api *API) GetDetailedAppointmentRepo(ctx context.Context, appointmentID in
 		LEFT JOIN users u ON a.user_id = u.id
 
 		-- Join lab test appointment details
-		LEFT JOIN lab_test_appointments la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'
+		LEFT JOIN lab_test_appointment_details la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'
 		LEFT JOIN lab_tests lt ON la.test_type_id = lt.id
 
 		-- Join doctor appointment details
-		LEFT JOIN doctor_appointments da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
+		LEFT JOIN doctor_appointment_details da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
 		LEFT JOIN doctors d ON da.doctor_id = d.id
 
+		-- Join IVF appointment details
+		LEFT JOIN ivf_appointment_details ia ON a.id = ia.appointment_id AND a.appointment_type = 'ivf'
+
 		-- Join hospital details (for both lab and doctor appointments)
 		LEFT JOIN hospitals h ON (la.hospital_id = h.id OR d.hospital_id = h.id)
 
@@ -851,11 +1063,17 @@ func (api *API) GetDetailedAppointmentRepo(ctx context.Context, appointmentID in
 		Specialization sql.NullString `db:"specialization"`
 		DoctorEmail    sql.NullString `db:"doctor_email"`
 		DoctorPhone    sql.NullString `db:"doctor_phone"`
+
+		// IVF appointment fields
+		IVFAppointmentID    sql.NullInt64  `db:"ivf_appointment_id"`
+		TreatmentType       sql.NullString `db:"treatment_type"`
+		CycleDay            sql.NullInt64  `db:"cycle_day"`
+		SpecialInstructions sql.NullString `db:"special_instructions"`
+		PreparationNotes    sql.NullString `db:"preparation_notes"`
 	}
 
 	err := api.Deps.DB.GetContext(ctx, &result, query, args...)
 	if err != nil {
-		log.Println("error fetching detailed appointment", err)
 		return model.DetailedAppointment{}, fmt.Errorf("failed to fetch detailed appointment: %w", err)
 	}
 
@@ -950,6 +1168,28 @@ func (api *API) GetDetailedAppointmentRepo(ctx context.Context, appointmentID in
 		}
 	}
 
+	// Add IVF details if IVF appointment
+	if result.AppointmentType == "ivf" && result.IVFAppointmentID.Valid {
+		detailed.IVFDetails = &model.IVFAppointmentDetails{
+			ID:            int(result.IVFAppointmentID.Int64),
+			AppointmentID: result.ID,
+		}
+
+		if result.TreatmentType.Valid {
+			detailed.IVFDetails.TreatmentType = &result.TreatmentType.String
+		}
+		if result.CycleDay.Valid {
+			cycleDay := int(result.CycleDay.Int64)
+			detailed.IVFDetails.CycleDay = &cycleDay
+		}
+		if result.SpecialInstructions.Valid {
+			detailed.IVFDetails.SpecialInstructions = &result.SpecialInstructions.String
+		}
+		if result.PreparationNotes.Valid {
+			detailed.IVFDetails.PreparationNotes = &result.PreparationNotes.String
+		}
+	}
+
 	// Get status history
 	statusHistory, _ := api.GetAppointmentStatusHistoryRepo(ctx, appointmentID)
 	detailed.StatusHistory = statusHistory
@@ -958,29 +1198,100 @@ func (api *API) GetDetailedAppointmentRepo(ctx context.Context, appointmentID in
 	rescheduleOffers, _ := api.GetRescheduleOffersRepo(ctx, appointmentID)
 	detailed.RescheduleOffers = rescheduleOffers
 
+	rescheduleRequests, _ := api.GetRescheduleRequestsRepo(ctx, appointmentID)
+	detailed.RescheduleRequests = rescheduleRequests
+
 	return detailed, nil
 }
 
 // Helper function to get reschedule offers
 func (api *API) GetRescheduleOffersRepo(ctx context.Context, appointmentID int) ([]model.RescheduleOffer, error) {
 	query := `
-		SELECT id, appointment_id, proposed_date, proposed_time, admin_notes, status, created_at, updated_at
+		SELECT id, appointment_id, proposed_date, proposed_time, admin_notes, status, expires_at,
+			CASE WHEN status = 'pending' THEN GREATEST(TIMESTAMPDIFF(SECOND, NOW(), expires_at), 0) ELSE 0 END AS remaining_seconds,
+			created_at, updated_at
 		FROM reschedule_offers
 		WHERE appointment_id = ?
-		ORDER BY created_at DESC`
+		ORDER BY created_at DESC, proposed_date, proposed_time`
 
 	var offers []model.RescheduleOffer
 	err := api.Deps.DB.SelectContext(ctx, &offers, query, appointmentID)
 	if err != nil {
-		log.Println("error fetching reschedule offers", err)
 		return nil, err
 	}
 
 	return offers, nil
 }
 
-func (api *API) UpdateAppointmentStatus(ctx context.Context, appointmentID int, status string, notes *string, changedByUserID *int) error {
-	return api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+type lockedAppointment struct {
+	Status          model.AppointmentStatus `db:"status"`
+	AppointmentType model.AppointmentType   `db:"appointment_type"`
+}
+
+// lockAppointment reads the current status of an appointment and holds a
+// row lock on it until the surrounding transaction ends. When userID is set the
+// appointment must also belong to that user.
+func lockAppointment(ctx context.Context, tx *sqlx.Tx, appointmentID int, userID *int) (lockedAppointment, error) {
+	query := `SELECT status, appointment_type FROM appointments WHERE id = ?`
+	args := []interface{}{appointmentID}
+	if userID != nil {
+		query += " AND user_id = ?"
+		args = append(args, *userID)
+	}
+	query += " FOR UPDATE"
+
+	var appointment lockedAppointment
+	if err := tx.GetContext(ctx, &appointment, query, args...); err != nil {
+		return lockedAppointment{}, err
+	}
+	return appointment, nil
+}
+
+// transitionAppointment locks the appointment and checks that moving it to the
+// requested status is allowed by the appointment state machine. It returns the
+// appointment type for the transition metrics.
+func transitionAppointment(ctx context.Context, tx *sqlx.Tx, appointmentID int, userID *int, to model.AppointmentStatus) (model.AppointmentType, error) {
+	current, err := lockAppointment(ctx, tx, appointmentID, userID)
+	if err != nil {
+		return "", err
+	}
+	return current.AppointmentType, model.ValidateTransition(current.Status, to)
+}
+
+// runTransition validates moving an appointment to status and runs fn to make
+// the change, in one transaction. The transition is counted once it commits.
+func (api *API) runTransition(ctx context.Context, appointmentID int, userID *int, to model.AppointmentStatus, fn func(tx *sqlx.Tx) error) error {
+	var appointmentType model.AppointmentType
+	err := api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		appointmentType, err = transitionAppointment(ctx, tx, appointmentID, userID, to)
+		if err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+	if err == nil {
+		api.countTransition(appointmentType, to)
+	}
+	return err
+}
+
+func (api *API) countTransition(appointmentType model.AppointmentType, to model.AppointmentStatus) {
+	api.Deps.Metrics.AppointmentTransitioned(string(appointmentType), string(to))
+}
+
+func (api *API) UpdateAppointmentStatus(ctx context.Context, appointmentID int, status string, notes *string, changedByUserID *int, notice *appointmentNotice) error {
+	return api.runTransition(ctx, appointmentID, nil, model.AppointmentStatus(status), func(tx *sqlx.Tx) error {
+		if releasesSlot(model.AppointmentStatus(status)) {
+			if err := releaseAppointmentSlot(ctx, tx, appointmentID); err != nil {
+				return err
+			}
+		}
+
+		if err := closeRescheduleRequests(ctx, tx, appointmentID, model.RequestDeclined, changedByUserID); err != nil {
+			return err
+		}
+
 		// Update appointment status
 		updateQuery := `UPDATE appointments SET status = ?, updated_at = NOW() WHERE id = ?`
 		_, err := tx.ExecContext(ctx, updateQuery, status, appointmentID)
@@ -993,12 +1304,45 @@ func (api *API) UpdateAppointmentStatus(ctx context.Context, appointmentID int,
 			INSERT INTO appointment_status_history (appointment_id, status, notes, changed_by_user_id)
 			VALUES (?, ?, ?, ?)`
 		_, err = tx.ExecContext(ctx, historyQuery, appointmentID, status, notes, changedByUserID)
+		if err != nil {
+			return err
+		}
+
+		return api.enqueueAppointmentNotice(ctx, tx, appointmentID, notice)
+	})
+}
+
+// CancelUserAppointment cancels an appointment on behalf of the patient who owns it.
+func (api *API) CancelUserAppointment(ctx context.Context, appointmentID, userID int) error {
+	return api.runTransition(ctx, appointmentID, &userID, model.StatusCanceled, func(tx *sqlx.Tx) error {
+		if err := releaseAppointmentSlot(ctx, tx, appointmentID); err != nil {
+			return err
+		}
+
+		if err := closeRescheduleRequests(ctx, tx, appointmentID, model.RequestWithdrawn, nil); err != nil {
+			return err
+		}
+
+		updateQuery := `UPDATE appointments SET status = ?, updated_at = NOW() WHERE id = ?`
+		_, err := tx.ExecContext(ctx, updateQuery, string(model.StatusCanceled), appointmentID)
+		if err != nil {
+			return err
+		}
+
+		historyQuery := `
+			INSERT INTO appointment_status_history (appointment_id, status, notes, changed_by_user_id)
+			VALUES (?, ?, ?, ?)`
+		_, err = tx.ExecContext(ctx, historyQuery, appointmentID, string(model.StatusCanceled), "Canceled by patient", userID)
 		return err
 	})
 }
 
-func (api *API) RejectAppointment(ctx context.Context, appointmentID int, rejectionReason, notes *string, changedByUserID *int) error {
-	return api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+func (api *API) RejectAppointment(ctx context.Context, appointmentID int, rejectionReason, notes *string, changedByUserID *int, notice *appointmentNotice) error {
+	return api.runTransition(ctx, appointmentID, nil, model.StatusRejected, func(tx *sqlx.Tx) error {
+		if err := releaseAppointmentSlot(ctx, tx, appointmentID); err != nil {
+			return err
+		}
+
 		// Update appointment with rejection
 		updateQuery := `
 			UPDATE appointments
@@ -1014,21 +1358,40 @@ func (api *API) RejectAppointment(ctx context.Context, appointmentID int, reject
 			INSERT INTO appointment_status_history (appointment_id, status, notes, changed_by_user_id)
 			VALUES (?, ?, ?, ?)`
 		_, err = tx.ExecContext(ctx, historyQuery, appointmentID, string(model.StatusRejected), notes, changedByUserID)
-		return err
+		if err != nil {
+			return err
+		}
+
+		return api.enqueueAppointmentNotice(ctx, tx, appointmentID, notice)
 	})
 }
 
-func (api *API) CreateRescheduleOffer(ctx context.Context, appointmentID int, proposedDate, proposedTime string, notes *string, changedByUserID *int) error {
-	return api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
-		// Create reschedule offer
-		offerQuery := `
-			INSERT INTO reschedule_offers (appointment_id, proposed_date, proposed_time, admin_notes)
-			VALUES (?, ?, ?, ?)`
-		_, err := tx.ExecContext(ctx, offerQuery, appointmentID, proposedDate, proposedTime, notes)
+// CreateRescheduleOffer proposes one or more slots to the patient, open until
+// deadline. Offers still pending from an earlier round are superseded.
+func (api *API) CreateRescheduleOffer(ctx context.Context, appointmentID int, slots []model.RescheduleSlot, deadline time.Time, notes *string, changedByUserID *int, notice *appointmentNotice) error {
+	return api.runTransition(ctx, appointmentID, nil, model.StatusRescheduleOffered, func(tx *sqlx.Tx) error {
+		supersedeQuery := `UPDATE reschedule_offers SET status = ?, updated_at = NOW() WHERE appointment_id = ? AND status = ?`
+		_, err := tx.ExecContext(ctx, supersedeQuery, model.OfferSuperseded, appointmentID, model.OfferPending)
 		if err != nil {
 			return err
 		}
 
+		// An offer answers any request the patient made
+		if err := closeRescheduleRequests(ctx, tx, appointmentID, model.RequestDeclined, changedByUserID); err != nil {
+			return err
+		}
+
+		// Create reschedule offers
+		offerQuery := `
+			INSERT INTO reschedule_offers (appointment_id, proposed_date, proposed_time, admin_notes, expires_at)
+			VALUES (?, ?, ?, ?, ?)`
+		for _, slot := range slots {
+			_, err = tx.ExecContext(ctx, offerQuery, appointmentID, slot.Date, slot.Time, notes, deadline.Format(slotTimeLayout))
+			if err != nil {
+				return err
+			}
+		}
+
 		// Update appointment status
 		updateQuery := `UPDATE appointments SET status = ?, updated_at = NOW() WHERE id = ?`
 		_, err = tx.ExecContext(ctx, updateQuery, string(model.StatusRescheduleOffered), appointmentID)
@@ -1041,7 +1404,11 @@ func (api *API) CreateRescheduleOffer(ctx context.Context, appointmentID int, pr
 			INSERT INTO appointment_status_history (appointment_id, status, notes, changed_by_user_id)
 			VALUES (?, ?, ?, ?)`
 		_, err = tx.ExecContext(ctx, historyQuery, appointmentID, string(model.StatusRescheduleOffered), notes, changedByUserID)
-		return err
+		if err != nil {
+			return err
+		}
+
+		return api.enqueueAppointmentNotice(ctx, tx, appointmentID, notice)
 	})
 }
 
@@ -1052,29 +1419,55 @@ func (api *API) UpdateAppointmentAdminNotes(ctx context.Context, appointmentID i
 }
 
 func (api *API) AcceptRescheduleOfferRepo(ctx context.Context, appointmentID, userID, offerID int) error {
-	return api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+	return api.runTransition(ctx, appointmentID, &userID, model.StatusRescheduleAccepted, func(tx *sqlx.Tx) error {
 		// Get the reschedule offer details
-		var offer model.RescheduleOffer
+		var offer struct {
+			ProposedDate string `db:"proposed_date"`
+			ProposedTime string `db:"proposed_time"`
+			Status       string `db:"status"`
+			Expired      bool   `db:"expired"`
+		}
 		offerQuery := `
-			SELECT proposed_date, proposed_time
+			SELECT DATE_FORMAT(proposed_date, '%Y-%m-%d') AS proposed_date, TIME_FORMAT(proposed_time, '%H:%i') AS proposed_time,
+				status, (expires_at <= NOW() OR TIMESTAMP(proposed_date, proposed_time) <= NOW()) AS expired
 			FROM reschedule_offers
-			WHERE id = ? AND appointment_id = ? AND status = 'pending'`
+			WHERE id = ? AND appointment_id = ?`
 		err := tx.GetContext(ctx, &offer, offerQuery, offerID, appointmentID)
 		if err != nil {
 			return err
 		}
+		if offer.Status != model.OfferPending {
+			return model.ErrOfferUnavailable
+		}
+		if offer.Expired {
+			return model.ErrOfferExpired
+		}
 
-		// Update reschedule offer status
-		updateOfferQuery := `UPDATE reschedule_offers SET status = 'accepted', updated_at = NOW() WHERE id = ?`
-		_, err = tx.ExecContext(ctx, updateOfferQuery, offerID)
+		// Accept this offer and retire the alternatives made alongside it
+		updateOfferQuery := `
+			UPDATE reschedule_offers
+			SET status = CASE WHEN id = ? THEN ? ELSE ? END, updated_at = NOW()
+			WHERE appointment_id = ? AND status = ?`
+		_, err = tx.ExecContext(ctx, updateOfferQuery, offerID, model.OfferAccepted, model.OfferSuperseded, appointmentID, model.OfferPending)
 		if err != nil {
 			return err
 		}
 
+		// Move the booking to the slot that was offered
+		proposedStart, err := parseSlotTime(offer.ProposedDate + " " + offer.ProposedTime)
+		if err != nil {
+			return err
+		}
+		if err := releaseAppointmentSlot(ctx, tx, appointmentID); err != nil {
+			return err
+		}
+		if err := reserveAppointmentSlot(ctx, tx, appointmentID, proposedStart); err != nil {
+			return err
+		}
+
 		// Update appointment with new date/time
-		// Combine the proposed date and time for TIMESTAMP field
-		proposedDateTime := offer.ProposedDate + " " + offer.ProposedTime + ":00"
-		
+		proposedDateTime := proposedStart.Format(slotTimeLayout)
+
 		updateAppointmentQuery := `
 			UPDATE appointments
 			SET appointment_datetime = ?, status = ?, updated_at = NOW()
@@ -1093,14 +1486,44 @@ func (api *API) AcceptRescheduleOfferRepo(ctx context.Context, appointmentID, us
 	})
 }
 
+// RejectRescheduleOfferRepo declines one offer. The appointment goes back to
+// pending for admin review once no other open offer is left.
 func (api *API) RejectRescheduleOfferRepo(ctx context.Context, appointmentID, userID, offerID int, reason *string) error {
-	return api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+	var appointmentType model.AppointmentType
+	var moved bool
+	err := api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		appointmentType, err = transitionAppointment(ctx, tx, appointmentID, &userID, model.StatusPending)
+		if err != nil {
+			return err
+		}
+
 		// Update reschedule offer status
-		updateOfferQuery := `UPDATE reschedule_offers SET status = 'rejected', updated_at = NOW() WHERE id = ?`
-		_, err := tx.ExecContext(ctx, updateOfferQuery, offerID)
+		updateOfferQuery := `
+			UPDATE reschedule_offers SET status = ?, updated_at = NOW()
+			WHERE id = ? AND appointment_id = ? AND status = ?`
+		result, err := tx.ExecContext(ctx, updateOfferQuery, model.OfferRejected, offerID, appointmentID, model.OfferPending)
 		if err != nil {
 			return err
 		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return model.ErrOfferUnavailable
+		}
+
+		// Same test the sweeper uses, so an offer whose slot has passed is not open
+		var openOffers int
+		openQuery := `
+			SELECT COUNT(*) FROM reschedule_offers
+			WHERE appointment_id = ? AND status = ?
+				AND expires_at > NOW() AND TIMESTAMP(proposed_date, proposed_time) > NOW()`
+		if err := tx.GetContext(ctx, &openOffers, openQuery, appointmentID, model.OfferPending); err != nil {
+			return err
+		}
+		if openOffers > 0 {
+			return nil
+		}
 
 		// Update appointment status back to pending for admin to review
 		updateAppointmentQuery := `UPDATE appointments SET status = ?, updated_at = NOW() WHERE id = ?`
@@ -1118,13 +1541,144 @@ func (api *API) RejectRescheduleOfferRepo(ctx context.Context, appointmentID, us
 			INSERT INTO appointment_status_history (appointment_id, status, notes, changed_by_user_id)
 			VALUES (?, ?, ?, ?)`
 		_, err = tx.ExecContext(ctx, historyQuery, appointmentID, string(model.StatusPending), notes, userID)
+		moved = err == nil
+		return err
+	})
+	if moved && err == nil {
+		api.countTransition(appointmentType, model.StatusPending)
+	}
+	return err
+}
+
+// CreateRescheduleRequestRepo records the new times a patient proposes and
+// puts the appointment in front of admins. A request still pending from
+// before is replaced.
+func (api *API) CreateRescheduleRequestRepo(ctx context.Context, appointmentID, userID int, slots []model.RescheduleSlot, reason string) error {
+	return api.runTransition(ctx, appointmentID, &userID, model.StatusRescheduleRequested, func(tx *sqlx.Tx) error {
+		if err := closeRescheduleRequests(ctx, tx, appointmentID, model.RequestSuperseded, nil); err != nil {
+			return err
+		}
+
+		requestQuery := `
+			INSERT INTO reschedule_requests (appointment_id, requested_by_user_id, proposed_date, proposed_time, reason)
+			VALUES (?, ?, ?, ?, ?)`
+		for _, slot := range slots {
+			_, err := tx.ExecContext(ctx, requestQuery, appointmentID, userID, slot.Date, slot.Time, reason)
+			if err != nil {
+				return err
+			}
+		}
+
+		updateQuery := `UPDATE appointments SET status = ?, updated_at = NOW() WHERE id = ?`
+		_, err := tx.ExecContext(ctx, updateQuery, string(model.StatusRescheduleRequested), appointmentID)
+		if err != nil {
+			return err
+		}
+
+		historyQuery := `
+			INSERT INTO appointment_status_history (appointment_id, status, notes, changed_by_user_id)
+			VALUES (?, ?, ?, ?)`
+		_, err = tx.ExecContext(ctx, historyQuery, appointmentID, string(model.StatusRescheduleRequested), "Patient requested reschedule: "+reason, userID)
 		return err
 	})
 }
 
+// ApproveRescheduleRequestRepo moves the appointment to the option the admin
+// picked from the patient's request and confirms it.
+func (api *API) ApproveRescheduleRequestRepo(ctx context.Context, appointmentID, requestID int, notes *string, adminID int, notice *appointmentNotice) error {
+	return api.runTransition(ctx, appointmentID, nil, model.StatusConfirmed, func(tx *sqlx.Tx) error {
+		var request struct {
+			ProposedDate string `db:"proposed_date"`
+			ProposedTime string `db:"proposed_time"`
+			Status       string `db:"status"`
+			Passed       bool   `db:"passed"`
+		}
+		requestQuery := `
+			SELECT DATE_FORMAT(proposed_date, '%Y-%m-%d') AS proposed_date, TIME_FORMAT(proposed_time, '%H:%i') AS proposed_time,
+				status, TIMESTAMP(proposed_date, proposed_time) <= NOW() AS passed
+			FROM reschedule_requests
+			WHERE id = ? AND appointment_id = ?`
+		if err := tx.GetContext(ctx, &request, requestQuery, requestID, appointmentID); err != nil {
+			return err
+		}
+		if request.Status != model.RequestPending || request.Passed {
+			return model.ErrRequestUnavailable
+		}
+
+		// Approve the chosen option and retire the others
+		updateRequestQuery := `
+			UPDATE reschedule_requests
+			SET status = CASE WHEN id = ? THEN ? ELSE ? END, reviewed_by_user_id = ?, updated_at = NOW()
+			WHERE appointment_id = ? AND status = ?`
+		_, err := tx.ExecContext(ctx, updateRequestQuery, requestID, model.RequestApproved, model.RequestSuperseded, adminID, appointmentID, model.RequestPending)
+		if err != nil {
+			return err
+		}
+
+		// Move the booking to the requested slot
+		requestedStart, err := parseSlotTime(request.ProposedDate + " " + request.ProposedTime)
+		if err != nil {
+			return err
+		}
+		if err := releaseAppointmentSlot(ctx, tx, appointmentID); err != nil {
+			return err
+		}
+		if err := reserveAppointmentSlot(ctx, tx, appointmentID, requestedStart); err != nil {
+			return err
+		}
+
+		updateAppointmentQuery := `
+			UPDATE appointments
+			SET appointment_datetime = ?, status = ?, updated_at = NOW()
+			WHERE id = ?`
+		_, err = tx.ExecContext(ctx, updateAppointmentQuery, requestedStart.Format(slotTimeLayout), string(model.StatusConfirmed), appointmentID)
+		if err != nil {
+			return err
+		}
+
+		historyNotes := "Reschedule request approved"
+		if notes != nil && *notes != "" {
+			historyNotes += ": " + *notes
+		}
+		historyQuery := `
+			INSERT INTO appointment_status_history (appointment_id, status, notes, changed_by_user_id)
+			VALUES (?, ?, ?, ?)`
+		_, err = tx.ExecContext(ctx, historyQuery, appointmentID, string(model.StatusConfirmed), historyNotes, adminID)
+		if err != nil {
+			return err
+		}
+
+		return api.enqueueAppointmentNotice(ctx, tx, appointmentID, notice)
+	})
+}
+
+func (api *API) GetRescheduleRequestsRepo(ctx context.Context, appointmentID int) ([]model.RescheduleRequest, error) {
+	query := `
+		SELECT id, appointment_id, requested_by_user_id,
+			DATE_FORMAT(proposed_date, '%Y-%m-%d') AS proposed_date, TIME_FORMAT(proposed_time, '%H:%i') AS proposed_time,
+			reason, status, reviewed_by_user_id, created_at, updated_at
+		FROM reschedule_requests
+		WHERE appointment_id = ?
+		ORDER BY created_at DESC, proposed_date, proposed_time`
+
+	requests := []model.RescheduleRequest{}
+	err := api.Deps.DB.SelectContext(ctx, &requests, query, appointmentID)
+	return requests, err
+}
+
+// closeRescheduleRequests settles any request still pending on an appointment
+// whose status is being changed some other way.
+func closeRescheduleRequests(ctx context.Context, tx *sqlx.Tx, appointmentID int, status string, reviewedBy *int) error {
+	query := `
+		UPDATE reschedule_requests SET status = ?, reviewed_by_user_id = ?, updated_at = NOW()
+		WHERE appointment_id = ? AND status = ?`
+	_, err := tx.ExecContext(ctx, query, status, reviewedBy, appointmentID, model.RequestPending)
+	return err
+}
+
 func (api *API) GetAppointmentStatusHistoryRepo(ctx context.Context, appointmentID int) ([]model.AppointmentStatusLog, error) {
 	query := `
-		SELECT id, appointment_id, status, notes, changed_by_user_id, changed_at
+		SELECT id, appointment_id, status, notes, changed_by_user_id, changed_by_system, changed_at
 		FROM appointment_status_history
 		WHERE appointment_id = ?
 		ORDER BY changed_at ASC`
@@ -1132,9 +1686,138 @@ func (api *API) GetAppointmentStatusHistoryRepo(ctx context.Context, appointment
 	var history []model.AppointmentStatusLog
 	err := api.Deps.DB.SelectContext(ctx, &history, query, appointmentID)
 	if err != nil {
-		log.Println("error fetching appointment status history", err)
 		return nil, fmt.Errorf("failed to fetch status history: %w", err)
 	}
 
 	return history, nil
 }
+
+type AppointmentEmailData struct {
+	UserID          int     `db:"user_id"`
+	PatientName     string  `db:"patient_name"`
+	PatientEmail    string  `db:"patient_email"`
+	PatientPhone    *string `db:"patient_phone"`
+	NotifyEmail     bool    `db:"notify_email"`
+	NotifySMS       bool    `db:"notify_sms"`
+	NotifyPush      bool    `db:"notify_push"`
+	AppointmentType string  `db:"appointment_type"`
+	AppointmentDate string  `db:"appointment_date"`
+	AppointmentTime string  `db:"appointment_time"`
+	TestName        *string `db:"test_name"`
+	HospitalName    *string `db:"hospital_name"`
+	PickupType      *string `db:"pickup_type"`
+	HomeLocation    *string `db:"home_location"`
+	Instructions    *string `db:"instructions"`
+	AdminNotes      *string `db:"admin_notes"`
+}
+
+func getAppointmentEmailData(ctx context.Context, q sqlx.QueryerContext, appointmentID int) (AppointmentEmailData, error) {
+	query := `
+		SELECT
+			u.id as user_id,
+			CONCAT(u.firstName, ' ', u.lastName) as patient_name,
+			u.email as patient_email,
+			u.phone as patient_phone,
+			u.notify_email,
+			u.notify_sms,
+			u.notify_push,
+			a.appointment_type,
+			DATE_FORMAT(a.appointment_datetime, '%Y-%m-%d') as appointment_date,
+			DATE_FORMAT(a.appointment_datetime, '%H:%i') as appointment_time,
+			lt.name as test_name,
+			h.name as hospital_name,
+			ltad.pickup_type,
+			ltad.home_location,
+			ltad.additional_instructions as instructions,
+			a.admin_notes
+		FROM appointments a
+		JOIN users u ON a.user_id = u.id
+		LEFT JOIN lab_test_appointment_details ltad ON a.id = ltad.appointment_id
+		LEFT JOIN lab_tests lt ON ltad.test_type_id = lt.id
+		LEFT JOIN hospitals h ON ltad.hospital_id = h.id
+		WHERE a.id = ?`
+
+	var data AppointmentEmailData
+	err := sqlx.GetContext(ctx, q, &data, query, appointmentID)
+	return data, err
+}
+
+// enqueueAppointmentNotice queues the patient notification for an appointment
+// change in the change's own transaction, once for every channel the patient
+// has turned on. A nil notice queues nothing.
+func (api *API) enqueueAppointmentNotice(ctx context.Context, tx *sqlx.Tx, appointmentID int, notice *appointmentNotice) error {
+	if notice == nil {
+		return nil
+	}
+
+	appointment, err := getAppointmentEmailData(ctx, tx, appointmentID)
+	if err != nil {
+		return err
+	}
+	data := notice.data(appointment)
+	data["AppointmentID"] = appointmentID
+
+	if appointment.NotifyEmail {
+		if err := outbox.Enqueue(ctx, tx, outbox.ChannelEmail, appointment.PatientEmail, notice.template, data); err != nil {
+			return err
+		}
+	}
+
+	if appointment.NotifySMS && appointment.PatientPhone != nil && api.Config.SmsProviderURL != "" {
+		if err := outbox.Enqueue(ctx, tx, outbox.ChannelSMS, *appointment.PatientPhone, notice.template, data); err != nil {
+			return err
+		}
+	}
+
+	if appointment.NotifyPush && api.Config.PushProviderURL != "" {
+		tokens, err := getPushTokens(ctx, tx, appointment.UserID)
+		if err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			if err := outbox.Enqueue(ctx, tx, outbox.ChannelPush, token, notice.template, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// AppointmentSearchTextRepo loads the searchable text of the given
+// appointments, used to highlight what a search matched.
+func (api *API) AppointmentSearchTextRepo(ctx context.Context, appointmentIDs []int) ([]model.AppointmentSearchText, error) {
+	if len(appointmentIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := "?" + strings.Repeat(",?", len(appointmentIDs)-1)
+	query := `
+		SELECT
+			a.id,
+			u.firstName AS first_name,
+			u.lastName AS last_name,
+			u.email,
+			h.name AS hospital_name,
+			lt.name AS lab_test_name,
+			a.admin_notes,
+			a.user_notes
+		FROM appointments a
+		JOIN users u ON u.id = a.user_id
+		LEFT JOIN doctor_appointment_details da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
+		LEFT JOIN lab_test_appointment_details la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'
+		LEFT JOIN hospitals h ON h.id = ` + appointmentHospitalExpr + `
+		LEFT JOIN lab_tests lt ON lt.id = la.test_type_id
+		WHERE a.id IN (` + placeholders + `)`
+
+	args := make([]interface{}, len(appointmentIDs))
+	for i, id := range appointmentIDs {
+		args[i] = id
+	}
+
+	var texts []model.AppointmentSearchText
+	if err := api.Deps.DB.SelectContext(ctx, &texts, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to fetch appointment search text: %w", err)
+	}
+	return texts, nil
+}
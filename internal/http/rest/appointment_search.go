@@ -0,0 +1,169 @@
+package rest
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util/apperr"
+)
+
+// Admin search matches each term against the full-text indexes added in
+// migration 0015. Every term has to match, but each may match a different
+// field, so "jane malaria" finds Jane's malaria test.
+
+const (
+	maxSearchLength = 100
+	maxSearchTerms  = 5
+	// snippetContext is how many bytes of text are kept either side of the
+	// first match in a long field
+	snippetContext = 60
+)
+
+// rgxSearchTerm picks the words out of a search. Terms shorter than two
+// characters are dropped, since the n-gram index cannot match them.
+var rgxSearchTerm = regexp.MustCompile(`[\p{L}\p{N}@._-]{2,}`)
+
+// appointmentSearchClause matches one term against the patient, hospital, lab
+// test and notes of an appointment. Each ? takes the term as a quoted phrase.
+const appointmentSearchClause = ` AND (
+		a.user_id IN (SELECT id FROM users WHERE MATCH(firstName, lastName, email) AGAINST (? IN BOOLEAN MODE))
+		OR ` + appointmentHospitalExpr + ` IN (SELECT id FROM hospitals WHERE MATCH(name) AGAINST (? IN BOOLEAN MODE))
+		OR la.test_type_id IN (SELECT id FROM lab_tests WHERE MATCH(name) AGAINST (? IN BOOLEAN MODE))
+		OR MATCH(a.admin_notes, a.user_notes) AGAINST (? IN BOOLEAN MODE))`
+
+// parseSearchTerms splits a search into the terms to match. It returns no
+// terms for an empty search.
+func parseSearchTerms(search string) ([]string, error) {
+	search = strings.TrimSpace(search)
+	if search == "" {
+		return nil, nil
+	}
+	if utf8.RuneCountInString(search) > maxSearchLength {
+		return nil, apperr.Validation(apperr.FieldError{Field: "q", Message: fmt.Sprintf("must be at most %d characters", maxSearchLength)})
+	}
+
+	var terms []string
+	seen := map[string]bool{}
+	for _, term := range rgxSearchTerm.FindAllString(strings.ToLower(search), -1) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		return nil, apperr.Validation(apperr.FieldError{Field: "q", Message: "must contain a word of at least 2 characters"})
+	}
+	if len(terms) > maxSearchTerms {
+		return nil, apperr.Validation(apperr.FieldError{Field: "q", Message: fmt.Sprintf("must have at most %d words", maxSearchTerms)})
+	}
+	return terms, nil
+}
+
+// appendAppointmentSearch requires every term to match. Terms are passed as
+// phrases, so characters that are operators in boolean mode match literally.
+func appendAppointmentSearch(query string, args []interface{}, terms []string) (string, []interface{}) {
+	for _, term := range terms {
+		phrase := `"` + term + `"`
+		query += appointmentSearchClause
+		args = append(args, phrase, phrase, phrase, phrase)
+	}
+	return query, args
+}
+
+// searchHighlights returns the fields of an appointment that contain any of
+// the terms, with the matches marked.
+func searchHighlights(text model.AppointmentSearchText, terms []string) []model.SearchHighlight {
+	patientName := strings.TrimSpace(text.FirstName + " " + text.LastName)
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"patient_name", &patientName},
+		{"email", &text.Email},
+		{"hospital", text.HospitalName},
+		{"lab_test", text.LabTestName},
+		{"admin_notes", text.AdminNotes},
+		{"user_notes", text.UserNotes},
+	}
+
+	matcher := termMatcher(terms)
+	var highlights []model.SearchHighlight
+	for _, field := range fields {
+		if field.value == nil {
+			continue
+		}
+		if snippet, ok := highlight(*field.value, matcher); ok {
+			highlights = append(highlights, model.SearchHighlight{Field: field.name, Snippet: snippet})
+		}
+	}
+	return highlights
+}
+
+// termMatcher matches any of the terms, case insensitively, preferring the
+// longest where terms overlap.
+func termMatcher(terms []string) *regexp.Regexp {
+	sorted := append([]string(nil), terms...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	quoted := make([]string, len(sorted))
+	for i, term := range sorted {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+}
+
+// highlight wraps each match in value with <mark> tags and escapes the rest.
+// Long values are cut down to the text around the first match.
+func highlight(value string, matcher *regexp.Regexp) (string, bool) {
+	matches := matcher.FindAllStringIndex(value, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	start := runeStart(value, matches[0][0]-snippetContext)
+	end := runeStart(value, matches[0][1]+snippetContext)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m[0] < pos {
+			continue
+		}
+		if m[1] > end {
+			break
+		}
+		b.WriteString(html.EscapeString(value[pos:m[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(value[m[0]:m[1]]))
+		b.WriteString("</mark>")
+		pos = m[1]
+	}
+	b.WriteString(html.EscapeString(value[pos:end]))
+	if end < len(value) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+// runeStart clamps i to value and moves it back to the start of a rune.
+func runeStart(value string, i int) int {
+	if i <= 0 {
+		return 0
+	}
+	if i >= len(value) {
+		return len(value)
+	}
+	for i > 0 && !utf8.RuneStart(value[i]) {
+		i--
+	}
+	return i
+}
@@ -0,0 +1,126 @@
+package rest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util/apperr"
+)
+
+func TestParseSearchTerms(t *testing.T) {
+	tests := []struct {
+		name    string
+		search  string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", search: "   ", want: nil},
+		{name: "words", search: "Jane Malaria", want: []string{"jane", "malaria"}},
+		{name: "duplicates dropped", search: "jane JANE jane", want: []string{"jane"}},
+		{name: "single letters dropped", search: "a jane b", want: []string{"jane"}},
+		{name: "email kept whole", search: "jane.doe@example.com", want: []string{"jane.doe@example.com"}},
+		{name: "operators ignored", search: `+jane -"malaria"*`, want: []string{"jane", "malaria"}},
+		{name: "non-latin", search: "Zoë 東京", want: []string{"zoë", "東京"}},
+		{name: "no usable word", search: "a b c", wantErr: true},
+		{name: "too many words", search: "one two three four five six", wantErr: true},
+		{name: "too long", search: strings.Repeat("x", maxSearchLength+1), wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSearchTerms(tt.search)
+		if tt.wantErr {
+			var appErr *apperr.Error
+			if !errors.As(err, &appErr) || appErr.Code != apperr.CodeValidation || appErr.Fields[0].Field != "q" {
+				t.Errorf("%s: got %v, want a validation error on q", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAppendAppointmentSearch(t *testing.T) {
+	query, args := appendAppointmentSearch("SELECT", nil, []string{"jane", "malaria"})
+	if strings.Count(query, appointmentSearchClause) != 2 {
+		t.Fatalf("expected one clause per term, got %q", query)
+	}
+	if len(args) != 8 || args[0] != `"jane"` || args[4] != `"malaria"` {
+		t.Fatalf("got args %v, want each term quoted four times", args)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	long := strings.Repeat("a", 100) + " malaria " + strings.Repeat("b", 100)
+
+	tests := []struct {
+		name   string
+		value  string
+		terms  []string
+		want   string
+		wantOK bool
+	}{
+		{name: "no match", value: "Jane Doe", terms: []string{"malaria"}},
+		{name: "case insensitive", value: "Jane Doe", terms: []string{"jane"}, want: "<mark>Jane</mark> Doe", wantOK: true},
+		{name: "every match", value: "Jane and jane", terms: []string{"jane"}, want: "<mark>Jane</mark> and <mark>jane</mark>", wantOK: true},
+		{name: "longest term wins", value: "Malaria", terms: []string{"ma", "malaria"}, want: "<mark>Malaria</mark>", wantOK: true},
+		{name: "html escaped", value: "<b>Jane</b>", terms: []string{"jane"}, want: "&lt;b&gt;<mark>Jane</mark>&lt;/b&gt;", wantOK: true},
+		{
+			name:   "long value cut around the match",
+			value:  long,
+			terms:  []string{"malaria"},
+			want:   "…" + strings.Repeat("a", snippetContext-1) + " <mark>malaria</mark> " + strings.Repeat("b", snippetContext-1) + "…",
+			wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		got, ok := highlight(tt.value, termMatcher(tt.terms))
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("%s: got %q, %v; want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRuneStart(t *testing.T) {
+	value := "añb" // ñ takes bytes 1 and 2
+	tests := []struct{ i, want int }{
+		{-5, 0},
+		{0, 0},
+		{1, 1},
+		{2, 1},
+		{3, 3},
+		{10, len(value)},
+	}
+	for _, tt := range tests {
+		if got := runeStart(value, tt.i); got != tt.want {
+			t.Errorf("runeStart(%d) = %d, want %d", tt.i, got, tt.want)
+		}
+	}
+}
+
+func TestSearchHighlights(t *testing.T) {
+	hospital := "General Hospital"
+	notes := "patient asked about malaria"
+	text := model.AppointmentSearchText{
+		FirstName:    "Jane",
+		LastName:     "Doe",
+		Email:        "jane@example.com",
+		HospitalName: &hospital,
+		AdminNotes:   &notes,
+	}
+
+	got := searchHighlights(text, []string{"jane", "malaria"})
+	var fields []string
+	for _, h := range got {
+		fields = append(fields, h.Field)
+	}
+	if want := "patient_name,email,admin_notes"; strings.Join(fields, ",") != want {
+		t.Fatalf("highlighted fields %v, want %s", fields, want)
+	}
+}
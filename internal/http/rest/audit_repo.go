@@ -0,0 +1,34 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// recordAudit appends an entry to the audit log. It takes the executor of the
+// caller so the entry commits or rolls back together with the audited change.
+func recordAudit(ctx context.Context, exec sqlx.ExecerContext, entry model.AuditEntry) error {
+	var metadata []byte
+	if len(entry.Metadata) > 0 {
+		var err error
+		metadata, err = json.Marshal(entry.Metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	stmt := `INSERT INTO audit_logs (
+		actor_user_id,
+		action,
+		target_type,
+		target_id,
+		metadata,
+		ip_address
+	) VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err := exec.ExecContext(ctx, stmt, entry.ActorID, entry.Action, entry.TargetType, entry.TargetID, metadata, optionalString(entry.IPAddress))
+	return err
+}
@@ -1,8 +1,8 @@
 package rest
 
 import (
-	"log"
 	"net/http"
+	"time"
 
 	"github.com/bwise1/your_care_api/internal/model"
 	"github.com/bwise1/your_care_api/util"
@@ -19,14 +19,18 @@ func (api *API) AuthRoutes() chi.Router {
 
 	mux.Method(http.MethodPost, "/token/refresh", Handler(api.TokenRefresh))
 
-	// mux.Method(http.MethodPost, "/verify-email", Handler(api.VerifyEmail))
-	mux.Method(http.MethodPost, "/resend-verification", Handler(api.ResendVerification))
-	// mux.Method(http.MethodPost, "/forgot-password", Handler(api.ForgotPassword))
-	// mux.Method(http.MethodPost, "/reset-password", Handler(api.ResetPassword))
+	mux.Method(http.MethodPost, "/verify-email", Handler(api.VerifyEmail))
+	mux.With(api.RateLimit(api.Config.ResendVerificationRateLimit, time.Hour)).
+		Method(http.MethodPost, "/resend-verification", Handler(api.ResendVerification))
+	mux.Method(http.MethodPost, "/forgot-password", Handler(api.ForgotPassword))
+	mux.Method(http.MethodPost, "/reset-password", Handler(api.ResetPassword))
 	mux.Group(func(r chi.Router) {
 		r.Use(api.RequireLogin)
 		r.Method(http.MethodPut, "/change-password", Handler(api.ChangePassword))
 		r.Method(http.MethodPost, "/logout", Handler(api.Logout))
+		r.Method(http.MethodGet, "/sessions", Handler(api.GetSessions))
+		r.Method(http.MethodDelete, "/sessions", Handler(api.DeleteOtherSessions))
+		r.Method(http.MethodDelete, "/sessions/{sessionID}", Handler(api.DeleteSession))
 	})
 
 	// mux.Method(http.MethodDelete, "/delete-account", Handler(api.DeleteAccount))
@@ -41,6 +45,8 @@ func (api *API) Login(_ http.ResponseWriter, r *http.Request) *ServerResponse {
 	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
 		return respondWithError(decodeErr, "unable to parse login request", values.BadRequestBody, &tc)
 	}
+	req.UserAgent = r.UserAgent()
+	req.IPAddress = api.clientIP(r)
 
 	user, status, message, err := api.LoginUser(req)
 	if err != nil {
@@ -60,7 +66,6 @@ func (api *API) CreateUser(_ http.ResponseWriter, r *http.Request) *ServerRespon
 	// Create user
 	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
 
-	log.Println(tc, "creating user")
 	var req model.UserRequest
 	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
 		return respondWithError(decodeErr, "unable to parse registration request", values.BadRequestBody, &tc)
@@ -90,6 +95,8 @@ func (api *API) TokenRefresh(_ http.ResponseWriter, r *http.Request) *ServerResp
 	if req.RefreshToken == "" {
 		return respondWithError(nil, "refresh token is required", values.BadRequestBody, &tc)
 	}
+	req.UserAgent = r.UserAgent()
+	req.IPAddress = api.clientIP(r)
 
 	token, status, message, err := api.RefreshToken(req)
 	if err != nil {
@@ -107,40 +114,98 @@ func (api *API) TokenRefresh(_ http.ResponseWriter, r *http.Request) *ServerResp
 func (api *API) Logout(_ http.ResponseWriter, r *http.Request) *ServerResponse {
 	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
 
-	userID := r.Context().Value("user_id")
-	// Invalidate the refresh token in the database
-	err := api.invalidateRefreshToken(r.Context(), userID.(int))
+	userID := r.Context().Value("user_id").(int)
+	sessionID, _ := r.Context().Value("session_id").(string)
+
+	status, message, err := api.LogUserOut(userID, sessionID)
 	if err != nil {
-		return respondWithError(err, "failed to logout", values.Error, &tc)
+		return respondWithError(err, message, status, &tc)
 	}
 
 	return &ServerResponse{
-		Message:    "logged out successfully",
-		Status:     values.Success,
-		StatusCode: util.StatusCode(values.Success),
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
 	}
 }
 
-// // VerifyEmail handles email verification using a token
-// func (api *API) VerifyEmail(_ http.ResponseWriter, r *http.Request) *ServerResponse {
-// 	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+// GetSessions lists the devices the user is signed in on
+func (api *API) GetSessions(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
 
-// 	var req model.EmailVerificationReq
-// 	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
-// 		return respondWithError(decodeErr, "unable to parse verification request", values.BadRequestBody, &tc)
-// 	}
+	userID := r.Context().Value("user_id").(int)
+	sessionID, _ := r.Context().Value("session_id").(string)
 
-// 	status, message, err := api.VerifyUserEmail(req)
-// 	if err != nil {
-// 		return respondWithError(err, message, status, &tc)
-// 	}
+	sessions, status, message, err := api.ListSessions(userID, sessionID)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
 
-// 	return &ServerResponse{
-// 		Message:    message,
-// 		Status:     status,
-// 		StatusCode: util.StatusCode(status),
-// 	}
-// }
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       sessions,
+	}
+}
+
+// DeleteSession signs a single device out
+func (api *API) DeleteSession(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	userID := r.Context().Value("user_id").(int)
+
+	status, message, err := api.RevokeSession(userID, chi.URLParam(r, "sessionID"))
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+	}
+}
+
+// DeleteOtherSessions signs out every device except the one making the request
+func (api *API) DeleteOtherSessions(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	userID := r.Context().Value("user_id").(int)
+	sessionID, _ := r.Context().Value("session_id").(string)
+
+	status, message, err := api.RevokeOtherSessions(userID, sessionID)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+	}
+}
+
+// VerifyEmail handles email verification using a token
+func (api *API) VerifyEmail(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	var req model.EmailVerificationReq
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse verification request", values.BadRequestBody, &tc)
+	}
+
+	status, message, err := api.VerifyUserEmail(req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+	}
+}
 
 // ResendVerification handles resending verification email
 func (api *API) ResendVerification(_ http.ResponseWriter, r *http.Request) *ServerResponse {
@@ -163,47 +228,47 @@ func (api *API) ResendVerification(_ http.ResponseWriter, r *http.Request) *Serv
 	}
 }
 
-// // ForgotPassword initiates the password reset process
-// func (api *API) ForgotPassword(_ http.ResponseWriter, r *http.Request) *ServerResponse {
-// 	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+// ForgotPassword initiates the password reset process
+func (api *API) ForgotPassword(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
 
-// 	var req model.ForgotPasswordReq
-// 	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
-// 		return respondWithError(decodeErr, "unable to parse forgot password request", values.BadRequestBody, &tc)
-// 	}
+	var req model.ForgotPasswordReq
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse forgot password request", values.BadRequestBody, &tc)
+	}
 
-// 	status, message, err := api.InitiatePasswordReset(req)
-// 	if err != nil {
-// 		return respondWithError(err, message, status, &tc)
-// 	}
+	status, message, err := api.InitiatePasswordReset(req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
 
-// 	return &ServerResponse{
-// 		Message:    message,
-// 		Status:     status,
-// 		StatusCode: util.StatusCode(status),
-// 	}
-// }
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+	}
+}
 
-// // ResetPassword handles password reset using a token
-// func (api *API) ResetPassword(_ http.ResponseWriter, r *http.Request) *ServerResponse {
-// 	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+// ResetPassword handles password reset using a token
+func (api *API) ResetPassword(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
 
-// 	var req model.ResetPasswordReq
-// 	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
-// 		return respondWithError(decodeErr, "unable to parse reset password request", values.BadRequestBody, &tc)
-// 	}
+	var req model.ResetPasswordReq
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse reset password request", values.BadRequestBody, &tc)
+	}
 
-// 	status, message, err := api.CompletePasswordReset(req)
-// 	if err != nil {
-// 		return respondWithError(err, message, status, &tc)
-// 	}
+	status, message, err := api.CompletePasswordReset(req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
 
-// 	return &ServerResponse{
-// 		Message:    message,
-// 		Status:     status,
-// 		StatusCode: util.StatusCode(status),
-// 	}
-// }
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+	}
+}
 
 // ChangePassword allows authenticated users to change their password
 func (api *API) ChangePassword(_ http.ResponseWriter, r *http.Request) *ServerResponse {
@@ -249,16 +314,18 @@ func (api *API) ChangePassword(_ http.ResponseWriter, r *http.Request) *ServerRe
 // 	}
 // }
 
+// CreateAdminUser registers a staff account from an invite token issued by an
+// existing admin. The very first admin is created with the bootstrap-admin command.
 func (api *API) CreateAdminUser(_ http.ResponseWriter, r *http.Request) *ServerResponse {
 	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
 
-	log.Println(tc, "creating admin user")
-	var req model.UserRequest
+	var req model.AcceptInviteRequest
 	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
 		return respondWithError(decodeErr, "unable to parse registration request", values.BadRequestBody, &tc)
 	}
+	req.IPAddress = api.clientIP(r)
 
-	user, status, message, err := api.RegisterAdminUser(req)
+	user, status, message, err := api.AcceptAdminInvite_H(req)
 	if err != nil {
 		return respondWithError(err, message, status, &tc)
 	}
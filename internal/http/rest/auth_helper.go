@@ -3,24 +3,31 @@ package rest
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/internal/outbox"
 	"github.com/bwise1/your_care_api/util"
+	"github.com/bwise1/your_care_api/util/apperr"
 	"github.com/bwise1/your_care_api/util/values"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	"github.com/lucsky/cuid"
 )
 
 type TokenClaims struct {
-	UserID int    `json:"sub"`
-	Role   string `json:"role"`
-	Exp    int64  `json:"exp"`
+	UserID    int    `json:"sub"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
+	Exp       int64  `json:"exp"`
 }
 
 // Simplified token creation
-func (api *API) createToken(id int, role string) (string, time.Time, error) {
+func (api *API) createToken(id int, role, sessionID string) (string, time.Time, error) {
 	exp_time, err := time.ParseDuration(api.Config.JwtExpires)
 	if err != nil {
 		return "", time.Time{}, err
@@ -30,6 +37,7 @@ func (api *API) createToken(id int, role string) (string, time.Time, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"sub":  id, // subject (user ID)
 		"role": role,
+		"sid":  sessionID,
 		"exp":  expiresAt.Unix(),
 		"iat":  time.Now().Unix(),
 		"typ":  "access",
@@ -43,7 +51,9 @@ func (api *API) createToken(id int, role string) (string, time.Time, error) {
 	return tokenString, expiresAt, nil
 }
 
-func (api *API) createRefreshToken(id int) (string, time.Time, error) {
+// createRefreshToken issues a refresh token for a session. Every token gets a
+// unique jti so that two rotations within the same second never collide.
+func (api *API) createRefreshToken(id int, sessionID string) (string, time.Time, error) {
 	exp_time, err := time.ParseDuration(api.Config.RefreshExpiry)
 	if err != nil {
 		return "", time.Time{}, err
@@ -52,6 +62,8 @@ func (api *API) createRefreshToken(id int) (string, time.Time, error) {
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"sub": id, // subject (user ID)
+		"sid": sessionID,
+		"jti": cuid.New(),
 		"exp": expiresAt.Unix(),
 		"iat": time.Now().Unix(),
 		"typ": "refresh",
@@ -91,7 +103,6 @@ func (api *API) verifyToken(tokenString string, isRefresh bool) (*TokenClaims, e
 
 	// Check for errors or invalid token
 	if err != nil || !token.Valid {
-		log.Println("error verifying token", err)
 		return nil, fmt.Errorf("invalid token")
 	}
 
@@ -114,42 +125,80 @@ func (api *API) verifyToken(tokenString string, isRefresh bool) (*TokenClaims, e
 	}
 	userID := int(userIDFloat)
 
-	//extract role
+	//extract role, refresh tokens do not carry one
 	role, ok := claims["role"].(string)
-	if !ok {
+	if !ok && !isRefresh {
 		return nil, fmt.Errorf("invalid role")
 	}
 
-	// Verify the refresh token from the database if it is a refresh token
-	if isRefresh {
-		log.Println("verifying refresh token in database")
-		valid, err := api.verifyRefreshTokenInDB(context.TODO(), userID, tokenString)
-		if err != nil {
-			return nil, fmt.Errorf("error verifying refresh token from database: %v", err)
-		}
-		if !valid {
-			return nil, fmt.Errorf("invalid refresh token")
-		}
-	}
-
-	// Log extracted user ID and token type
-	log.Println("user id", userID)
-	log.Println("token type", tokenType)
+	// Tokens issued before sessions existed have no sid
+	sessionID, _ := claims["sid"].(string)
 
 	// Return the extracted claims
 	return &TokenClaims{
-		UserID: userID,
-		Role:   role,
-		Exp:    int64(claims["exp"].(float64)),
+		UserID:    userID,
+		Role:      role,
+		SessionID: sessionID,
+		Exp:       int64(claims["exp"].(float64)),
 	}, nil
 }
 
-func (api *API) LogUserOut(userID int) (bool, error) {
-	err := api.invalidateRefreshToken(context.TODO(), userID)
+// LogUserOut ends the session the request was made from. Without a session
+// every device is signed out.
+func (api *API) LogUserOut(userID int, sessionID string) (string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	var err error
+	if sessionID == "" {
+		err = api.RevokeUserSessions(ctx, userID, "")
+	} else {
+		err = api.RevokeSessionRepo(ctx, userID, sessionID)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return values.Error, "failed to logout", err
+	}
+	return values.Success, "logged out successfully", nil
+}
+
+func (api *API) ListSessions(userID int, currentSessionID string) ([]model.Session, string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	sessions, err := api.ListSessionsRepo(ctx, userID)
 	if err != nil {
-		return false, err
+		return nil, values.Error, fmt.Sprintf("%s [LsSe]", values.SystemErr), err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
 	}
-	return true, nil
+	return sessions, values.Success, "Sessions fetched successfully", nil
+}
+
+func (api *API) RevokeSession(userID int, sessionID string) (string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	err := api.RevokeSessionRepo(ctx, userID, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return values.NotFound, "Session not found", apperr.Wrap(err, apperr.CodeNotFound, "Session not found")
+		}
+		return values.Error, fmt.Sprintf("%s [RvSe]", values.SystemErr), err
+	}
+	return values.Success, "Session revoked successfully", nil
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current device.
+func (api *API) RevokeOtherSessions(userID int, currentSessionID string) (string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	err := api.RevokeUserSessions(ctx, userID, currentSessionID)
+	if err != nil {
+		return values.Error, fmt.Sprintf("%s [RvSe]", values.SystemErr), err
+	}
+	return values.Success, "Other sessions revoked successfully", nil
 }
 
 // auth_helper.go
@@ -157,65 +206,188 @@ func (api *API) LogUserOut(userID int) (bool, error) {
 func (api *API) ResendVerificationEmail(req model.ResendVerificationReq) (string, string, error) {
 	// Validate email format
 	if err := util.ValidEmail(req.Email); err != nil {
-		return values.BadRequestBody, "invalid email format", err
+		return values.BadRequestBody, "invalid email format", apperr.Validation(apperr.FieldError{Field: "email", Message: "must be a valid email address"})
 	}
 
 	// Get user by email
-	user, err := api.GetUserByEmail(context.Background(), req.Email)
+	user, err := api.GetUserByEmail(api.rootContext(), req.Email)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return values.NotFound, "user not found", fmt.Errorf("user not found")
+			return values.NotFound, "user not found", apperr.Wrap(err, apperr.CodeAccountNotFound, "user not found")
 		}
 		return values.Error, "error getting user", err
 	}
 
 	// Check if email is already verified
 	if user.IsEmailVerified {
-		return values.BadRequestBody, "email already verified", fmt.Errorf("email already verified")
+		return values.BadRequestBody, "email already verified", apperr.New(apperr.CodeEmailAlreadyVerified, "email already verified")
+	}
+
+	cooldown, err := time.ParseDuration(api.Config.VerificationResendCooldown)
+	if err != nil {
+		return values.Error, fmt.Sprintf("%s [RsVc]", values.SystemErr), err
 	}
 
 	// Generate new verification code
 	verificationCode := util.RandomString(6, values.Numbers)
-	expiryTime := time.Now().Add(time.Minute * 10)
+	expiryTime := time.Now().Add(emailVerificationTTL)
 
-	// Update user's verification code and expiry
-	err = api.updateVerificationCode(context.Background(), user.ID, verificationCode, expiryTime)
+	// Update user's verification code and expiry, queueing the new code
+	notice := api.verificationNotice(user.FirstName, user.Email, verificationCode)
+	err = api.updateVerificationCode(api.rootContext(), user.ID, verificationCode, expiryTime, cooldown, api.Config.MaxVerificationResends, notice)
 	if err != nil {
+		if errors.Is(err, model.ErrVerificationResendLimit) {
+			message := "a verification code was sent recently, please wait before requesting another"
+			return values.TooManyRequests, message, apperr.Wrap(err, apperr.CodeVerificationResendLimited, message)
+		}
 		return values.Error, "error updating verification code", err
 	}
 
-	//TODO: Send verification email
-	// err = api.Deps.EmailService.SendVerificationEmail(user.Email, user.FirstName, verificationCode)
-	// if err != nil {
-	// 	return values.Error, "error sending verification email", err
-	// }
-
 	return values.Success, "verification email sent successfully", nil
 }
 
-// func (api *API) verifyEmail(emailReq model.EmailVerificationReq) (bool, error) {
-// 	emailReq.Email = strings.Trim(emailReq.Email, " ")
+// passwordResetSent is returned by InitiatePasswordReset whether or not the
+// email belongs to an account, so the endpoint cannot be used to probe for users.
+const passwordResetSent = "If an account exists for this email, a password reset link has been sent"
 
-// 	err := util.ValidEmail(emailReq.Email)
-// 	if err != nil {
-// 		return false, err
-// 	}
+func (api *API) InitiatePasswordReset(req model.ForgotPasswordReq) (string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
 
-// 	//confirm if the users code rhymes with the email veridicatuin code also check if it has expired
+	req.Email = strings.TrimSpace(req.Email)
+	if err := util.ValidEmail(req.Email); err != nil {
+		return values.BadRequestBody, "invalid email format", apperr.Validation(apperr.FieldError{Field: "email", Message: "must be a valid email address"})
+	}
 
-// 	user, err := api.GetUserByEmail(context.TODO(), emailReq.Email)
-// 	if err != nil {
-// 		if err.Error() == values.NotFound {
-// 			return false, fmt.Errorf("user does not exist")
-// 		}
-// 		return false, err
-// 	}
-// 	if user.IsEmailVerified {
-// 		return false, fmt.Errorf("email already verified")
-// 	}
+	ttl, err := time.ParseDuration(api.Config.PasswordResetTTL)
+	if err != nil {
+		return values.Error, fmt.Sprintf("%s [PwRs]", values.SystemErr), err
+	}
 
-// 	if !user {
-// 		return false, fmt.Errorf("no email verification code")
-// 	}
+	user, err := api.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return values.Success, passwordResetSent, nil
+		}
+		return values.Error, fmt.Sprintf("%s [PwRs]", values.SystemErr), err
+	}
 
-// }
+	token, err := util.RandomToken(32)
+	if err != nil {
+		return values.Error, fmt.Sprintf("%s [PwRs]", values.SystemErr), err
+	}
+
+	// Queued rather than sent here, so the response time does not reveal
+	// whether the account exists
+	notice := &emailNotice{
+		recipient: user.Email,
+		template:  "resetEmail.tmpl",
+		data: map[string]interface{}{
+			"Name":      user.FirstName,
+			"ResetURL":  strings.TrimRight(api.Config.ClientURL, "/") + "/reset-password?token=" + token,
+			"ExpiresIn": util.HumanDuration(ttl),
+		},
+	}
+	err = api.CreatePasswordResetToken(ctx, user.ID, util.HashToken(token), ttl, notice)
+	if err != nil {
+		return values.Error, fmt.Sprintf("%s [PwRs]", values.SystemErr), err
+	}
+
+	return values.Success, passwordResetSent, nil
+}
+
+func (api *API) CompletePasswordReset(req model.ResetPasswordReq) (string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" {
+		return values.BadRequestBody, "reset token is required", apperr.Validation(apperr.FieldError{Field: "token", Message: "is required"})
+	}
+	if len(req.NewPassword) < 8 {
+		return values.BadRequestBody, "new password must be at least 8 characters", apperr.Validation(apperr.FieldError{Field: "new_password", Message: "must be at least 8 characters"})
+	}
+
+	hashedPassword, err := util.HashPassword([]byte(req.NewPassword))
+	if err != nil {
+		return values.Error, fmt.Sprintf("%s [HsPw]", values.SystemErr), err
+	}
+
+	err = api.ResetPasswordWithToken(ctx, util.HashToken(req.Token), hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return values.BadRequestBody, "reset link is invalid or has expired", apperr.Wrap(err, apperr.CodeResetTokenInvalid, "reset link is invalid or has expired")
+		}
+		return values.Error, fmt.Sprintf("%s [PwRs]", values.SystemErr), err
+	}
+
+	return values.Success, "password has been reset, please login", nil
+}
+
+// emailVerificationTTL is how long a verification code stays valid.
+const emailVerificationTTL = 10 * time.Minute
+
+func (api *API) VerifyUserEmail(req model.EmailVerificationReq) (string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	req.Email = strings.TrimSpace(req.Email)
+	req.Code = strings.TrimSpace(req.Code)
+	if err := util.ValidEmail(req.Email); err != nil {
+		return values.BadRequestBody, "invalid email format", apperr.Validation(apperr.FieldError{Field: "email", Message: "must be a valid email address"})
+	}
+	if req.Code == "" {
+		return values.BadRequestBody, "verification code is required", apperr.Validation(apperr.FieldError{Field: "code", Message: "is required"})
+	}
+
+	err := api.VerifyEmailCode(ctx, req.Email, req.Code, api.Config.MaxVerificationAttempts)
+	switch {
+	case err == nil:
+		return values.Success, "email verified successfully", nil
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, model.ErrVerificationCodeInvalid):
+		return values.BadRequestBody, "invalid verification code", apperr.Wrap(err, apperr.CodeVerificationCodeInvalid, "invalid verification code")
+	case errors.Is(err, model.ErrVerificationCodeExpired):
+		message := "verification code has expired, please request a new one"
+		return values.BadRequestBody, message, apperr.Wrap(err, apperr.CodeVerificationCodeExpired, message)
+	case errors.Is(err, model.ErrVerificationLocked):
+		message := "too many failed attempts, please request a new verification code"
+		return values.TooManyRequests, message, apperr.Wrap(err, apperr.CodeVerificationLocked, message)
+	case errors.Is(err, model.ErrEmailAlreadyVerified):
+		return values.BadRequestBody, "email already verified", apperr.Wrap(err, apperr.CodeEmailAlreadyVerified, "email already verified")
+	default:
+		return values.Error, fmt.Sprintf("%s [VrEm]", values.SystemErr), err
+	}
+}
+
+// emailNotice is an account email queued in the outbox in the same
+// transaction that issues its code or link, so it is retried until delivered
+// and never sent for a change that rolled back.
+type emailNotice struct {
+	recipient string
+	template  string
+	data      map[string]interface{}
+}
+
+// enqueue queues the email in tx. A nil notice queues nothing.
+func (n *emailNotice) enqueue(ctx context.Context, tx *sqlx.Tx) error {
+	if n == nil {
+		return nil
+	}
+	return outbox.Enqueue(ctx, tx, outbox.ChannelEmail, n.recipient, n.template, n.data)
+}
+
+// verificationNotice carries a verification code along with a link that
+// pre-fills it on the client.
+func (api *API) verificationNotice(name, email, code string) *emailNotice {
+	query := url.Values{"email": {email}, "code": {code}}
+	return &emailNotice{
+		recipient: email,
+		template:  "verifyEmail.tmpl",
+		data: map[string]interface{}{
+			"Name":            name,
+			"Code":            code,
+			"VerificationURL": strings.TrimRight(api.Config.ClientURL, "/") + "/verify-email?" + query.Encode(),
+			"ExpiresIn":       util.HumanDuration(emailVerificationTTL),
+		},
+	}
+}
@@ -5,15 +5,15 @@ import (
 	"crypto/subtle"
 	"database/sql"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/jmoiron/sqlx"
 )
 
-func (api *API) CreateUserRepo(ctx context.Context, req model.UserRequest) error {
-	log.Println("creating user, ", req)
-
+// CreateUserRepo creates the account and queues its verification email in
+// one transaction.
+func (api *API) CreateUserRepo(ctx context.Context, req model.UserRequest, notice *emailNotice) error {
 	stmt := `INSERT INTO users(
 		firstName,
 		lastName,
@@ -22,37 +22,17 @@ func (api *API) CreateUserRepo(ctx context.Context, req model.UserRequest) error
 		dateOfBirth,
 		sex,
 		emailVerificationCode,
-		emailVerificationCodeExpires
-	)VALUES(?, ?, ?, ?, ?, ?,?,?)`
-
-	_, err := api.Deps.DB.ExecContext(ctx, stmt, req.FirstName, req.LastName, req.Email, req.Password, req.DateOfBirth, req.Sex, req.EmailVerificationCode, req.EmailVerificationCodeExpires)
-	if err != nil {
-		log.Println("error creating user", err)
-		return err
-	}
-	return nil
-}
-
-func (api *API) CreateAdminUserRepo(ctx context.Context, req model.UserRequest) error {
-	log.Println("creating admin user, ", req)
+		emailVerificationCodeExpires,
+		emailVerificationSentAt
+	)VALUES(?, ?, ?, ?, ?, ?,?,?, NOW())`
 
-	stmt := `INSERT INTO users(
-        firstName,
-        lastName,
-        email,
-        password,
-		dateOfBirth,
-        sex,
-        role_id,
-		isEmailVerified
-    )VALUES(?, ?, ?, ?,'1995-08-15', ?, (SELECT id FROM roles WHERE name = 'admin'), 1)`
-
-	_, err := api.Deps.DB.ExecContext(ctx, stmt, req.FirstName, req.LastName, req.Email, req.Password, req.Sex)
-	if err != nil {
-		log.Println("error creating admin user", err)
-		return err
-	}
-	return nil
+	return api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, stmt, req.FirstName, req.LastName, req.Email, req.Password, req.DateOfBirth, req.Sex, req.EmailVerificationCode, req.EmailVerificationCodeExpires)
+		if err != nil {
+			return err
+		}
+		return notice.enqueue(ctx, tx)
+	})
 }
 
 func (api *API) EmailExists(ctx context.Context, email string) (bool, error) {
@@ -61,7 +41,6 @@ func (api *API) EmailExists(ctx context.Context, email string) (bool, error) {
 
 	err := api.Deps.DB.QueryRowContext(ctx, stmt, email).Scan(&exists)
 	if err != nil {
-		log.Println("error checking email", err)
 		return false, err
 	}
 	return exists, nil
@@ -77,6 +56,8 @@ func (api *API) GetUserByEmail(ctx context.Context, email string) (model.User, e
         u.password,
         u.role_id,
         r.name as role,
+        u.hospital_id,
+        r.hospital_scoped,
         u.isActive,
         u.isEmailVerified
     FROM users u
@@ -91,11 +72,12 @@ func (api *API) GetUserByEmail(ctx context.Context, email string) (model.User, e
 		&user.Password,
 		&user.RoleID,
 		&user.Role,
+		&user.HospitalID,
+		&user.HospitalScoped,
 		&user.IsActive,
 		&user.IsEmailVerified,
 	)
 	if err != nil {
-		log.Println("error getting user", err)
 		return model.User{}, err
 	}
 	return user, nil
@@ -111,6 +93,8 @@ func (api *API) GetUserByID(ctx context.Context, id int) (model.User, error) {
         u.password,
         u.role_id,
         r.name as role,
+        u.hospital_id,
+        r.hospital_scoped,
         u.isActive,
         u.isEmailVerified
     FROM users u
@@ -125,11 +109,12 @@ func (api *API) GetUserByID(ctx context.Context, id int) (model.User, error) {
 		&user.Password,
 		&user.RoleID,
 		&user.Role,
+		&user.HospitalID,
+		&user.HospitalScoped,
 		&user.IsActive,
 		&user.IsEmailVerified,
 	)
 	if err != nil {
-		log.Println("error getting user", err)
 		return model.User{}, err
 	}
 	return user, nil
@@ -141,62 +126,103 @@ func (api *API) GetUserBySocialID(provider, socialID string) (*model.User, error
 	return nil, nil
 }
 
-func (api *API) StoreRefreshToken(ctx context.Context, userID int, refreshToken string, expiry time.Time) error {
-	stmt := `UPDATE users SET refreshToken = ? WHERE id = ?`
-	_, err := api.Deps.DB.ExecContext(ctx, stmt, refreshToken, userID)
-	if err != nil {
-		log.Println("error storing refresh token", err)
-		return err
-	}
-	return nil
-}
-
-func (api *API) invalidateRefreshToken(ctx context.Context, userID int) error {
-	stmt := `UPDATE users SET refresh_token = NULL WHERE id = ?`
-	_, err := api.Deps.DB.ExecContext(ctx, stmt, userID)
-	if err != nil {
-		log.Println("error invalidating refresh token", err)
-		return err
-	}
-	return nil
-}
-
-func (api *API) verifyRefreshTokenInDB(ctx context.Context, userID int, refreshToken string) (bool, error) {
-	var token string
-	stmt := `SELECT refreshToken FROM users WHERE id = ?`
+// updateVerificationCode replaces the user's verification code, gives them a
+// fresh set of attempts and queues notice. It returns
+// model.ErrVerificationResendLimit when the last code went out less than
+// cooldown ago, or maxResends codes have been resent without a day's break.
+func (api *API) updateVerificationCode(ctx context.Context, userID int, code string, expiry time.Time, cooldown time.Duration, maxResends int, notice *emailNotice) error {
+	// Resends is assigned before SentAt, so it sees the previous send time
+	stmt := `UPDATE users SET
+		emailVerificationCode = ?,
+		emailVerificationCodeExpires = ?,
+		emailVerificationAttempts = 0,
+		emailVerificationResends = IF(emailVerificationSentAt IS NULL OR emailVerificationSentAt < DATE_SUB(NOW(), INTERVAL 1 DAY), 1, emailVerificationResends + 1),
+		emailVerificationSentAt = NOW()
+	WHERE id = ?
+	AND (emailVerificationSentAt IS NULL OR emailVerificationSentAt <= DATE_SUB(NOW(), INTERVAL ? SECOND))
+	AND (emailVerificationSentAt IS NULL OR emailVerificationSentAt < DATE_SUB(NOW(), INTERVAL 1 DAY) OR emailVerificationResends < ?)`
 
-	err := api.Deps.DB.QueryRowContext(ctx, stmt, userID).Scan(&token)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return false, nil
+	return api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, stmt, code, expiry, userID, int(cooldown.Seconds()), maxResends)
+		if err != nil {
+			return err
 		}
-		return false, fmt.Errorf("error verifying refresh token: %w", err)
-	}
 
-	// Use constant-time comparison
-	return subtle.ConstantTimeCompare([]byte(token), []byte(refreshToken)) == 1, nil
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return model.ErrVerificationResendLimit
+		}
+		return notice.enqueue(ctx, tx)
+	})
 }
 
-func (api *API) updateVerificationCode(ctx context.Context, userID int, code string, expiry time.Time) error {
-	stmt := `UPDATE users SET
-        emailVerificationCode = ?,
-        emailVerificationCodeExpires = ?
-    WHERE id = ?`
+// VerifyEmailCode checks a verification code for the account with the given
+// email and marks the email as verified when it matches. Every wrong code
+// counts towards maxAttempts; once reached the code is locked until a new one
+// is requested.
+func (api *API) VerifyEmailCode(ctx context.Context, email, code string, maxAttempts int) error {
+	// A wrong code is reported after the transaction commits, so the attempt
+	// counter is not rolled back along with it.
+	var result error
+	err := api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		var state struct {
+			ID              int            `db:"id"`
+			IsEmailVerified bool           `db:"isEmailVerified"`
+			Code            sql.NullString `db:"emailVerificationCode"`
+			Expired         bool           `db:"expired"`
+			Attempts        int            `db:"emailVerificationAttempts"`
+		}
+		stmt := `SELECT
+			id,
+			isEmailVerified,
+			emailVerificationCode,
+			COALESCE(emailVerificationCodeExpires < NOW(), TRUE) AS expired,
+			emailVerificationAttempts
+		FROM users
+		WHERE email = ?
+		FOR UPDATE`
+		if err := tx.GetContext(ctx, &state, stmt, email); err != nil {
+			return err
+		}
+
+		switch {
+		case state.IsEmailVerified:
+			result = model.ErrEmailAlreadyVerified
+			return nil
+		case state.Attempts >= maxAttempts:
+			result = model.ErrVerificationLocked
+			return nil
+		case !state.Code.Valid || subtle.ConstantTimeCompare([]byte(state.Code.String), []byte(code)) != 1:
+			result = model.ErrVerificationCodeInvalid
+			_, err := tx.ExecContext(ctx, `UPDATE users SET emailVerificationAttempts = emailVerificationAttempts + 1 WHERE id = ?`, state.ID)
+			return err
+		case state.Expired:
+			result = model.ErrVerificationCodeExpired
+			return nil
+		}
 
-	_, err := api.Deps.DB.ExecContext(ctx, stmt, code, expiry, userID)
+		stmt = `UPDATE users SET
+			isEmailVerified = 1,
+			emailVerificationCode = NULL,
+			emailVerificationCodeExpires = NULL,
+			emailVerificationAttempts = 0
+		WHERE id = ?`
+		_, err := tx.ExecContext(ctx, stmt, state.ID)
+		return err
+	})
 	if err != nil {
-		log.Println("error updating verification code:", err)
 		return err
 	}
-
-	return nil
-
+	return result
 }
+
 func (api *API) UpdateUserPassword(ctx context.Context, userID int, hashedPassword string) error {
 	stmt := `UPDATE users SET password = ? WHERE id = ?`
 	result, err := api.Deps.DB.ExecContext(ctx, stmt, hashedPassword, userID)
 	if err != nil {
-		log.Println("error updating password:", err)
 		return err
 	}
 
@@ -211,22 +237,63 @@ func (api *API) UpdateUserPassword(ctx context.Context, userID int, hashedPasswo
 	return nil
 }
 
-// func (api *API) CreateAdminUserRepo(ctx context.Context, req model.UserRequest) error {
-// 	log.Println("creating admin user, ", req)
-
-// 	stmt := `INSERT INTO users(
-//         firstName,
-//         lastName,
-//         email,
-//         password,
-//         sex,
-//         role_id
-//     )VALUES(?, ?, ?, ?, ?, (SELECT id FROM roles WHERE name = 'admin'))`
-
-// 	_, err := api.Deps.DB.ExecContext(ctx, stmt, req.FirstName, req.LastName, req.Email, req.Password, "Other")
-// 	if err != nil {
-// 		log.Println("error creating admin user", err)
-// 		return err
-// 	}
-// 	return nil
-// }
+// CreatePasswordResetToken stores a new reset token for the user, retires any
+// reset tokens issued before it, so only the latest link works, and queues
+// notice carrying the link.
+func (api *API) CreatePasswordResetToken(ctx context.Context, userID int, tokenHash string, ttl time.Duration, notice *emailNotice) error {
+	return api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		stmt := `UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = ? AND used_at IS NULL`
+		if _, err := tx.ExecContext(ctx, stmt, userID); err != nil {
+			return err
+		}
+
+		stmt = `INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+			VALUES (?, ?, DATE_ADD(NOW(), INTERVAL ? SECOND))`
+		if _, err := tx.ExecContext(ctx, stmt, userID, tokenHash, int(ttl.Seconds())); err != nil {
+			return err
+		}
+		return notice.enqueue(ctx, tx)
+	})
+}
+
+// ResetPasswordWithToken consumes an unused, unexpired reset token, sets the
+// new password on its user and revokes all of that user's sessions. All three
+// happen in one transaction, so a failure leaves the token usable again. It
+// returns sql.ErrNoRows for unknown, used or expired tokens.
+func (api *API) ResetPasswordWithToken(ctx context.Context, tokenHash, hashedPassword string) error {
+	return api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		var token struct {
+			ID     int `db:"id"`
+			UserID int `db:"user_id"`
+		}
+		stmt := `SELECT id, user_id FROM password_reset_tokens
+			WHERE token_hash = ? AND used_at IS NULL AND expires_at > NOW()
+			FOR UPDATE`
+		if err := tx.GetContext(ctx, &token, stmt, tokenHash); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `UPDATE password_reset_tokens SET used_at = NOW() WHERE id = ?`, token.ID)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `UPDATE users SET password = ? WHERE id = ?`, hashedPassword, token.UserID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("no user found with ID: %d", token.UserID)
+		}
+
+		// Sign out every device that was using the old password
+		stmt = `UPDATE user_sessions SET revoked_at = NOW()
+			WHERE user_id = ? AND revoked_at IS NULL`
+		_, err = tx.ExecContext(ctx, stmt, token.UserID)
+		return err
+	})
+}
@@ -0,0 +1,138 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util"
+	"github.com/bwise1/your_care_api/util/tracing"
+	"github.com/bwise1/your_care_api/util/values"
+	"github.com/go-chi/chi/v5"
+)
+
+func (api *API) DoctorRoutes() chi.Router {
+	mux := chi.NewRouter()
+	mux.Method(http.MethodGet, "/", Handler(api.GetDoctors))
+	mux.Method(http.MethodGet, "/{doctorID}", Handler(api.GetDoctor))
+
+	// Doctors are created, updated and deleted under /admin/doctors
+	return mux
+}
+
+func (api *API) GetDoctors(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	queryParams := r.URL.Query()
+	filter := model.DoctorFilter{
+		Specialization: queryParams.Get("specialization"),
+	}
+	if hospitalID := queryParams.Get("hospital_id"); hospitalID != "" {
+		id, err := strconv.Atoi(hospitalID)
+		if err != nil {
+			return respondWithError(err, "Invalid hospital ID", values.BadRequestBody, &tc)
+		}
+		filter.HospitalID = &id
+	}
+
+	doctors, status, message, err := api.GetDoctors_H(filter)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       doctors,
+	}
+}
+
+func (api *API) GetDoctor(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "doctorID"))
+	if err != nil {
+		return respondWithError(err, "Invalid doctor ID", values.BadRequestBody, &tc)
+	}
+
+	doctor, status, message, err := api.GetDoctor_H(id)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       doctor,
+	}
+}
+
+func (api *API) CreateDoctor(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	var req model.Doctor
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse doctor creation request", values.BadRequestBody, &tc)
+	}
+
+	doctor, status, message, err := api.CreateDoctor_H(req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       doctor,
+	}
+}
+
+func (api *API) UpdateDoctor(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "doctorID"))
+	if err != nil {
+		return respondWithError(err, "Invalid doctor ID", values.BadRequestBody, &tc)
+	}
+
+	var req model.Doctor
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse doctor update request", values.BadRequestBody, &tc)
+	}
+	req.ID = id
+
+	doctor, status, message, err := api.UpdateDoctor_H(req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       doctor,
+	}
+}
+
+func (api *API) DeleteDoctor(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "doctorID"))
+	if err != nil {
+		return respondWithError(err, "Invalid doctor ID", values.BadRequestBody, &tc)
+	}
+
+	status, message, err := api.DeleteDoctor_H(id)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+	}
+}
@@ -0,0 +1,136 @@
+package rest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util/apperr"
+	"github.com/bwise1/your_care_api/util/values"
+)
+
+func (api *API) GetDoctors_H(filter model.DoctorFilter) ([]model.Doctor, string, string, error) {
+	doctors, err := api.GetDoctorsRepo(api.rootContext(), filter)
+	if err != nil {
+		return nil, values.Error, fmt.Sprintf("%s [GeDo]", values.SystemErr), err
+	}
+	return doctors, values.Success, "Fetched doctors successfully", nil
+}
+
+func (api *API) GetDoctor_H(doctorID int) (model.Doctor, string, string, error) {
+	doctor, err := api.GetDoctorByIDRepo(api.rootContext(), doctorID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Doctor{}, values.NotFound, "Doctor not found", err
+		}
+		return model.Doctor{}, values.Error, fmt.Sprintf("%s [GeDo]", values.SystemErr), err
+	}
+	return doctor, values.Success, "Fetched doctor successfully", nil
+}
+
+func (api *API) CreateDoctor_H(req model.Doctor) (model.Doctor, string, string, error) {
+	if message, err := validateDoctor(&req); err != nil {
+		return model.Doctor{}, values.BadRequestBody, message, err
+	}
+
+	id, err := api.CreateDoctorRepo(api.rootContext(), req)
+	if err != nil {
+		return model.Doctor{}, values.Error, fmt.Sprintf("%s [CrDo]", values.SystemErr), err
+	}
+
+	req.ID = id
+	return req, values.Created, "Doctor created successfully", nil
+}
+
+func (api *API) UpdateDoctor_H(req model.Doctor) (model.Doctor, string, string, error) {
+	if message, err := validateDoctor(&req); err != nil {
+		return model.Doctor{}, values.BadRequestBody, message, err
+	}
+
+	if _, status, message, err := api.GetDoctor_H(req.ID); err != nil {
+		return model.Doctor{}, status, message, err
+	}
+
+	err := api.UpdateDoctorRepo(api.rootContext(), req)
+	if err != nil {
+		return model.Doctor{}, values.Error, fmt.Sprintf("%s [UpDo]", values.SystemErr), err
+	}
+	return req, values.Success, "Doctor updated successfully", nil
+}
+
+func (api *API) DeleteDoctor_H(doctorID int) (string, string, error) {
+	err := api.DeleteDoctorRepo(api.rootContext(), doctorID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return values.NotFound, "Doctor not found", err
+		}
+		// Doctors with appointments are kept for the appointment history
+		if apperr.IsRowReferenced(err) {
+			return values.Conflict, "Doctor has appointments and cannot be deleted", apperr.Wrap(err, apperr.CodeInUse, "Doctor has appointments and cannot be deleted")
+		}
+		return values.Error, fmt.Sprintf("%s [DlDo]", values.SystemErr), err
+	}
+	return values.Success, "Doctor deleted successfully", nil
+}
+
+// validateDoctor trims the doctor fields and checks the availability window.
+func validateDoctor(doctor *model.Doctor) (string, error) {
+	doctor.Name = strings.TrimSpace(doctor.Name)
+	doctor.Specialization = strings.TrimSpace(doctor.Specialization)
+
+	if doctor.Name == "" {
+		return "doctor name is required", errors.New("missing doctor name")
+	}
+	if doctor.HospitalID == 0 {
+		return "hospital_id is required", errors.New("missing hospital id")
+	}
+
+	from, err := parseClock(doctor.AvailableFrom)
+	if err != nil {
+		return "available_from must be in HH:MM format", err
+	}
+	to, err := parseClock(doctor.AvailableTo)
+	if err != nil {
+		return "available_to must be in HH:MM format", err
+	}
+	if !from.Before(to) {
+		return "available_from must be before available_to", errors.New("invalid availability window")
+	}
+
+	return "", nil
+}
+
+// doctorAvailableAt reports whether the time of day of at falls inside the
+// doctor's availability window. The window end is exclusive.
+func doctorAvailableAt(doctor model.Doctor, at time.Time) bool {
+	from, err := parseClock(doctor.AvailableFrom)
+	if err != nil {
+		return false
+	}
+	to, err := parseClock(doctor.AvailableTo)
+	if err != nil {
+		return false
+	}
+
+	clock := time.Date(0, 1, 1, at.Hour(), at.Minute(), at.Second(), 0, time.UTC)
+	return !clock.Before(from) && clock.Before(to)
+}
+
+// parseClock parses a time of day in HH:MM or HH:MM:SS format.
+func parseClock(value string) (time.Time, error) {
+	if t, err := time.Parse("15:04", value); err == nil {
+		return t, nil
+	}
+	return time.Parse("15:04:05", value)
+}
+
+func optionalString(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
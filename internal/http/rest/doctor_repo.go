@@ -0,0 +1,134 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/bwise1/your_care_api/internal/model"
+)
+
+func (api *API) GetDoctorsRepo(ctx context.Context, filter model.DoctorFilter) ([]model.Doctor, error) {
+	stmt := `SELECT
+		id,
+		COALESCE(hospital_id, 0) AS hospital_id,
+		name,
+		COALESCE(specialization, '') AS specialization,
+		COALESCE(email, '') AS email,
+		COALESCE(phone, '') AS phone,
+		COALESCE(TIME_FORMAT(available_from, '%H:%i'), '') AS available_from,
+		COALESCE(TIME_FORMAT(available_to, '%H:%i'), '') AS available_to
+	FROM doctors
+	WHERE 1=1`
+
+	var args []interface{}
+	if filter.HospitalID != nil {
+		stmt += " AND hospital_id = ?"
+		args = append(args, *filter.HospitalID)
+	}
+	if filter.Specialization != "" {
+		stmt += " AND specialization LIKE ?"
+		args = append(args, "%"+filter.Specialization+"%")
+	}
+	stmt += " ORDER BY name"
+
+	doctors := []model.Doctor{}
+	err := api.Deps.DB.SelectContext(ctx, &doctors, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	return doctors, nil
+}
+
+func (api *API) GetDoctorByIDRepo(ctx context.Context, doctorID int) (model.Doctor, error) {
+	stmt := `SELECT
+		id,
+		COALESCE(hospital_id, 0) AS hospital_id,
+		name,
+		COALESCE(specialization, '') AS specialization,
+		COALESCE(email, '') AS email,
+		COALESCE(phone, '') AS phone,
+		COALESCE(TIME_FORMAT(available_from, '%H:%i'), '') AS available_from,
+		COALESCE(TIME_FORMAT(available_to, '%H:%i'), '') AS available_to
+	FROM doctors
+	WHERE id = ?`
+
+	var doctor model.Doctor
+	err := api.Deps.DB.GetContext(ctx, &doctor, stmt, doctorID)
+	if err != nil {
+		return model.Doctor{}, err
+	}
+	return doctor, nil
+}
+
+func (api *API) CreateDoctorRepo(ctx context.Context, req model.Doctor) (int, error) {
+	stmt := `INSERT INTO doctors (
+		hospital_id,
+		name,
+		specialization,
+		email,
+		phone,
+		available_from,
+		available_to
+	) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := api.Deps.DB.ExecContext(ctx, stmt,
+		req.HospitalID,
+		req.Name,
+		req.Specialization,
+		req.Email,
+		req.Phone,
+		req.AvailableFrom,
+		req.AvailableTo,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (api *API) UpdateDoctorRepo(ctx context.Context, req model.Doctor) error {
+	stmt := `UPDATE doctors SET
+		hospital_id = ?,
+		name = ?,
+		specialization = ?,
+		email = ?,
+		phone = ?,
+		available_from = ?,
+		available_to = ?
+	WHERE id = ?`
+
+	_, err := api.Deps.DB.ExecContext(ctx, stmt,
+		req.HospitalID,
+		req.Name,
+		req.Specialization,
+		req.Email,
+		req.Phone,
+		req.AvailableFrom,
+		req.AvailableTo,
+		req.ID,
+	)
+	return err
+}
+
+func (api *API) DeleteDoctorRepo(ctx context.Context, doctorID int) error {
+	stmt := `DELETE FROM doctors WHERE id = ?`
+	result, err := api.Deps.DB.ExecContext(ctx, stmt, doctorID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// requireAffected turns an update or delete that matched nothing into sql.ErrNoRows.
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
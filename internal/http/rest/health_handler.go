@@ -8,7 +8,7 @@ import (
 	"net/http"
 )
 
-func HealthRoutes() chi.Router {
+func (api *API) HealthRoutes() chi.Router {
 	mux := chi.NewRouter()
 	mux.Method(http.MethodGet, "/", Handler(func(w http.ResponseWriter, r *http.Request) *ServerResponse {
 		return &ServerResponse{
@@ -18,5 +18,37 @@ func HealthRoutes() chi.Router {
 			Data:       cuid.New(),
 		}
 	}))
+	mux.Method(http.MethodGet, "/live", Handler(api.LivenessHandler))
+	mux.Method(http.MethodGet, "/ready", Handler(api.ReadinessHandler))
 	return mux
 }
+
+// LivenessHandler reports that the process is up and serving requests. It does
+// not touch any dependency, so a database outage never gets the API restarted.
+func (api *API) LivenessHandler(_ http.ResponseWriter, _ *http.Request) *ServerResponse {
+	return &ServerResponse{
+		Message:    "alive",
+		Status:     values.Success,
+		StatusCode: http.StatusOK,
+	}
+}
+
+// ReadinessHandler reports whether the API should receive traffic, with the
+// status and latency of every dependency it checked.
+func (api *API) ReadinessHandler(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	report, ready := api.CheckReadiness(r.Context())
+	if !ready {
+		return &ServerResponse{
+			Message:    "not ready",
+			Status:     values.Failed,
+			StatusCode: http.StatusServiceUnavailable,
+			Data:       report,
+		}
+	}
+	return &ServerResponse{
+		Message:    "ready",
+		Status:     values.Success,
+		StatusCode: http.StatusOK,
+		Data:       report,
+	}
+}
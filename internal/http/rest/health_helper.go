@@ -0,0 +1,83 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/db"
+	"github.com/bwise1/your_care_api/internal/model"
+)
+
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// CheckReadiness probes the database, the schema version and, when enabled,
+// the SMTP server. Checks run one after another, each under the configured
+// timeout. Failure details are logged rather than returned, since the
+// endpoint is public.
+func (api *API) CheckReadiness(ctx context.Context) (model.HealthReport, bool) {
+	timeout, err := time.ParseDuration(api.Config.HealthCheckTimeout)
+	if err != nil || timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+
+	report := model.HealthReport{
+		Status:     model.HealthUp,
+		Components: map[string]model.ComponentHealth{},
+	}
+	check := func(name string, probe func(ctx context.Context) (string, error)) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		start := time.Now()
+		detail, err := probe(ctx)
+		component := model.ComponentHealth{
+			Status:    model.HealthUp,
+			LatencyMs: time.Since(start).Milliseconds(),
+			Detail:    detail,
+		}
+		if err != nil {
+			api.Deps.Logger.Warn("readiness check failed", "component", name, "error", err)
+			component.Status = model.HealthDown
+			report.Status = model.HealthDown
+		}
+		report.Components[name] = component
+	}
+
+	check("database", func(ctx context.Context) (string, error) {
+		return "", api.Deps.DB.PingContext(ctx)
+	})
+	check("schema", api.checkSchemaVersion)
+	if api.Config.HealthCheckSMTP {
+		check("smtp", func(ctx context.Context) (string, error) {
+			return "", api.Deps.Mailer.Check(ctx)
+		})
+	}
+
+	// Dependencies are still reported while draining so operators can see
+	// them, but the instance stays out of rotation
+	if api.draining.Load() {
+		report.Status = model.HealthDraining
+	}
+	return report, report.Status == model.HealthUp
+}
+
+// checkSchemaVersion fails when the database is behind the migrations embedded
+// in this binary. A database ahead of the binary is fine, as it is during a
+// rolling deploy.
+func (api *API) checkSchemaVersion(ctx context.Context) (string, error) {
+	latest, err := db.LatestVersion()
+	if err != nil {
+		return "", err
+	}
+	current, err := api.Deps.DB.SchemaVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	detail := fmt.Sprintf("database at %d, binary expects %d", current, latest)
+	if current < latest {
+		return detail, db.ErrSchemaOutdated
+	}
+	return detail, nil
+}
@@ -1,7 +1,6 @@
 package rest
 
 import (
-	"log"
 	"net/http"
 	"strconv"
 
@@ -19,11 +18,14 @@ func (api *API) HospitalRoutes() chi.Router {
 
 	mux.Group(func(r chi.Router) {
 		r.Use(api.RequireLogin)
-		r.Use(api.RequireAdmin)
-		r.Method(http.MethodPost, "/", Handler(api.CreateHospital))
-		r.Method(http.MethodDelete, "/{hospitalID}", Handler(api.DeleteHospital))
+		r.With(api.RequirePermission(model.PermHospitalsWrite)).Method(http.MethodPost, "/", Handler(api.CreateHospital))
+		r.With(api.RequirePermission(model.PermHospitalsWrite)).Method(http.MethodDelete, "/{hospitalID}", Handler(api.DeleteHospital))
+	})
 
-		//under review
+	mux.Group(func(r chi.Router) {
+		r.Use(api.RequireLogin)
+		r.Use(api.RequirePermission(model.PermLabTestsPrice))
+		r.Use(api.RequireHospitalScope)
 		r.Method(http.MethodPut, "/lab-tests/{labTestID}", Handler(api.UpdateHospitalLabTest))
 		r.Method(http.MethodDelete, "/lab-tests/{labTestID}", Handler(api.DeleteHospitalLabTest))
 	})
@@ -130,8 +132,6 @@ func (api *API) CreateHospitalLabTest(w http.ResponseWriter, r *http.Request) *S
 
 	test, status, message, err := api.CreateHospitalLabTest_H(req)
 	if err != nil {
-
-		log.Println(err)
 		return respondWithError(err, message, status, &tc)
 	}
 	return &ServerResponse{Message: message, Status: status, StatusCode: util.StatusCode(status), Data: test}
@@ -154,9 +154,14 @@ func (api *API) GetAHospitalLabTests(w http.ResponseWriter, r *http.Request) *Se
 func (api *API) UpdateHospitalLabTest(w http.ResponseWriter, r *http.Request) *ServerResponse {
 	var req model.HospitalLabTest
 	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+	id, err := strconv.Atoi(chi.URLParam(r, "labTestID"))
+	if err != nil {
+		return respondWithError(err, "Invalid lab test ID", values.BadRequestBody, &tc)
+	}
 	if err := util.DecodeJSONBody(&tc, r.Body, &req); err != nil {
 		return respondWithError(err, "Invalid request", values.BadRequestBody, &tc)
 	}
+	req.ID = id
 	status, message, err := api.UpdateHospitalLabTest_H(req)
 	if err != nil {
 		return respondWithError(err, message, status, &tc)
@@ -1,7 +1,6 @@
 package rest
 
 import (
-	"context"
 	"fmt"
 
 	"github.com/bwise1/your_care_api/internal/model"
@@ -11,7 +10,7 @@ import (
 func (api *API) GetHospitals_H() ([]model.Hospital, string, string, error) {
 
 	var err error
-	var ctx = context.TODO()
+	var ctx = api.rootContext()
 
 	hospitals, err := api.GetAllHospitals(ctx)
 	if err != nil {
@@ -24,7 +23,7 @@ func (api *API) GetHospitals_H() ([]model.Hospital, string, string, error) {
 func (api *API) GetLabTestsByHospital_H(hospitalID int) ([]model.HospitalLabTest, string, string, error) {
 
 	var err error
-	var ctx = context.TODO()
+	var ctx = api.rootContext()
 
 	tests, err := api.GetLabTestsByHospital(ctx, hospitalID)
 	if err != nil {
@@ -36,7 +35,7 @@ func (api *API) GetLabTestsByHospital_H(hospitalID int) ([]model.HospitalLabTest
 
 func (api *API) CreateHospital_H(req model.Hospital) (model.Hospital, string, string, error) {
 	var err error
-	var ctx = context.TODO()
+	var ctx = api.rootContext()
 
 	hospitalID, err := api.CreateHospitalRepo(ctx, req)
 	if err != nil {
@@ -49,7 +48,7 @@ func (api *API) CreateHospital_H(req model.Hospital) (model.Hospital, string, st
 
 func (api *API) DeleteHospital_H(hospitalID int) (string, string, error) {
 	var err error
-	var ctx = context.TODO()
+	var ctx = api.rootContext()
 
 	err = api.DeleteHospitalRepo(ctx, hospitalID)
 	if err != nil {
@@ -60,7 +59,7 @@ func (api *API) DeleteHospital_H(hospitalID int) (string, string, error) {
 }
 
 func (api *API) CreateHospitalLabTest_H(req model.HospitalLabTest) (model.HospitalLabTest, string, string, error) {
-	id, err := api.CreateHospitalLabTestRepo(context.TODO(), req)
+	id, err := api.CreateHospitalLabTestRepo(api.rootContext(), req)
 	if err != nil {
 		return model.HospitalLabTest{}, values.Error, "Failed to create hospital lab test", err
 	}
@@ -69,7 +68,7 @@ func (api *API) CreateHospitalLabTest_H(req model.HospitalLabTest) (model.Hospit
 }
 
 func (api *API) GetHospitalLabTests_H(hospitalID int) ([]model.HospitalLabTest, string, string, error) {
-	tests, err := api.GetAHospitalLabTestsRepo(context.TODO(), hospitalID)
+	tests, err := api.GetAHospitalLabTestsRepo(api.rootContext(), hospitalID)
 	if err != nil {
 		return nil, values.Error, "Failed to fetch hospital lab tests", err
 	}
@@ -77,7 +76,7 @@ func (api *API) GetHospitalLabTests_H(hospitalID int) ([]model.HospitalLabTest,
 }
 
 func (api *API) UpdateHospitalLabTest_H(req model.HospitalLabTest) (string, string, error) {
-	err := api.UpdateHospitalLabTestRepo(context.TODO(), req)
+	err := api.UpdateHospitalLabTestRepo(api.rootContext(), req)
 	if err != nil {
 		return values.Error, "Failed to update hospital lab test", err
 	}
@@ -85,7 +84,7 @@ func (api *API) UpdateHospitalLabTest_H(req model.HospitalLabTest) (string, stri
 }
 
 func (api *API) DeleteHospitalLabTest_H(id int) (string, string, error) {
-	err := api.DeleteHospitalLabTestRepo(context.TODO(), id)
+	err := api.DeleteHospitalLabTestRepo(api.rootContext(), id)
 	if err != nil {
 		return values.Error, "Failed to delete hospital lab test", err
 	}
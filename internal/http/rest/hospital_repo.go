@@ -167,3 +167,9 @@ func (api *API) DeleteHospitalLabTestRepo(ctx context.Context, id int) error {
 	_, err := api.Deps.DB.ExecContext(ctx, stmt, id)
 	return err
 }
+
+func (api *API) HospitalExists(ctx context.Context, hospitalID int) (bool, error) {
+	var exists bool
+	err := api.Deps.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM hospitals WHERE id = ?)`, hospitalID).Scan(&exists)
+	return exists, err
+}
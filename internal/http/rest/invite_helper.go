@@ -0,0 +1,148 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util"
+	"github.com/bwise1/your_care_api/util/values"
+)
+
+// CreateAdminInvite_H issues an invite for a staff account and emails the
+// link. The invited role follows the same rules as assigning a role directly.
+func (api *API) CreateAdminInvite_H(actor model.User, req model.CreateInviteRequest) (model.AdminInvite, string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	req.Email = strings.TrimSpace(req.Email)
+	if err := util.ValidEmail(req.Email); err != nil {
+		return model.AdminInvite{}, values.BadRequestBody, "invalid email format", err
+	}
+	if strings.TrimSpace(req.Role) == "" {
+		req.Role = model.RoleAdmin
+	}
+	if req.Role == model.RoleUser {
+		return model.AdminInvite{}, values.BadRequestBody, "patients sign up themselves and cannot be invited", errors.New("invalid invite role")
+	}
+
+	role, hospitalID, status, message, err := api.resolveRoleGrant(ctx, actor, req.Role, req.HospitalID)
+	if err != nil {
+		return model.AdminInvite{}, status, message, err
+	}
+	req.Role = role.Name
+	req.HospitalID = hospitalID
+
+	exists, err := api.EmailExists(ctx, req.Email)
+	if err != nil {
+		return model.AdminInvite{}, values.Error, fmt.Sprintf("%s [EmCh]", values.SystemErr), err
+	}
+	if exists {
+		return model.AdminInvite{}, values.Conflict, "An account already exists for this email, assign it a role instead", errors.New(values.Conflict)
+	}
+
+	ttl, err := time.ParseDuration(api.Config.AdminInviteTTL)
+	if err != nil {
+		return model.AdminInvite{}, values.Error, fmt.Sprintf("%s [AdIn]", values.SystemErr), err
+	}
+
+	token, err := util.RandomToken(32)
+	if err != nil {
+		return model.AdminInvite{}, values.Error, fmt.Sprintf("%s [AdIn]", values.SystemErr), err
+	}
+
+	notice := &emailNotice{
+		recipient: req.Email,
+		template:  "adminInvite.tmpl",
+		data: map[string]interface{}{
+			"InvitedBy": strings.TrimSpace(actor.FirstName + " " + actor.LastName),
+			"Role":      strings.ReplaceAll(role.Name, "_", " "),
+			"InviteURL": strings.TrimRight(api.Config.ClientURL, "/") + "/accept-invite?token=" + token,
+			"ExpiresIn": util.HumanDuration(ttl),
+		},
+	}
+
+	req.InvitedBy = actor.ID
+	invite, err := api.CreateAdminInviteRepo(ctx, req, role.ID, util.HashToken(token), ttl, notice)
+	if err != nil {
+		return model.AdminInvite{}, values.Error, fmt.Sprintf("%s [AdIn]", values.SystemErr), err
+	}
+
+	return invite, values.Created, "Invite sent successfully", nil
+}
+
+// AcceptAdminInvite_H creates the account an invite was issued for.
+func (api *API) AcceptAdminInvite_H(req model.AcceptInviteRequest) (model.User, string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	req.Token = strings.TrimSpace(req.Token)
+	req.FirstName = strings.TrimSpace(req.FirstName)
+	req.LastName = strings.TrimSpace(req.LastName)
+	if req.Token == "" {
+		return model.User{}, values.BadRequestBody, "invite token is required", errors.New("missing invite token")
+	}
+	if message, err := validateStaffProfile(req.FirstName, req.LastName, req.Password, req.DateOfBirth, req.Sex); err != nil {
+		return model.User{}, values.BadRequestBody, message, err
+	}
+
+	hashedPassword, err := util.HashPassword([]byte(req.Password))
+	if err != nil {
+		return model.User{}, values.Error, fmt.Sprintf("%s [HsPw]", values.SystemErr), err
+	}
+	req.Password = hashedPassword
+
+	user, err := api.AcceptAdminInviteRepo(ctx, util.HashToken(req.Token), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrInviteInvalid):
+			return model.User{}, values.BadRequestBody, "invite link is invalid or has already been used", err
+		case errors.Is(err, model.ErrInviteExpired):
+			return model.User{}, values.BadRequestBody, "invite link has expired, please ask for a new one", err
+		}
+		return model.User{}, values.Error, fmt.Sprintf("%s [AcIn]", values.SystemErr), err
+	}
+
+	return user, values.Created, "Admin registration completed successfully", nil
+}
+
+// BootstrapAdmin creates the first super admin account. It is only reachable
+// from the command line and fails once a super admin exists.
+func (api *API) BootstrapAdmin(ctx context.Context, req model.BootstrapAdminRequest) (int, error) {
+	req.Email = strings.TrimSpace(req.Email)
+	req.FirstName = strings.TrimSpace(req.FirstName)
+	req.LastName = strings.TrimSpace(req.LastName)
+	if err := util.ValidEmail(req.Email); err != nil {
+		return 0, err
+	}
+	if message, err := validateStaffProfile(req.FirstName, req.LastName, req.Password, req.DateOfBirth, req.Sex); err != nil {
+		return 0, fmt.Errorf("%s: %w", message, err)
+	}
+
+	hashedPassword, err := util.HashPassword([]byte(req.Password))
+	if err != nil {
+		return 0, err
+	}
+	req.Password = hashedPassword
+
+	return api.BootstrapAdminRepo(ctx, req)
+}
+
+func validateStaffProfile(firstName, lastName, password, dateOfBirth, sex string) (string, error) {
+	if firstName == "" || lastName == "" {
+		return "first_name and last_name are required", errors.New("missing name")
+	}
+	if len(password) < 8 {
+		return "password must be at least 8 characters", errors.New("password too short")
+	}
+	if _, err := time.Parse("2006-01-02", dateOfBirth); err != nil {
+		return "date_of_birth must be in YYYY-MM-DD format", err
+	}
+	if sex != "Male" && sex != "Female" && sex != "Other" {
+		return "sex must be Male, Female or Other", errors.New("invalid sex")
+	}
+	return "", nil
+}
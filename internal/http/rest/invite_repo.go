@@ -0,0 +1,198 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateAdminInviteRepo stores a new invite, retiring any invite still pending
+// for the same email so only the latest link works, and queues notice
+// carrying the link.
+func (api *API) CreateAdminInviteRepo(ctx context.Context, req model.CreateInviteRequest, roleID int, tokenHash string, ttl time.Duration, notice *emailNotice) (model.AdminInvite, error) {
+	invite := model.AdminInvite{
+		Email:      req.Email,
+		Role:       req.Role,
+		HospitalID: req.HospitalID,
+		ExpiresAt:  time.Now().Add(ttl),
+	}
+
+	err := api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		stmt := `UPDATE admin_invites SET expires_at = NOW() WHERE email = ? AND accepted_at IS NULL AND expires_at > NOW()`
+		if _, err := tx.ExecContext(ctx, stmt, req.Email); err != nil {
+			return err
+		}
+
+		stmt = `INSERT INTO admin_invites (email, role_id, hospital_id, token_hash, invited_by, expires_at)
+			VALUES (?, ?, ?, ?, ?, DATE_ADD(NOW(), INTERVAL ? SECOND))`
+		result, err := tx.ExecContext(ctx, stmt, req.Email, roleID, req.HospitalID, tokenHash, req.InvitedBy, int(ttl.Seconds()))
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		invite.ID = int(id)
+
+		if err := notice.enqueue(ctx, tx); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, model.AuditEntry{
+			ActorID:    &req.InvitedBy,
+			Action:     model.AuditInviteCreated,
+			TargetType: "admin_invite",
+			TargetID:   strconv.Itoa(invite.ID),
+			Metadata: map[string]interface{}{
+				"email":       req.Email,
+				"role":        req.Role,
+				"hospital_id": req.HospitalID,
+			},
+			IPAddress: req.IPAddress,
+		})
+	})
+	return invite, err
+}
+
+// AcceptAdminInviteRepo consumes an invite and creates the account it was
+// issued for. The password in req must already be hashed. The invite row is
+// locked so the same token can never create two accounts.
+func (api *API) AcceptAdminInviteRepo(ctx context.Context, tokenHash string, req model.AcceptInviteRequest) (model.User, error) {
+	var user model.User
+	err := api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		var invite struct {
+			ID         int          `db:"id"`
+			Email      string       `db:"email"`
+			RoleID     int          `db:"role_id"`
+			Role       string       `db:"role"`
+			HospitalID *int         `db:"hospital_id"`
+			Expired    bool         `db:"expired"`
+			AcceptedAt sql.NullTime `db:"accepted_at"`
+		}
+		stmt := `SELECT i.id, i.email, i.role_id, r.name AS role, i.hospital_id, i.expires_at < NOW() AS expired, i.accepted_at
+			FROM admin_invites i
+			JOIN roles r ON r.id = i.role_id
+			WHERE i.token_hash = ?
+			FOR UPDATE`
+		if err := tx.GetContext(ctx, &invite, stmt, tokenHash); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.ErrInviteInvalid
+			}
+			return err
+		}
+		if invite.AcceptedAt.Valid {
+			return model.ErrInviteInvalid
+		}
+		if invite.Expired {
+			return model.ErrInviteExpired
+		}
+
+		// The invite link proves ownership of the address, so it starts verified
+		stmt = `INSERT INTO users (
+			firstName,
+			lastName,
+			email,
+			password,
+			dateOfBirth,
+			sex,
+			role_id,
+			hospital_id,
+			isEmailVerified
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1)`
+		result, err := tx.ExecContext(ctx, stmt, req.FirstName, req.LastName, invite.Email, req.Password, req.DateOfBirth, req.Sex, invite.RoleID, invite.HospitalID)
+		if err != nil {
+			return err
+		}
+		userID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE admin_invites SET accepted_at = NOW(), accepted_user_id = ? WHERE id = ?`, userID, invite.ID)
+		if err != nil {
+			return err
+		}
+
+		user = model.User{
+			ID:              int(userID),
+			FirstName:       req.FirstName,
+			LastName:        req.LastName,
+			Email:           invite.Email,
+			Sex:             req.Sex,
+			RoleID:          invite.RoleID,
+			Role:            invite.Role,
+			HospitalID:      invite.HospitalID,
+			IsActive:        true,
+			IsEmailVerified: true,
+		}
+
+		return recordAudit(ctx, tx, model.AuditEntry{
+			ActorID:    &user.ID,
+			Action:     model.AuditInviteAccepted,
+			TargetType: "admin_invite",
+			TargetID:   strconv.Itoa(invite.ID),
+			Metadata: map[string]interface{}{
+				"email": invite.Email,
+				"role":  invite.Role,
+			},
+			IPAddress: req.IPAddress,
+		})
+	})
+	return user, err
+}
+
+// BootstrapAdminRepo creates the first super admin. It refuses to run once a
+// super admin exists, so it cannot be used to mint further ones. Plain admins
+// do not count, since they cannot invite or promote a super admin.
+func (api *API) BootstrapAdminRepo(ctx context.Context, req model.BootstrapAdminRequest) (int, error) {
+	var userID int
+	err := api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		var exists bool
+		stmt := `SELECT EXISTS(
+			SELECT 1 FROM users u
+			JOIN roles r ON r.id = u.role_id
+			WHERE r.name = ?
+		)`
+		if err := tx.GetContext(ctx, &exists, stmt, model.RoleSuperAdmin); err != nil {
+			return err
+		}
+		if exists {
+			return model.ErrSuperAdminExists
+		}
+
+		stmt = `INSERT INTO users (
+			firstName,
+			lastName,
+			email,
+			password,
+			dateOfBirth,
+			sex,
+			role_id,
+			isEmailVerified
+		) VALUES (?, ?, ?, ?, ?, ?, (SELECT id FROM roles WHERE name = ?), 1)`
+		result, err := tx.ExecContext(ctx, stmt, req.FirstName, req.LastName, req.Email, req.Password, req.DateOfBirth, req.Sex, model.RoleSuperAdmin)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		userID = int(id)
+
+		return recordAudit(ctx, tx, model.AuditEntry{
+			Action:     model.AuditAdminBootstrap,
+			TargetType: "user",
+			TargetID:   strconv.Itoa(userID),
+			Metadata: map[string]interface{}{
+				"email": req.Email,
+			},
+		})
+	})
+	return userID, err
+}
@@ -1,7 +1,6 @@
 package rest
 
 import (
-	"log"
 	"net/http"
 	"strconv"
 
@@ -20,7 +19,7 @@ func (api *API) LabTestRoutes() chi.Router {
 	// Admin endpoints
 	mux.Group(func(r chi.Router) {
 		r.Use(api.RequireLogin)
-		r.Use(api.RequireAdmin)
+		r.Use(api.RequirePermission(model.PermLabTestsWrite))
 		r.Method(http.MethodPost, "/", Handler(api.CreateLabTestHandler))
 		r.Method(http.MethodPut, "/{labTestID}", Handler(api.UpdateLabTestHandler))
 		r.Method(http.MethodDelete, "/{labTestID}", Handler(api.DeleteLabTestHandler))
@@ -66,7 +65,6 @@ func (api *API) CreateLabTestHandler(_ http.ResponseWriter, r *http.Request) *Se
 	}
 	test, status, message, err := api.CreateLabTestHelper(req)
 	if err != nil {
-		log.Println(err)
 		return respondWithError(err, message, status, &tc)
 	}
 	return &ServerResponse{Message: message, Status: status, StatusCode: util.StatusCode(status), Data: test}
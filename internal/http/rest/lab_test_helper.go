@@ -1,33 +1,28 @@
 package rest
 
 import (
-	"context"
-	"log"
-
 	"github.com/bwise1/your_care_api/internal/model"
 	"github.com/bwise1/your_care_api/util/values"
 )
 
 func (api *API) GetAllLabTestsHelper() ([]model.LabTest, string, string, error) {
-	tests, err := api.GetAllLabTestsRepo(context.TODO())
+	tests, err := api.GetAllLabTestsRepo(api.rootContext())
 	if err != nil {
-		log.Println(err)
 		return nil, values.Error, "Failed to fetch lab tests", err
 	}
 	return tests, values.Success, "Fetched lab tests", nil
 }
 
 func (api *API) GetAvailableTestsForSelectionHelper() ([]model.TestForSelection, string, string, error) {
-	tests, err := api.GetAvailableTestsForSelectionRepo(context.TODO())
+	tests, err := api.GetAvailableTestsForSelectionRepo(api.rootContext())
 	if err != nil {
-		log.Println(err)
 		return nil, values.Error, "Failed to fetch available tests", err
 	}
 	return tests, values.Success, "Fetched available tests for selection", nil
 }
 
 func (api *API) CreateLabTestHelper(req model.LabTest) (model.LabTest, string, string, error) {
-	id, err := api.CreateLabTestRepo(context.TODO(), req)
+	id, err := api.CreateLabTestRepo(api.rootContext(), req)
 	if err != nil {
 		return model.LabTest{}, values.Error, "Failed to create lab test", err
 	}
@@ -36,7 +31,7 @@ func (api *API) CreateLabTestHelper(req model.LabTest) (model.LabTest, string, s
 }
 
 func (api *API) UpdateLabTestHelper(req model.LabTest) (string, string, error) {
-	err := api.UpdateLabTestRepo(context.TODO(), req)
+	err := api.UpdateLabTestRepo(api.rootContext(), req)
 	if err != nil {
 		return values.Error, "Failed to update lab test", err
 	}
@@ -44,7 +39,7 @@ func (api *API) UpdateLabTestHelper(req model.LabTest) (string, string, error) {
 }
 
 func (api *API) DeleteLabTestHelper(id int) (string, string, error) {
-	err := api.DeleteLabTestRepo(context.TODO(), id)
+	err := api.DeleteLabTestRepo(api.rootContext(), id)
 	if err != nil {
 		return values.Error, "Failed to delete lab test", err
 	}
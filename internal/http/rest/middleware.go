@@ -2,68 +2,203 @@ package rest
 
 import (
 	"context"
+	"crypto/subtle"
+	"database/sql"
 	"errors"
-	"log"
+	"net"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util/logger"
 	"github.com/bwise1/your_care_api/util/tracing"
 	"github.com/bwise1/your_care_api/util/values"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/lucsky/cuid"
 )
 
-// RequestTracing handles the request tracing context
-func RequestTracing(next http.Handler) http.Handler {
+// rgxRequestID limits client supplied request IDs to something safe to log
+var rgxRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestTracing handles the request tracing context. The request ID is
+// echoed back on the response and attached to the request's logger.
+func (api *API) RequestTracing(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-
 		requestID := r.Header.Get(values.HeaderRequestID)
-		if requestID == "" {
+		if !rgxRequestID.MatchString(requestID) {
 			requestID = cuid.New()
 		}
 
 		tracingContext := tracing.Context{
 			RequestID: requestID,
 		}
+		for header, value := range tracingContext.OutgoingHeaders() {
+			w.Header().Set(header, value)
+		}
 
 		ctx = context.WithValue(ctx, values.ContextTracingKey, tracingContext)
+		ctx = logger.WithContext(ctx, api.Deps.Logger.With("request_id", requestID))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 
 	return http.HandlerFunc(fn)
 }
 
+// accessLogEntry collects fields that are only known deeper in the handler
+// chain, such as the signed in user, for the access log line.
+type accessLogEntry struct {
+	userID int
+}
+
+type accessLogKey struct{}
+
+// AccessLog writes one line per request with its route, status and latency.
+func (api *API) AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &accessLogEntry{}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), accessLogKey{}, entry)))
+
+		route := r.URL.Path
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		attrs := []any{
+			"method", r.Method,
+			"route", route,
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"bytes", ww.BytesWritten(),
+		}
+		if entry.userID != 0 {
+			attrs = append(attrs, "user_id", entry.userID)
+		}
+
+		log := logger.FromContext(r.Context())
+		switch {
+		case status >= http.StatusInternalServerError:
+			log.Error("request", attrs...)
+		default:
+			log.Info("request", attrs...)
+		}
+	})
+}
+
+// RecordMetrics counts requests and their latency by method, route pattern
+// and status. Requests that match no route, or use a method outside
+// metricMethods, share a single series, so clients cannot mint new ones.
+func (api *API) RecordMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		method := r.Method
+		if !metricMethods[method] {
+			method = "OTHER"
+		}
+		api.Deps.Metrics.ObserveRequest(method, route, status, time.Since(start))
+	})
+}
+
+// metricMethods are the request methods recorded under their own name
+var metricMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// RequireMetricsToken only lets requests carrying the MetricsToken as a
+// bearer token through. Without a configured token the route is hidden.
+func (api *API) RequireMetricsToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if api.Config.MetricsToken == "" {
+			writeErrorResponse(w, r, errors.New(values.NotFound), values.NotFound, "not-found")
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(api.Config.MetricsToken)) != 1 {
+			writeErrorResponse(w, r, errors.New(values.NotAuthorised), values.NotAuthorised, "not-authorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setRequestUser records the signed in user on the access log entry and the
+// request's logger.
+func setRequestUser(r *http.Request, userID int) *http.Request {
+	if entry, ok := r.Context().Value(accessLogKey{}).(*accessLogEntry); ok {
+		entry.userID = userID
+	}
+	ctx := logger.WithContext(r.Context(), logger.FromContext(r.Context()).With("user_id", userID))
+	return r.WithContext(ctx)
+}
+
 func (api *API) RequireLogin(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authorization := strings.Split(r.Header.Get("Authorization"), " ")
 		if len(authorization) != 2 || authorization[0] != "Bearer" {
-			writeErrorResponse(w, errors.New(values.NotAuthorised), values.NotAuthorised, "not-authorized")
+			writeErrorResponse(w, r, errors.New(values.NotAuthorised), values.NotAuthorised, "not-authorized")
 			return
 		}
 
-		// log.Println(authorization[1])
 		claims, err := api.verifyToken(authorization[1], false)
 		if err != nil {
-			log.Println("error verifyig token", err.Error())
+			logger.FromContext(r.Context()).Debug("rejected access token", "error", err)
 			if err.Error() == "token expired" {
 				// Handle the expired token case
-				writeErrorResponse(w, err, values.TokenExpired, "token-expired")
+				writeErrorResponse(w, r, err, values.TokenExpired, "token-expired")
 				return
 			}
-			writeErrorResponse(w, err, values.NotAuthorised, "invalid-token")
+			writeErrorResponse(w, r, err, values.NotAuthorised, "invalid-token")
 			return
 		}
 
-		dbCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		dbCtx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
-		log.Println("claims", claims.UserID)
+		// Access tokens stop working as soon as their session is revoked
+		if claims.SessionID != "" {
+			active, err := api.SessionActive(dbCtx, claims.SessionID)
+			if err != nil || !active {
+				writeErrorResponse(w, r, errors.New(values.NotAuthorised), values.NotAuthorised, "session-revoked")
+				return
+			}
+		}
+
 		// Get additional user info from database if needed
 		user, err := api.GetUserByID(dbCtx, claims.UserID)
 		if err != nil {
-			writeErrorResponse(w, err, values.NotAuthorised, "user-not-found")
+			writeErrorResponse(w, r, err, values.NotAuthorised, "user-not-found")
 			return
 		}
 
@@ -71,37 +206,257 @@ func (api *API) RequireLogin(next http.Handler) http.Handler {
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, "user_id", claims.UserID)
 		ctx = context.WithValue(ctx, "user", user)
+		ctx = context.WithValue(ctx, "session_id", claims.SessionID)
 
-		// Add is_admin or role to context
-		isAdmin := user.RoleID == 2 // Assuming 2 is admin
+		permissions, err := api.GetRolePermissions(dbCtx, user.RoleID)
+		if err != nil {
+			writeErrorResponse(w, r, err, values.Error, "unable to load permissions")
+			return
+		}
+
+		// Hospital scoped staff act only within their hospital. Until one is
+		// assigned to them they get no permissions at all.
+		var hospitalID *int
+		if user.HospitalScoped {
+			if user.HospitalID == nil {
+				permissions = model.PermissionSet{}
+			}
+			hospitalID = user.HospitalID
+		}
+
+		// is_admin means the user can see every appointment, not just their own
+		isAdmin := permissions.Has(model.PermAppointmentsRead) && hospitalID == nil
 
-		log.Println("mid admin ", user.RoleID)
 		ctx = context.WithValue(ctx, "is_admin", isAdmin)
 		ctx = context.WithValue(ctx, "role", user.Role)
+		ctx = context.WithValue(ctx, "permissions", permissions)
+		ctx = context.WithValue(ctx, "hospital_id", hospitalID)
 
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, setRequestUser(r.WithContext(ctx), user.ID))
+	})
+}
+
+// RequirePermission only lets through users whose role grants permission.
+// It must run after RequireLogin.
+func (api *API) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			permissions, _ := r.Context().Value("permissions").(model.PermissionSet)
+			if !permissions.Has(permission) {
+				writeErrorResponse(w, r, errors.New(values.NotAllowed), values.NotAllowed, "missing permission "+permission)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// scopedHospitalID returns the hospital the signed-in user is restricted to,
+// or nil when they can act on every hospital.
+func scopedHospitalID(r *http.Request) *int {
+	hospitalID, _ := r.Context().Value("hospital_id").(*int)
+	return hospitalID
+}
+
+// inHospitalScope reports whether a resource belonging to hospitalID can be
+// touched by the signed-in user.
+func inHospitalScope(r *http.Request, hospitalID *int) bool {
+	scope := scopedHospitalID(r)
+	return scope == nil || (hospitalID != nil && *hospitalID == *scope)
+}
+
+// RequireAppointmentScope hides appointments outside a scoped user's hospital.
+// It expects the appointment ID in the {id} URL parameter.
+func (api *API) RequireAppointmentScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if scopedHospitalID(r) == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		appointmentID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeErrorResponse(w, r, err, values.BadRequestBody, "Invalid appointment ID")
+			return
+		}
+
+		hospitalID, err := api.AppointmentHospitalID(r.Context(), appointmentID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			writeErrorResponse(w, r, err, values.Error, "unable to check appointment access")
+			return
+		}
+		if err != nil || !inHospitalScope(r, hospitalID) {
+			writeErrorResponse(w, r, errors.New(values.NotFound), values.NotFound, "Appointment not found")
+			return
+		}
+
+		next.ServeHTTP(w, r)
 	})
 }
 
-func (api *API) RequireAdmin(next http.Handler) http.Handler {
+// RequireProviderScope keeps scoped users to the schedules of their own
+// hospital's doctors and lab. It expects {providerType} and {providerID}.
+func (api *API) RequireProviderScope(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		userID := r.Context().Value("user_id").(int) // Get the user ID from the context
+		if scopedHospitalID(r) == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-		// log.Println("Heree")
-		// Fetch the user from the database to get the role ID
-		user, err := api.GetUserByID(r.Context(), userID)
+		providerID, err := strconv.Atoi(chi.URLParam(r, "providerID"))
 		if err != nil {
-			writeErrorResponse(w, errors.New(values.NotAuthorised), values.NotAuthorised, "user not found")
+			writeErrorResponse(w, r, err, values.BadRequestBody, "provider_id must be a number")
+			return
+		}
+
+		var hospitalID *int
+		switch model.ProviderType(chi.URLParam(r, "providerType")) {
+		case model.ProviderDoctor:
+			hospitalID, err = api.DoctorHospitalID(r.Context(), providerID)
+		case model.ProviderHospitalLab:
+			hospitalID = &providerID
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			writeErrorResponse(w, r, err, values.Error, "unable to check provider access")
+			return
+		}
+		if err != nil || !inHospitalScope(r, hospitalID) {
+			writeErrorResponse(w, r, errors.New(values.NotAllowed), values.NotAllowed, "provider belongs to another hospital")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireHospitalScope keeps scoped users to their own hospital. It expects
+// the hospital ID in {hospitalID}, or a hospital lab test ID in {labTestID}.
+func (api *API) RequireHospitalScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if scopedHospitalID(r) == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		param, lookup := chi.URLParam(r, "hospitalID"), false
+		if param == "" {
+			param, lookup = chi.URLParam(r, "labTestID"), true
+		}
+		id, err := strconv.Atoi(param)
+		if err != nil {
+			writeErrorResponse(w, r, err, values.BadRequestBody, "unable to parse id")
+			return
+		}
+
+		hospitalID := &id
+		if lookup {
+			hospitalID, err = api.HospitalLabTestHospitalID(r.Context(), id)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				writeErrorResponse(w, r, err, values.Error, "unable to check hospital access")
+				return
+			}
+		}
+		if err != nil || !inHospitalScope(r, hospitalID) {
+			writeErrorResponse(w, r, errors.New(values.NotAllowed), values.NotAllowed, "resource belongs to another hospital")
 			return
 		}
 
-		// log.Println(user)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireVerifiedEmail blocks users who have not verified their email address
+// when the REQUIRE_EMAIL_VERIFICATION policy is on. It must run after RequireLogin.
+func (api *API) RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !api.Config.RequireEmailVerification {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-		if user.RoleID != 2 { // Assuming 2 is the role ID for admin
-			writeErrorResponse(w, errors.New(values.NotAuthorised), values.NotAuthorised, "admin access required")
+		user, ok := r.Context().Value("user").(model.User)
+		if !ok {
+			writeErrorResponse(w, r, errors.New(values.NotAuthorised), values.NotAuthorised, "not-authorized")
+			return
+		}
+		if !user.IsEmailVerified {
+			writeErrorResponse(w, r, errors.New(values.NotAllowed), values.NotAllowed, "email-not-verified")
 			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// clientIP returns the address of the client. X-Forwarded-For and X-Real-IP
+// are only believed when the request comes from one of the TrustedProxies;
+// the client is then the nearest address in X-Forwarded-For that is not a
+// trusted proxy. Anyone else could put any address in those headers.
+func (api *API) clientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !api.isTrustedProxy(remote) {
+		return remote
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if i == 0 || !api.isTrustedProxy(hop) {
+				return hop
+			}
+		}
+	}
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		return realIP
+	}
+	return remote
+}
+
+// trustedProxies holds the parsed TrustedProxies setting.
+type trustedProxies struct {
+	once sync.Once
+	nets []*net.IPNet
+}
+
+// isTrustedProxy reports whether addr is in one of the TrustedProxies, which
+// may be single addresses or CIDR ranges. Invalid entries are logged once and
+// ignored.
+func (api *API) isTrustedProxy(addr string) bool {
+	api.trustedProxies.once.Do(func() {
+		for _, entry := range api.Config.TrustedProxies {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			if !strings.Contains(entry, "/") {
+				if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+					entry += "/32"
+				} else {
+					entry += "/128"
+				}
+			}
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				api.Deps.Logger.Error("ignoring invalid trusted proxy", "proxy", entry, "error", err)
+				continue
+			}
+			api.trustedProxies.nets = append(api.trustedProxies.nets, network)
+		}
+	})
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range api.trustedProxies.nets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/bwise1/your_care_api/util"
+	"github.com/bwise1/your_care_api/util/tracing"
+	"github.com/bwise1/your_care_api/util/values"
+	"github.com/go-chi/chi/v5"
+)
+
+func (api *API) GetNotifications(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	messages, status, message, err := api.GetNotifications_H(r.URL.Query().Get("status"), limit)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       messages,
+	}
+}
+
+func (api *API) GetNotification(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "notificationID"), 10, 64)
+	if err != nil {
+		return respondWithError(err, "Invalid notification ID", values.BadRequestBody, &tc)
+	}
+
+	notification, status, message, err := api.GetNotification_H(id)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       notification,
+	}
+}
+
+func (api *API) ReplayNotification(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "notificationID"), 10, 64)
+	if err != nil {
+		return respondWithError(err, "Invalid notification ID", values.BadRequestBody, &tc)
+	}
+
+	status, message, err := api.ReplayNotification_H(id)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+	}
+}
@@ -0,0 +1,87 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/outbox"
+	"github.com/bwise1/your_care_api/util/values"
+)
+
+const maxNotificationListLimit = 200
+
+// secretTemplates carry one-time codes and links. Their payloads are kept out
+// of the notification endpoints, so reading the outbox cannot be used to take
+// over an account.
+var secretTemplates = map[string]bool{
+	"verifyEmail.tmpl": true,
+	"resetEmail.tmpl":  true,
+	"adminInvite.tmpl": true,
+}
+
+func redactSecretPayload(message *outbox.Message) {
+	if secretTemplates[message.Template] {
+		message.Payload = json.RawMessage(`{"redacted":true}`)
+	}
+}
+
+func (api *API) GetNotifications_H(status string, limit int) ([]outbox.Message, string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	if status == "" {
+		status = outbox.StatusDead
+	}
+	if status != outbox.StatusPending && status != outbox.StatusSent && status != outbox.StatusDead {
+		return nil, values.BadRequestBody, "status must be pending, sent or dead", errors.New("invalid notification status")
+	}
+	if limit <= 0 || limit > maxNotificationListLimit {
+		limit = 50
+	}
+
+	messages, err := api.Deps.Outbox.List(ctx, status, limit)
+	if err != nil {
+		return nil, values.Error, fmt.Sprintf("%s [GeNo]", values.SystemErr), err
+	}
+	for i := range messages {
+		redactSecretPayload(&messages[i])
+	}
+	return messages, values.Success, "Fetched notifications successfully", nil
+}
+
+func (api *API) GetNotification_H(id int64) (outbox.Message, string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	message, err := api.Deps.Outbox.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return outbox.Message{}, values.NotFound, "Notification not found", err
+		}
+		return outbox.Message{}, values.Error, fmt.Sprintf("%s [GeNo]", values.SystemErr), err
+	}
+	redactSecretPayload(&message)
+	return message, values.Success, "Fetched notification successfully", nil
+}
+
+// ReplayNotification_H queues a dead notification for delivery again.
+func (api *API) ReplayNotification_H(id int64) (string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	err := api.Deps.Outbox.Replay(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return values.NotFound, "Notification not found", err
+		case errors.Is(err, outbox.ErrNotDead):
+			return values.Conflict, "Only dead notifications can be replayed", err
+		}
+		return values.Error, fmt.Sprintf("%s [RpNo]", values.SystemErr), err
+	}
+	return values.Success, "Notification queued for delivery", nil
+}
@@ -0,0 +1,152 @@
+package rest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util/apperr"
+)
+
+// Lists are paged with opaque cursors holding the sort value and id of the
+// last row returned. The next page seeks past that row on an index, so deep
+// pages cost the same as the first, unlike LIMIT/OFFSET.
+
+const maxPageLimit = 100
+
+// appointmentSortColumns maps the sort keys clients may ask for to columns
+var appointmentSortColumns = map[string]string{
+	model.SortAppointmentDatetime: "a.appointment_datetime",
+	model.SortCreatedAt:           "a.created_at",
+}
+
+// pageCursor is the encoded form of a cursor. Sort and order are carried so a
+// cursor cannot be replayed against a list ordered differently.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// parsePageRequest reads limit, sort, order and cursor from the query string.
+// Invalid values are reported as validation errors.
+func parsePageRequest(query url.Values, defaultSort, defaultOrder string, defaultLimit int) (model.PageRequest, error) {
+	page := model.PageRequest{
+		Limit: defaultLimit,
+		Sort:  defaultSort,
+		Order: defaultOrder,
+	}
+	var fields []apperr.FieldError
+
+	if limit := query.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 1 || l > maxPageLimit {
+			fields = append(fields, apperr.FieldError{Field: "limit", Message: fmt.Sprintf("must be a number between 1 and %d", maxPageLimit)})
+		} else {
+			page.Limit = l
+		}
+	}
+	if sort := query.Get("sort"); sort != "" {
+		if _, ok := appointmentSortColumns[sort]; !ok {
+			fields = append(fields, apperr.FieldError{Field: "sort", Message: "must be one of: appointment_datetime, created_at"})
+		} else {
+			page.Sort = sort
+		}
+	}
+	if order := query.Get("order"); order != "" {
+		if order != model.SortAsc && order != model.SortDesc {
+			fields = append(fields, apperr.FieldError{Field: "order", Message: "must be one of: asc, desc"})
+		} else {
+			page.Order = order
+		}
+	}
+	if len(fields) > 0 {
+		return page, apperr.Validation(fields...)
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		c, err := decodeCursor(cursor)
+		if err != nil || c.Sort != page.Sort || c.Order != page.Order {
+			return page, apperr.Validation(apperr.FieldError{Field: "cursor", Message: "is invalid or does not match the requested sort"})
+		}
+		page.After = &model.PageCursor{Value: c.Value, ID: c.ID}
+	}
+	return page, nil
+}
+
+func encodeCursor(c pageCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(value string) (pageCursor, error) {
+	var c pageCursor
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+	if c.ID <= 0 || c.Value == "" {
+		return c, fmt.Errorf("incomplete cursor")
+	}
+	return c, nil
+}
+
+// appendAppointmentPage adds the seek past the cursor, the ordering and the
+// limit to an appointment list query. One row more than the limit is fetched
+// to tell whether another page follows.
+func appendAppointmentPage(query string, args []interface{}, page model.PageRequest) (string, []interface{}) {
+	column := appointmentSortColumns[page.Sort]
+	direction, comparison := "DESC", "<"
+	if page.Order == model.SortAsc {
+		direction, comparison = "ASC", ">"
+	}
+
+	if page.After != nil {
+		query += fmt.Sprintf(" AND (%[1]s %[2]s ? OR (%[1]s = ? AND a.id %[2]s ?))", column, comparison)
+		args = append(args, page.After.Value, page.After.Value, page.After.ID)
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, a.id %s LIMIT ?", column, direction, direction)
+	args = append(args, page.Limit+1)
+	return query, args
+}
+
+// newAppointmentPage trims the extra row fetched by appendAppointmentPage and
+// builds the cursor for the next page from the last row kept.
+func newAppointmentPage(appointments []model.AppointmentDetails, page model.PageRequest) model.AppointmentPage {
+	result := model.AppointmentPage{
+		Appointments: appointments,
+		Pagination:   model.Pagination{Limit: page.Limit},
+	}
+	if len(appointments) <= page.Limit {
+		return result
+	}
+
+	result.Appointments = appointments[:page.Limit]
+	result.Pagination.HasMore = true
+	last := result.Appointments[page.Limit-1]
+	sortValue := last.AppointmentDatetime
+	if page.Sort == model.SortCreatedAt {
+		sortValue = last.CreatedAt
+	}
+	if sortValue == nil {
+		return result
+	}
+
+	// Values are formatted in the location the driver read them in, which
+	// gives back the stored wall-clock time to compare against
+	cursor := encodeCursor(pageCursor{
+		Sort:  page.Sort,
+		Order: page.Order,
+		Value: sortValue.Format(slotTimeLayout),
+		ID:    last.ID,
+	})
+	result.Pagination.NextCursor = &cursor
+	return result
+}
@@ -0,0 +1,164 @@
+package rest
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util/apperr"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := pageCursor{Sort: model.SortCreatedAt, Order: model.SortAsc, Value: "2024-10-17 14:30:00", ID: 42}
+	got, err := decodeCursor(encodeCursor(want))
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeCursorRejectsBadInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "not a cursor!"},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("plain text"))},
+		{"missing id", encodeCursor(pageCursor{Sort: model.SortCreatedAt, Order: model.SortDesc, Value: "2024-10-17 14:30:00"})},
+		{"missing value", encodeCursor(pageCursor{Sort: model.SortCreatedAt, Order: model.SortDesc, ID: 7})},
+	}
+	for _, tt := range tests {
+		if _, err := decodeCursor(tt.cursor); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestParsePageRequest(t *testing.T) {
+	valid := encodeCursor(pageCursor{Sort: model.SortAppointmentDatetime, Order: model.SortDesc, Value: "2024-10-17 14:30:00", ID: 9})
+	otherSort := encodeCursor(pageCursor{Sort: model.SortCreatedAt, Order: model.SortDesc, Value: "2024-10-17 14:30:00", ID: 9})
+
+	tests := []struct {
+		name       string
+		query      string
+		wantLimit  int
+		wantSort   string
+		wantOrder  string
+		wantAfter  *model.PageCursor
+		wantFields []string
+	}{
+		{name: "defaults", query: "", wantLimit: 20, wantSort: model.SortAppointmentDatetime, wantOrder: model.SortDesc},
+		{name: "explicit", query: "limit=5&sort=created_at&order=asc", wantLimit: 5, wantSort: model.SortCreatedAt, wantOrder: model.SortAsc},
+		{name: "cursor", query: "cursor=" + valid, wantLimit: 20, wantSort: model.SortAppointmentDatetime, wantOrder: model.SortDesc, wantAfter: &model.PageCursor{Value: "2024-10-17 14:30:00", ID: 9}},
+		{name: "limit too large", query: "limit=101", wantFields: []string{"limit"}},
+		{name: "limit not a number", query: "limit=ten", wantFields: []string{"limit"}},
+		{name: "every field invalid", query: "limit=0&sort=name&order=up", wantFields: []string{"limit", "sort", "order"}},
+		{name: "garbled cursor", query: "cursor=abc", wantFields: []string{"cursor"}},
+		{name: "cursor for another sort", query: "cursor=" + otherSort, wantFields: []string{"cursor"}},
+		{name: "cursor for another order", query: "order=asc&cursor=" + valid, wantFields: []string{"cursor"}},
+	}
+	for _, tt := range tests {
+		query, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("%s: bad test query: %v", tt.name, err)
+		}
+		page, err := parsePageRequest(query, model.SortAppointmentDatetime, model.SortDesc, 20)
+
+		if tt.wantFields != nil {
+			var appErr *apperr.Error
+			if !errors.As(err, &appErr) || appErr.Code != apperr.CodeValidation {
+				t.Errorf("%s: got %v, want a validation error", tt.name, err)
+				continue
+			}
+			var fields []string
+			for _, f := range appErr.Fields {
+				fields = append(fields, f.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("%s: invalid fields %v, want %v", tt.name, fields, tt.wantFields)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if page.Limit != tt.wantLimit || page.Sort != tt.wantSort || page.Order != tt.wantOrder {
+			t.Errorf("%s: got %d %s %s, want %d %s %s", tt.name, page.Limit, page.Sort, page.Order, tt.wantLimit, tt.wantSort, tt.wantOrder)
+		}
+		if (page.After == nil) != (tt.wantAfter == nil) || (page.After != nil && *page.After != *tt.wantAfter) {
+			t.Errorf("%s: got cursor %+v, want %+v", tt.name, page.After, tt.wantAfter)
+		}
+	}
+}
+
+func TestAppendAppointmentPage(t *testing.T) {
+	tests := []struct {
+		name      string
+		page      model.PageRequest
+		wantQuery string
+		wantArgs  int
+	}{
+		{
+			name:      "first page",
+			page:      model.PageRequest{Limit: 10, Sort: model.SortCreatedAt, Order: model.SortDesc},
+			wantQuery: " ORDER BY a.created_at DESC, a.id DESC LIMIT ?",
+			wantArgs:  1,
+		},
+		{
+			name:      "after a cursor",
+			page:      model.PageRequest{Limit: 10, Sort: model.SortAppointmentDatetime, Order: model.SortAsc, After: &model.PageCursor{Value: "2024-10-17 14:30:00", ID: 3}},
+			wantQuery: " AND (a.appointment_datetime > ? OR (a.appointment_datetime = ? AND a.id > ?)) ORDER BY a.appointment_datetime ASC, a.id ASC LIMIT ?",
+			wantArgs:  4,
+		},
+	}
+	for _, tt := range tests {
+		query, args := appendAppointmentPage("", nil, tt.page)
+		if query != tt.wantQuery {
+			t.Errorf("%s: query %q, want %q", tt.name, query, tt.wantQuery)
+		}
+		if len(args) != tt.wantArgs || args[len(args)-1] != tt.page.Limit+1 {
+			t.Errorf("%s: args %v, want %d ending in limit+1", tt.name, args, tt.wantArgs)
+		}
+	}
+}
+
+func TestNewAppointmentPage(t *testing.T) {
+	at := func(hour int) *time.Time {
+		v := time.Date(2024, 10, 17, hour, 0, 0, 0, time.Local)
+		return &v
+	}
+	rows := []model.AppointmentDetails{
+		{ID: 1, AppointmentDatetime: at(9)},
+		{ID: 2, AppointmentDatetime: at(10)},
+		{ID: 3, AppointmentDatetime: at(11)},
+	}
+	page := model.PageRequest{Limit: 2, Sort: model.SortAppointmentDatetime, Order: model.SortAsc}
+
+	result := newAppointmentPage(rows, page)
+	if len(result.Appointments) != 2 || !result.Pagination.HasMore || result.Pagination.NextCursor == nil {
+		t.Fatalf("got %d rows, has_more %v, cursor %v; want 2 rows and a next cursor", len(result.Appointments), result.Pagination.HasMore, result.Pagination.NextCursor)
+	}
+
+	// The cursor leads back to the last row kept
+	query := url.Values{"sort": {page.Sort}, "order": {page.Order}, "cursor": {*result.Pagination.NextCursor}}
+	next, err := parsePageRequest(query, model.SortCreatedAt, model.SortDesc, 20)
+	if err != nil {
+		t.Fatalf("parsePageRequest: %v", err)
+	}
+	if next.After == nil || next.After.ID != 2 || next.After.Value != "2024-10-17 10:00:00" {
+		t.Fatalf("got cursor %+v, want row 2 at 2024-10-17 10:00:00", next.After)
+	}
+
+	last := newAppointmentPage(rows[:2], page)
+	if last.Pagination.HasMore || last.Pagination.NextCursor != nil {
+		t.Fatalf("a page that fits the limit has more: %+v", last.Pagination)
+	}
+}
@@ -0,0 +1,106 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util"
+	"github.com/bwise1/your_care_api/util/tracing"
+	"github.com/bwise1/your_care_api/util/values"
+	"github.com/go-chi/chi/v5"
+)
+
+func (api *API) ProfileRoutes() chi.Router {
+	mux := chi.NewRouter()
+	mux.Use(api.RequireLogin)
+	mux.Method(http.MethodGet, "/notifications", Handler(api.GetNotificationPreferences))
+	mux.Method(http.MethodPut, "/notifications", Handler(api.UpdateNotificationPreferences))
+	mux.Method(http.MethodPost, "/push-tokens", Handler(api.RegisterPushToken))
+	mux.Method(http.MethodDelete, "/push-tokens", Handler(api.RemovePushToken))
+	return mux
+}
+
+func (api *API) GetNotificationPreferences(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	userID := r.Context().Value("user_id").(int)
+
+	prefs, status, message, err := api.GetNotificationPreferences_H(userID)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       prefs,
+	}
+}
+
+func (api *API) UpdateNotificationPreferences(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	var req model.UpdateNotificationPreferencesReq
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse notification preferences", values.BadRequestBody, &tc)
+	}
+
+	userID := r.Context().Value("user_id").(int)
+
+	prefs, status, message, err := api.UpdateNotificationPreferences_H(userID, req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       prefs,
+	}
+}
+
+func (api *API) RegisterPushToken(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	var req model.PushTokenReq
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse push token request", values.BadRequestBody, &tc)
+	}
+
+	userID := r.Context().Value("user_id").(int)
+
+	status, message, err := api.RegisterPushToken_H(userID, req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+	}
+}
+
+func (api *API) RemovePushToken(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	var req model.PushTokenReq
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse push token request", values.BadRequestBody, &tc)
+	}
+
+	userID := r.Context().Value("user_id").(int)
+
+	status, message, err := api.RemovePushToken_H(userID, req.Token)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+	}
+}
@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util/values"
+)
+
+// rgxPhone matches numbers in E.164 format, which SMS providers expect.
+var rgxPhone = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+func (api *API) GetNotificationPreferences_H(userID int) (model.NotificationPreferences, string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	prefs, err := api.GetNotificationPreferencesRepo(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.NotificationPreferences{}, values.NotFound, "User not found", err
+		}
+		return model.NotificationPreferences{}, values.Error, fmt.Sprintf("%s [GeNp]", values.SystemErr), err
+	}
+	return prefs, values.Success, "Fetched notification preferences successfully", nil
+}
+
+func (api *API) UpdateNotificationPreferences_H(userID int, req model.UpdateNotificationPreferencesReq) (model.NotificationPreferences, string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	prefs, status, message, err := api.GetNotificationPreferences_H(userID)
+	if err != nil {
+		return model.NotificationPreferences{}, status, message, err
+	}
+
+	if req.Email != nil {
+		prefs.Email = *req.Email
+	}
+	if req.SMS != nil {
+		prefs.SMS = *req.SMS
+	}
+	if req.Push != nil {
+		prefs.Push = *req.Push
+	}
+	if req.Phone != nil {
+		phone := strings.ReplaceAll(strings.TrimSpace(*req.Phone), " ", "")
+		if phone != "" && !rgxPhone.MatchString(phone) {
+			return model.NotificationPreferences{}, values.BadRequestBody, "phone must be in international format, e.g. +2348012345678", errors.New("invalid phone number")
+		}
+		prefs.Phone = optionalString(phone)
+	}
+	if prefs.SMS && prefs.Phone == nil {
+		return model.NotificationPreferences{}, values.BadRequestBody, "add a phone number to turn on SMS notifications", errors.New("sms without phone")
+	}
+
+	if err := api.UpdateNotificationPreferencesRepo(ctx, userID, prefs); err != nil {
+		return model.NotificationPreferences{}, values.Error, fmt.Sprintf("%s [UpNp]", values.SystemErr), err
+	}
+	return prefs, values.Success, "Notification preferences updated successfully", nil
+}
+
+func (api *API) RegisterPushToken_H(userID int, req model.PushTokenReq) (string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" || len(req.Token) > 512 {
+		return values.BadRequestBody, "a device token of at most 512 characters is required", errors.New("invalid push token")
+	}
+	switch req.Platform {
+	case model.PushPlatformIOS, model.PushPlatformAndroid, model.PushPlatformWeb:
+	default:
+		return values.BadRequestBody, "platform must be ios, android or web", errors.New("invalid push platform")
+	}
+
+	if err := api.SavePushTokenRepo(ctx, userID, req); err != nil {
+		return values.Error, fmt.Sprintf("%s [RgPt]", values.SystemErr), err
+	}
+	return values.Success, "Device registered for push notifications", nil
+}
+
+func (api *API) RemovePushToken_H(userID int, token string) (string, string, error) {
+	ctx, cancel := context.WithTimeout(api.rootContext(), 5*time.Second)
+	defer cancel()
+
+	err := api.DeletePushTokenRepo(ctx, userID, strings.TrimSpace(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return values.NotFound, "Device not found", err
+		}
+		return values.Error, fmt.Sprintf("%s [RmPt]", values.SystemErr), err
+	}
+	return values.Success, "Device removed from push notifications", nil
+}
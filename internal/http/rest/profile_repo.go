@@ -0,0 +1,44 @@
+package rest
+
+import (
+	"context"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+func (api *API) GetNotificationPreferencesRepo(ctx context.Context, userID int) (model.NotificationPreferences, error) {
+	var prefs model.NotificationPreferences
+	stmt := `SELECT notify_email, notify_sms, notify_push, phone FROM users WHERE id = ?`
+	err := api.Deps.DB.GetContext(ctx, &prefs, stmt, userID)
+	return prefs, err
+}
+
+func (api *API) UpdateNotificationPreferencesRepo(ctx context.Context, userID int, prefs model.NotificationPreferences) error {
+	stmt := `UPDATE users SET notify_email = ?, notify_sms = ?, notify_push = ?, phone = ? WHERE id = ?`
+	_, err := api.Deps.DB.ExecContext(ctx, stmt, prefs.Email, prefs.SMS, prefs.Push, prefs.Phone, userID)
+	return err
+}
+
+// SavePushTokenRepo registers a device for the user. A token already known to
+// the system moves to this user, since a device only has one signed-in account.
+func (api *API) SavePushTokenRepo(ctx context.Context, userID int, req model.PushTokenReq) error {
+	stmt := `INSERT INTO user_push_tokens (user_id, token, platform) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE user_id = VALUES(user_id), platform = VALUES(platform)`
+	_, err := api.Deps.DB.ExecContext(ctx, stmt, userID, req.Token, req.Platform)
+	return err
+}
+
+func (api *API) DeletePushTokenRepo(ctx context.Context, userID int, token string) error {
+	result, err := api.Deps.DB.ExecContext(ctx, `DELETE FROM user_push_tokens WHERE user_id = ? AND token = ?`, userID, token)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+func getPushTokens(ctx context.Context, q sqlx.QueryerContext, userID int) ([]string, error) {
+	var tokens []string
+	err := sqlx.SelectContext(ctx, q, &tokens, `SELECT token FROM user_push_tokens WHERE user_id = ? ORDER BY id`, userID)
+	return tokens, err
+}
@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwise1/your_care_api/util/values"
+)
+
+// RateLimit allows each client IP at most limit requests per window to the
+// routes it wraps, answering the rest with 429. Counts are kept in memory, so
+// each instance of the API limits on its own. A limit below one disables it.
+func (api *API) RateLimit(limit int, window time.Duration) func(http.Handler) http.Handler {
+	limiter := &rateLimiter{limit: limit, window: window, clients: map[string]*rateWindow{}}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit < 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if retryAfter, ok := limiter.allow(api.clientIP(r), time.Now()); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+				writeErrorResponse(w, r, errors.New(values.TooManyRequests), values.TooManyRequests, "too-many-requests")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimiter counts requests per key in fixed windows.
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	clients   map[string]*rateWindow
+	nextPrune time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// allow records a request for key and reports whether it is within the limit,
+// and if not, how long until the key's window resets.
+func (l *rateLimiter) allow(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Forget clients whose window has ended, at most once per window
+	if now.After(l.nextPrune) {
+		for k, w := range l.clients {
+			if now.Sub(w.start) >= l.window {
+				delete(l.clients, k)
+			}
+		}
+		l.nextPrune = now.Add(l.window)
+	}
+
+	w, ok := l.clients[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.clients[key] = w
+	}
+	if w.count >= l.limit {
+		return w.start.Add(l.window).Sub(now), false
+	}
+	w.count++
+	return 0, true
+}
@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwise1/your_care_api/util"
+)
+
+// SendAppointmentReminders queues a reminder for every appointment that has
+// reached one of the lead times. leads must be sorted longest first. Each
+// appointment gets at most one reminder per lead time, and an appointment
+// booked inside a shorter lead time skips straight to that reminder.
+func (api *API) SendAppointmentReminders(ctx context.Context, leads []time.Duration) error {
+	for i, lead := range leads {
+		var shorter time.Duration
+		if i+1 < len(leads) {
+			shorter = leads[i+1]
+		}
+
+		ids, err := api.DueRemindersRepo(ctx, lead, shorter)
+		if err != nil {
+			return fmt.Errorf("finding reminders due %s before: %w", lead, err)
+		}
+
+		for _, id := range ids {
+			if err := api.SendReminderRepo(ctx, id, lead, reminderNotice(lead)); err != nil {
+				api.Deps.Logger.Error("error sending reminder", "appointment_id", id, "lead", lead.String(), "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// reminderNotice reminds the patient of an appointment that starts within
+// lead. The time left is worked out when the reminder is queued, so one sent
+// late, or for an appointment booked inside the lead, does not overstate it.
+// Lab tests carry preparation text for the pickup type.
+func reminderNotice(lead time.Duration) *appointmentNotice {
+	return &appointmentNotice{
+		template: "appointmentReminder.tmpl",
+		data: func(appointment AppointmentEmailData) map[string]interface{} {
+			pickupType := ""
+			if appointment.PickupType != nil {
+				pickupType = *appointment.PickupType
+			}
+			startsIn := lead
+			startsAt, err := time.ParseInLocation("2006-01-02 15:04", appointment.AppointmentDate+" "+appointment.AppointmentTime, time.Local)
+			if err == nil {
+				startsIn = roundTimeLeft(time.Until(startsAt))
+			}
+			return map[string]interface{}{
+				"PatientName":     appointment.PatientName,
+				"AppointmentType": appointment.AppointmentType,
+				"AppointmentDate": appointment.AppointmentDate,
+				"AppointmentTime": appointment.AppointmentTime,
+				"StartsIn":        util.HumanDuration(startsIn),
+				"TestName":        appointment.TestName,
+				"HospitalName":    appointment.HospitalName,
+				"HomePickup":      pickupType == "home",
+				"HospitalPickup":  pickupType == "hospital",
+				"HomeLocation":    appointment.HomeLocation,
+				"Instructions":    appointment.Instructions,
+			}
+		},
+	}
+}
+
+// roundTimeLeft rounds to whole hours from an hour up and to whole minutes
+// below that, so reminders read "24 hours" rather than "1437 minutes".
+func roundTimeLeft(d time.Duration) time.Duration {
+	if d >= time.Hour {
+		return d.Round(time.Hour)
+	}
+	if d < time.Minute {
+		return time.Minute
+	}
+	return d.Round(time.Minute)
+}
@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"context"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+const reminderBatchSize = 100
+
+// remindableStatuses are the statuses of appointments that are going ahead
+var remindableStatuses = []interface{}{model.StatusConfirmed, model.StatusScheduled, model.StatusRescheduleAccepted}
+
+// DueRemindersRepo returns appointments starting within lead, but not within
+// the next shorter lead time, that have not had the reminder for lead at their
+// current time yet. A reschedule therefore brings the reminders back.
+func (api *API) DueRemindersRepo(ctx context.Context, lead, shorter time.Duration) ([]int, error) {
+	stmt := `SELECT a.id
+		FROM appointments a
+		LEFT JOIN appointment_reminders ar ON ar.appointment_id = a.id AND ar.lead_minutes = ?
+			AND ar.appointment_datetime = a.appointment_datetime
+		WHERE ar.id IS NULL
+		AND a.status IN (?, ?, ?)
+		AND a.appointment_datetime > DATE_ADD(NOW(), INTERVAL ? MINUTE)
+		AND a.appointment_datetime <= DATE_ADD(NOW(), INTERVAL ? MINUTE)
+		ORDER BY a.appointment_datetime
+		LIMIT ?`
+
+	args := []interface{}{int(lead.Minutes())}
+	args = append(args, remindableStatuses...)
+	args = append(args, int(shorter.Minutes()), int(lead.Minutes()), reminderBatchSize)
+
+	ids := []int{}
+	err := api.Deps.DB.SelectContext(ctx, &ids, stmt, args...)
+	return ids, err
+}
+
+// SendReminderRepo records the reminder for an appointment at its current time
+// and queues it in one transaction. It does nothing if the reminder was
+// already recorded or the appointment is no longer going ahead.
+func (api *API) SendReminderRepo(ctx context.Context, appointmentID int, lead time.Duration, notice *appointmentNotice) error {
+	return api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		stmt := `INSERT IGNORE INTO appointment_reminders (appointment_id, lead_minutes, appointment_datetime)
+			SELECT id, ?, appointment_datetime FROM appointments WHERE id = ? AND status IN (?, ?, ?)`
+		args := append([]interface{}{int(lead.Minutes()), appointmentID}, remindableStatuses...)
+		result, err := tx.ExecContext(ctx, stmt, args...)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return nil
+		}
+
+		return api.enqueueAppointmentNotice(ctx, tx, appointmentID, notice)
+	})
+}
@@ -3,12 +3,17 @@ package rest
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 
+	"github.com/bwise1/your_care_api/internal/model"
 	"github.com/bwise1/your_care_api/util"
+	"github.com/bwise1/your_care_api/util/apperr"
+	"github.com/bwise1/your_care_api/util/logger"
 	"github.com/bwise1/your_care_api/util/tracing"
 	"github.com/bwise1/your_care_api/util/values"
+	"github.com/go-chi/chi/v5"
 )
 
 type ServerResponse struct {
@@ -18,6 +23,18 @@ type ServerResponse struct {
 	StatusCode int             `json:"status_code"`
 	Context    context.Context `json:"context,omitempty"`
 	Data       interface{}     `json:"data,omitempty"`
+
+	// Set on errors only. Code is stable for clients to branch on, Errors
+	// lists invalid request fields.
+	Code      apperr.Code         `json:"code,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+	Errors    []apperr.FieldError `json:"errors,omitempty"`
+
+	// failed and cause are set by respondWithError so the failure is logged
+	// with the request's logger once the response is written. They are
+	// never sent to the client.
+	failed bool
+	cause  error
 }
 
 func respondWithJSONPayload(ctx *tracing.Context, data interface{}, status, message string) *ServerResponse {
@@ -35,29 +52,94 @@ func respondWithJSONPayload(ctx *tracing.Context, data interface{}, status, mess
 	}
 }
 
-// respondWithError logs an error with zap and parses the error to the ServerResponse
+// respondWithError parses the error to the ServerResponse. The error is
+// logged when the response is written, server errors at error level and client
+// errors only at debug.
+//
+// A typed apperr.Error decides the status, code and message itself. Missing
+// rows, duplicate keys and rows still in use only replace a generic error
+// status, so handlers that already picked a status for them keep it.
 func respondWithError(err error, message, status string, tracingContext *tracing.Context) *ServerResponse {
-	// logger.Log.Error(err.Error(), message, status)
-	return &ServerResponse{
-		// Err:        err,
+	code := apperr.CodeForStatus(status)
+	var fields []apperr.FieldError
+
+	if appErr, ok := apperr.From(err); ok {
+		var typed *apperr.Error
+		switch {
+		case errors.As(err, &typed) || status == values.Error:
+			status, code, fields = appErr.Status(), appErr.Code, appErr.Fields
+			if appErr.Message != "" {
+				message = appErr.Message
+			}
+		case appErr.Code == apperr.CodeValidation && status == values.BadRequestBody:
+			code, fields = appErr.Code, appErr.Fields
+		}
+	}
+
+	response := &ServerResponse{
 		Message:    message,
 		Status:     status,
 		StatusCode: util.StatusCode(status),
+		Code:       code,
+		Errors:     fields,
+		failed:     true,
+		cause:      err,
+	}
+	if tracingContext != nil {
+		response.RequestID = tracingContext.RequestID
+	}
+
+	// Illegal status changes tell the client where the appointment can go instead
+	var transitionErr *model.TransitionError
+	if errors.As(err, &transitionErr) {
+		response.Code = apperr.CodeInvalidTransition
+		response.Data = map[string]interface{}{
+			"current_status":      transitionErr.From,
+			"requested_status":    transitionErr.To,
+			"allowed_next_states": transitionErr.Allowed,
+		}
 	}
+
+	return response
+}
+
+// logFailure logs a failed response with the request's logger, which carries
+// the request ID and signed in user, along with the matched route.
+func logFailure(r *http.Request, response *ServerResponse) {
+	route := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		route = rctx.RoutePattern()
+	}
+
+	attrs := []any{"route", route, "status", response.Status, "message", response.Message}
+	if response.cause != nil {
+		attrs = append(attrs, "error", response.cause)
+	}
+	log := logger.FromContext(r.Context())
+	if response.StatusCode >= http.StatusInternalServerError {
+		log.Error("request failed", attrs...)
+		return
+	}
+	log.Debug("request failed", attrs...)
 }
 
 func writeJSONResponse(w http.ResponseWriter, content []byte, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	if _, err := w.Write(content); err != nil {
-		// logger.Log.Error("unable to write json response")
-		log.Println("unable to write json response")
+		slog.Default().Error("unable to write json response", "error", err)
 	}
 }
 
-// writeErrorResponse writes an error response to the client
-func writeErrorResponse(w http.ResponseWriter, err error, status, errMessage string) {
-	r := respondWithError(err, errMessage, status, nil)
-	response, _ := json.Marshal(r)
-	writeJSONResponse(w, response, r.StatusCode)
+// writeErrorResponse writes an error response to the client and logs it. The
+// request ID is taken from the response header set by RequestTracing.
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, err error, status, errMessage string) {
+	var tc *tracing.Context
+	if requestID := w.Header().Get(values.HeaderRequestID); requestID != "" {
+		tc = &tracing.Context{RequestID: requestID}
+	}
+	resp := respondWithError(err, errMessage, status, tc)
+	logFailure(r, resp)
+	response, _ := json.Marshal(resp)
+	writeJSONResponse(w, response, resp.StatusCode)
 }
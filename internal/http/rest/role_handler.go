@@ -0,0 +1,82 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util"
+	"github.com/bwise1/your_care_api/util/tracing"
+	"github.com/bwise1/your_care_api/util/values"
+	"github.com/go-chi/chi/v5"
+)
+
+func (api *API) GetRoles(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	roles, status, message, err := api.GetRoles_H()
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       roles,
+	}
+}
+
+func (api *API) CreateAdminInvite(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	var req model.CreateInviteRequest
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse invite request", values.BadRequestBody, &tc)
+	}
+	req.IPAddress = api.clientIP(r)
+
+	actor := r.Context().Value("user").(model.User)
+
+	invite, status, message, err := api.CreateAdminInvite_H(actor, req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       invite,
+	}
+}
+
+func (api *API) AssignUserRole(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		return respondWithError(err, "Invalid user ID", values.BadRequestBody, &tc)
+	}
+
+	var req model.AssignRoleRequest
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse role assignment request", values.BadRequestBody, &tc)
+	}
+	req.UserID = userID
+	req.IPAddress = api.clientIP(r)
+
+	actor := r.Context().Value("user").(model.User)
+
+	user, status, message, err := api.AssignUserRole_H(actor, req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       user,
+	}
+}
@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util/values"
+)
+
+func (api *API) GetRoles_H() ([]model.Role, string, string, error) {
+	roles, err := api.GetRolesRepo(api.rootContext())
+	if err != nil {
+		return nil, values.Error, fmt.Sprintf("%s [GeRo]", values.SystemErr), err
+	}
+	return roles, values.Success, "Fetched roles successfully", nil
+}
+
+// AssignUserRole_H changes a user's role on behalf of actor. Admin level roles
+// can only be granted or taken away by a super admin, and nobody can change
+// their own role.
+func (api *API) AssignUserRole_H(actor model.User, req model.AssignRoleRequest) (model.User, string, string, error) {
+	ctx := api.rootContext()
+
+	if req.UserID == actor.ID {
+		return model.User{}, values.NotAllowed, "You cannot change your own role", errors.New("self role change")
+	}
+
+	role, hospitalID, status, message, err := api.resolveRoleGrant(ctx, actor, req.Role, req.HospitalID)
+	if err != nil {
+		return model.User{}, status, message, err
+	}
+
+	user, err := api.GetUserByID(ctx, req.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, values.NotFound, "User not found", err
+		}
+		return model.User{}, values.Error, fmt.Sprintf("%s [AsRo]", values.SystemErr), err
+	}
+	if actor.Role != model.RoleSuperAdmin && isAdminRole(user.Role) {
+		return model.User{}, values.NotAllowed, "Only a super admin can grant or revoke admin roles", errors.New("admin role change not allowed")
+	}
+
+	entry := model.AuditEntry{
+		ActorID:    &actor.ID,
+		Action:     model.AuditUserRoleAssigned,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+		Metadata: map[string]interface{}{
+			"from_role":   user.Role,
+			"to_role":     role.Name,
+			"hospital_id": hospitalID,
+		},
+		IPAddress: req.IPAddress,
+	}
+	if err := api.AssignUserRoleRepo(ctx, user.ID, role.ID, hospitalID, entry); err != nil {
+		return model.User{}, values.Error, fmt.Sprintf("%s [AsRo]", values.SystemErr), err
+	}
+
+	user.RoleID = role.ID
+	user.Role = role.Name
+	user.HospitalID = hospitalID
+	user.HospitalScoped = role.HospitalScoped
+	return user, values.Success, "Role assigned successfully", nil
+}
+
+// resolveRoleGrant checks that actor may hand out roleName and returns the
+// role with the hospital it should be scoped to. Only hospital scoped roles
+// keep a hospital, and they must have one.
+func (api *API) resolveRoleGrant(ctx context.Context, actor model.User, roleName string, hospitalID *int) (model.Role, *int, string, string, error) {
+	roleName = strings.TrimSpace(roleName)
+	if roleName == "" {
+		return model.Role{}, nil, values.BadRequestBody, "role is required", errors.New("missing role")
+	}
+
+	role, err := api.GetRoleByName(ctx, roleName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Role{}, nil, values.BadRequestBody, "Unknown role", err
+		}
+		return model.Role{}, nil, values.Error, fmt.Sprintf("%s [RoGr]", values.SystemErr), err
+	}
+
+	if actor.Role != model.RoleSuperAdmin && isAdminRole(role.Name) {
+		return model.Role{}, nil, values.NotAllowed, "Only a super admin can grant or revoke admin roles", errors.New("admin role change not allowed")
+	}
+
+	if !role.HospitalScoped {
+		return role, nil, "", "", nil
+	}
+	if hospitalID == nil {
+		return model.Role{}, nil, values.BadRequestBody, fmt.Sprintf("hospital_id is required for the %s role", role.Name), errors.New("missing hospital id")
+	}
+	exists, err := api.HospitalExists(ctx, *hospitalID)
+	if err != nil {
+		return model.Role{}, nil, values.Error, fmt.Sprintf("%s [RoGr]", values.SystemErr), err
+	}
+	if !exists {
+		return model.Role{}, nil, values.NotFound, "Hospital not found", errors.New("hospital not found")
+	}
+	return role, hospitalID, "", "", nil
+}
+
+func isAdminRole(name string) bool {
+	return name == model.RoleAdmin || name == model.RoleSuperAdmin
+}
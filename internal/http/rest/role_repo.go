@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// GetRolePermissions returns the permissions granted to a role.
+func (api *API) GetRolePermissions(ctx context.Context, roleID int) (model.PermissionSet, error) {
+	stmt := `SELECT p.name
+		FROM role_permissions rp
+		JOIN permissions p ON p.id = rp.permission_id
+		WHERE rp.role_id = ?`
+
+	var names []string
+	if err := api.Deps.DB.SelectContext(ctx, &names, stmt, roleID); err != nil {
+		return nil, err
+	}
+
+	permissions := make(model.PermissionSet, len(names))
+	for _, name := range names {
+		permissions[name] = true
+	}
+	return permissions, nil
+}
+
+func (api *API) GetRolesRepo(ctx context.Context) ([]model.Role, error) {
+	roles := []model.Role{}
+	err := api.Deps.DB.SelectContext(ctx, &roles, `SELECT id, name, description, hospital_scoped FROM roles ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+
+	var grants []struct {
+		RoleID int    `db:"role_id"`
+		Name   string `db:"name"`
+	}
+	stmt := `SELECT rp.role_id, p.name
+		FROM role_permissions rp
+		JOIN permissions p ON p.id = rp.permission_id
+		ORDER BY p.name`
+	if err := api.Deps.DB.SelectContext(ctx, &grants, stmt); err != nil {
+		return nil, err
+	}
+
+	byRole := make(map[int][]string, len(roles))
+	for _, grant := range grants {
+		byRole[grant.RoleID] = append(byRole[grant.RoleID], grant.Name)
+	}
+	for i := range roles {
+		roles[i].Permissions = byRole[roles[i].ID]
+		if roles[i].Permissions == nil {
+			roles[i].Permissions = []string{}
+		}
+	}
+	return roles, nil
+}
+
+func (api *API) GetRoleByName(ctx context.Context, name string) (model.Role, error) {
+	var role model.Role
+	err := api.Deps.DB.GetContext(ctx, &role, `SELECT id, name, description, hospital_scoped FROM roles WHERE name = ?`, name)
+	return role, err
+}
+
+// AssignUserRoleRepo sets a user's role and the hospital it is scoped to, and
+// records the change in the audit log.
+func (api *API) AssignUserRoleRepo(ctx context.Context, userID, roleID int, hospitalID *int, entry model.AuditEntry) error {
+	return api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, `UPDATE users SET role_id = ?, hospital_id = ? WHERE id = ?`, roleID, hospitalID, userID)
+		if err != nil {
+			return err
+		}
+		if err := requireAffected(result); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, entry)
+	})
+}
+
+// AppointmentHospitalID returns the hospital an appointment belongs to: the
+// hospital of the lab for lab tests, or the doctor's hospital otherwise.
+// Appointments not tied to a hospital return nil.
+func (api *API) AppointmentHospitalID(ctx context.Context, appointmentID int) (*int, error) {
+	stmt := `SELECT COALESCE(la.hospital_id, d.hospital_id)
+		FROM appointments a
+		LEFT JOIN lab_test_appointment_details la ON a.id = la.appointment_id
+		LEFT JOIN doctor_appointment_details da ON a.id = da.appointment_id
+		LEFT JOIN doctors d ON d.id = COALESCE(a.doctor_id, da.doctor_id)
+		WHERE a.id = ?`
+	return api.nullableHospitalID(ctx, stmt, appointmentID)
+}
+
+func (api *API) DoctorHospitalID(ctx context.Context, doctorID int) (*int, error) {
+	return api.nullableHospitalID(ctx, `SELECT hospital_id FROM doctors WHERE id = ?`, doctorID)
+}
+
+func (api *API) HospitalLabTestHospitalID(ctx context.Context, hospitalLabTestID int) (*int, error) {
+	return api.nullableHospitalID(ctx, `SELECT hospital_id FROM hospital_lab_tests WHERE id = ?`, hospitalLabTestID)
+}
+
+func (api *API) nullableHospitalID(ctx context.Context, stmt string, id int) (*int, error) {
+	var hospitalID sql.NullInt64
+	if err := api.Deps.DB.GetContext(ctx, &hospitalID, stmt, id); err != nil {
+		return nil, err
+	}
+	if !hospitalID.Valid {
+		return nil, nil
+	}
+	value := int(hospitalID.Int64)
+	return &value, nil
+}
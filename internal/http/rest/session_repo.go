@@ -0,0 +1,124 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+func (api *API) CreateSession(ctx context.Context, userID int, familyID, tokenHash, userAgent, ipAddress string, expiresAt time.Time) error {
+	stmt := `INSERT INTO user_sessions (
+		user_id,
+		family_id,
+		token_hash,
+		user_agent,
+		ip_address,
+		expires_at
+	) VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err := api.Deps.DB.ExecContext(ctx, stmt, userID, familyID, tokenHash, optionalString(userAgent), optionalString(ipAddress), expiresAt)
+	return err
+}
+
+// RotateSession swaps the refresh token of a session for a new one. If the
+// presented token is not the latest one issued to the family it has been
+// replayed, so the whole session is revoked and ErrRefreshTokenReused returned.
+func (api *API) RotateSession(ctx context.Context, familyID string, userID int, oldHash, newHash, userAgent, ipAddress string, expiresAt time.Time) error {
+	// Reuse is reported after the transaction commits so the revocation sticks
+	var result error
+	err := api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		var session struct {
+			ID        int          `db:"id"`
+			TokenHash string       `db:"token_hash"`
+			Expired   bool         `db:"expired"`
+			RevokedAt sql.NullTime `db:"revoked_at"`
+		}
+		stmt := `SELECT id, token_hash, expires_at < NOW() AS expired, revoked_at
+			FROM user_sessions
+			WHERE family_id = ? AND user_id = ?
+			FOR UPDATE`
+		if err := tx.GetContext(ctx, &session, stmt, familyID, userID); err != nil {
+			return err
+		}
+
+		switch {
+		case session.RevokedAt.Valid:
+			result = model.ErrSessionRevoked
+			return nil
+		case session.TokenHash != oldHash:
+			result = model.ErrRefreshTokenReused
+			_, err := tx.ExecContext(ctx, `UPDATE user_sessions SET revoked_at = NOW() WHERE id = ?`, session.ID)
+			return err
+		case session.Expired:
+			result = model.ErrSessionExpired
+			return nil
+		}
+
+		stmt = `UPDATE user_sessions SET
+			token_hash = ?,
+			user_agent = ?,
+			ip_address = ?,
+			last_used_at = NOW(),
+			expires_at = ?
+		WHERE id = ?`
+		_, err := tx.ExecContext(ctx, stmt, newHash, optionalString(userAgent), optionalString(ipAddress), expiresAt, session.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+// SessionActive reports whether the session exists and has not been revoked or expired.
+func (api *API) SessionActive(ctx context.Context, familyID string) (bool, error) {
+	var active bool
+	stmt := `SELECT EXISTS(
+		SELECT 1 FROM user_sessions
+		WHERE family_id = ? AND revoked_at IS NULL AND expires_at > NOW()
+	)`
+	err := api.Deps.DB.GetContext(ctx, &active, stmt, familyID)
+	return active, err
+}
+
+func (api *API) ListSessionsRepo(ctx context.Context, userID int) ([]model.Session, error) {
+	stmt := `SELECT family_id, user_agent, ip_address, created_at, last_used_at, expires_at
+		FROM user_sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC`
+
+	sessions := []model.Session{}
+	err := api.Deps.DB.SelectContext(ctx, &sessions, stmt, userID)
+	return sessions, err
+}
+
+func (api *API) RevokeSessionRepo(ctx context.Context, userID int, familyID string) error {
+	stmt := `UPDATE user_sessions SET revoked_at = NOW()
+		WHERE user_id = ? AND family_id = ? AND revoked_at IS NULL`
+	result, err := api.Deps.DB.ExecContext(ctx, stmt, userID, familyID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// RevokeUserSessions signs the user out of every device except keepFamilyID,
+// which may be empty to revoke them all.
+func (api *API) RevokeUserSessions(ctx context.Context, userID int, keepFamilyID string) error {
+	stmt := `UPDATE user_sessions SET revoked_at = NOW()
+		WHERE user_id = ? AND family_id <> ? AND revoked_at IS NULL`
+	_, err := api.Deps.DB.ExecContext(ctx, stmt, userID, keepFamilyID)
+	return err
+}
+
+// isSessionError reports whether err means the refresh token can no longer be used.
+func isSessionError(err error) bool {
+	return errors.Is(err, sql.ErrNoRows) ||
+		errors.Is(err, model.ErrSessionRevoked) ||
+		errors.Is(err, model.ErrSessionExpired) ||
+		errors.Is(err, model.ErrRefreshTokenReused)
+}
@@ -0,0 +1,121 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util"
+	"github.com/bwise1/your_care_api/util/tracing"
+	"github.com/bwise1/your_care_api/util/values"
+	"github.com/go-chi/chi/v5"
+)
+
+func (api *API) SlotRoutes() chi.Router {
+	mux := chi.NewRouter()
+	mux.Method(http.MethodGet, "/", Handler(api.GetAvailableSlots))
+	return mux
+}
+
+// GetAvailableSlots lists the free slots of a provider between two dates,
+// e.g. /slots?provider_type=doctor&provider_id=3&from=2025-01-06&to=2025-01-10
+func (api *API) GetAvailableSlots(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	queryParams := r.URL.Query()
+	providerID, err := strconv.Atoi(queryParams.Get("provider_id"))
+	if err != nil {
+		return respondWithError(err, "Invalid provider ID", values.BadRequestBody, &tc)
+	}
+
+	query := model.SlotQuery{
+		Provider: model.SlotProvider{
+			Type: model.ProviderType(queryParams.Get("provider_type")),
+			ID:   providerID,
+		},
+	}
+
+	query.From, err = time.ParseInLocation("2006-01-02", queryParams.Get("from"), time.Local)
+	if err != nil {
+		return respondWithError(err, "from must be a date in YYYY-MM-DD format", values.BadRequestBody, &tc)
+	}
+	query.To = query.From
+	if to := queryParams.Get("to"); to != "" {
+		query.To, err = time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			return respondWithError(err, "to must be a date in YYYY-MM-DD format", values.BadRequestBody, &tc)
+		}
+	}
+
+	slots, status, message, err := api.GetAvailableSlots_H(query)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       slots,
+	}
+}
+
+func (api *API) GetProviderSchedules(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	provider, err := slotProviderFromURL(r)
+	if err != nil {
+		return respondWithError(err, "Invalid provider ID", values.BadRequestBody, &tc)
+	}
+
+	schedules, status, message, err := api.GetProviderSchedules_H(provider)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       schedules,
+	}
+}
+
+func (api *API) ReplaceProviderSchedules(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	provider, err := slotProviderFromURL(r)
+	if err != nil {
+		return respondWithError(err, "Invalid provider ID", values.BadRequestBody, &tc)
+	}
+
+	var req []model.ProviderSchedule
+	if decodeErr := util.DecodeJSONBody(&tc, r.Body, &req); decodeErr != nil {
+		return respondWithError(decodeErr, "unable to parse schedule request", values.BadRequestBody, &tc)
+	}
+
+	schedules, status, message, err := api.ReplaceProviderSchedules_H(provider, req)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       schedules,
+	}
+}
+
+func slotProviderFromURL(r *http.Request) (model.SlotProvider, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "providerID"))
+	if err != nil {
+		return model.SlotProvider{}, errors.New("invalid provider id")
+	}
+	return model.SlotProvider{
+		Type: model.ProviderType(chi.URLParam(r, "providerType")),
+		ID:   id,
+	}, nil
+}
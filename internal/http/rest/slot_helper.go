@@ -0,0 +1,191 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util/values"
+)
+
+// maxSlotQueryDays bounds GET /slots so a single request cannot ask for months of slots.
+const maxSlotQueryDays = 31
+
+func (api *API) GetAvailableSlots_H(query model.SlotQuery) ([]model.Slot, string, string, error) {
+	if message, err := validateSlotProvider(query.Provider); err != nil {
+		return nil, values.BadRequestBody, message, err
+	}
+	if query.To.Before(query.From) {
+		return nil, values.BadRequestBody, "to must not be before from", errors.New("invalid slot range")
+	}
+	if query.To.Sub(query.From) >= maxSlotQueryDays*24*time.Hour {
+		return nil, values.BadRequestBody, fmt.Sprintf("date range cannot exceed %d days", maxSlotQueryDays), errors.New("slot range too large")
+	}
+
+	schedules, err := api.GetProviderSchedulesRepo(api.rootContext(), query.Provider)
+	if err != nil {
+		return nil, values.Error, fmt.Sprintf("%s [GeSl]", values.SystemErr), err
+	}
+
+	// The range is inclusive of the `to` day
+	end := query.To.AddDate(0, 0, 1)
+	booked, err := api.GetBookedSlotsRepo(api.rootContext(), query.Provider, query.From, end)
+	if err != nil {
+		return nil, values.Error, fmt.Sprintf("%s [GeSl]", values.SystemErr), err
+	}
+
+	slots := []model.Slot{}
+	now := time.Now()
+	for _, slot := range generateSlots(schedules, query.From, end) {
+		if !slot.Start.After(now) {
+			continue
+		}
+		slot.Available = slot.Capacity - booked[slot.Start.Format(slotTimeLayout)]
+		if slot.Available <= 0 {
+			continue
+		}
+		slots = append(slots, slot)
+	}
+
+	return slots, values.Success, "Fetched available slots successfully", nil
+}
+
+func (api *API) GetProviderSchedules_H(provider model.SlotProvider) ([]model.ProviderSchedule, string, string, error) {
+	if message, err := validateSlotProvider(provider); err != nil {
+		return nil, values.BadRequestBody, message, err
+	}
+
+	schedules, err := api.GetProviderSchedulesRepo(api.rootContext(), provider)
+	if err != nil {
+		return nil, values.Error, fmt.Sprintf("%s [GePs]", values.SystemErr), err
+	}
+	return schedules, values.Success, "Fetched provider schedule successfully", nil
+}
+
+func (api *API) ReplaceProviderSchedules_H(provider model.SlotProvider, schedules []model.ProviderSchedule) ([]model.ProviderSchedule, string, string, error) {
+	if message, err := validateSlotProvider(provider); err != nil {
+		return nil, values.BadRequestBody, message, err
+	}
+
+	seen := map[int]bool{}
+	for i := range schedules {
+		s := &schedules[i]
+		if s.Weekday < 0 || s.Weekday > 6 {
+			return nil, values.BadRequestBody, "weekday must be between 0 (Sunday) and 6 (Saturday)", errors.New("invalid weekday")
+		}
+		if seen[s.Weekday] {
+			return nil, values.BadRequestBody, "each weekday can only appear once", errors.New("duplicate weekday")
+		}
+		seen[s.Weekday] = true
+
+		if s.SlotMinutes == 0 {
+			s.SlotMinutes = model.DefaultSlotMinutes
+		}
+		if s.Capacity == 0 {
+			s.Capacity = model.DefaultSlotCapacity
+		}
+		if s.SlotMinutes < 0 || s.Capacity < 0 {
+			return nil, values.BadRequestBody, "slot_minutes and capacity must be positive", errors.New("invalid schedule")
+		}
+
+		from, err := parseClock(s.StartTime)
+		if err != nil {
+			return nil, values.BadRequestBody, "start_time must be in HH:MM format", err
+		}
+		to, err := parseClock(s.EndTime)
+		if err != nil {
+			return nil, values.BadRequestBody, "end_time must be in HH:MM format", err
+		}
+		if to.Sub(from) < time.Duration(s.SlotMinutes)*time.Minute {
+			return nil, values.BadRequestBody, "end_time must leave room for at least one slot after start_time", errors.New("invalid schedule window")
+		}
+
+		s.ProviderType = provider.Type
+		s.ProviderID = provider.ID
+	}
+
+	if err := api.ReplaceProviderSchedulesRepo(api.rootContext(), provider, schedules); err != nil {
+		return nil, values.Error, fmt.Sprintf("%s [RpPs]", values.SystemErr), err
+	}
+	return schedules, values.Success, "Provider schedule updated successfully", nil
+}
+
+func validateSlotProvider(provider model.SlotProvider) (string, error) {
+	if provider.Type != model.ProviderDoctor && provider.Type != model.ProviderHospitalLab && provider.Type != model.ProviderIVFClinic {
+		return "provider_type must be doctor, hospital_lab or ivf_clinic", errors.New("invalid provider type")
+	}
+	if provider.ID <= 0 {
+		return "provider_id is required", errors.New("missing provider id")
+	}
+	if provider.Type == model.ProviderIVFClinic && provider.ID != model.IVFClinicID {
+		return fmt.Sprintf("provider_id of the IVF clinic is %d", model.IVFClinicID), errors.New("unknown ivf clinic")
+	}
+	return "", nil
+}
+
+// generateSlots expands the weekly schedules into concrete slots for every day
+// in [from, to). Only whole slots that fit before the end of the working day are returned.
+func generateSlots(schedules []model.ProviderSchedule, from, to time.Time) []model.Slot {
+	byWeekday := make(map[time.Weekday]model.ProviderSchedule, len(schedules))
+	for _, s := range schedules {
+		byWeekday[time.Weekday(s.Weekday)] = s
+	}
+
+	var slots []model.Slot
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		schedule, ok := byWeekday[day.Weekday()]
+		if !ok {
+			continue
+		}
+		slots = append(slots, daySlots(schedule, day)...)
+	}
+	return slots
+}
+
+func daySlots(schedule model.ProviderSchedule, day time.Time) []model.Slot {
+	start, err := parseClock(schedule.StartTime)
+	if err != nil {
+		return nil
+	}
+	end, err := parseClock(schedule.EndTime)
+	if err != nil {
+		return nil
+	}
+
+	length := time.Duration(schedule.SlotMinutes) * time.Minute
+	if length <= 0 {
+		return nil
+	}
+
+	dayStart := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, day.Location())
+	dayEnd := time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, day.Location())
+
+	var slots []model.Slot
+	for t := dayStart; !t.Add(length).After(dayEnd); t = t.Add(length) {
+		slots = append(slots, model.Slot{
+			Start:     t,
+			End:       t.Add(length),
+			Capacity:  schedule.Capacity,
+			Available: schedule.Capacity,
+		})
+	}
+	return slots
+}
+
+// slotAt returns the slot that starts exactly at `at`, if the provider's
+// schedule has one.
+func slotAt(schedules []model.ProviderSchedule, at time.Time) (model.Slot, bool) {
+	for _, s := range schedules {
+		if time.Weekday(s.Weekday) != at.Weekday() {
+			continue
+		}
+		for _, slot := range daySlots(s, at) {
+			if slot.Start.Equal(at) {
+				return slot, true
+			}
+		}
+	}
+	return model.Slot{}, false
+}
@@ -0,0 +1,166 @@
+package rest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+)
+
+// 2024-10-14 is a Monday
+func slotTime(day, hour, minute int) time.Time {
+	return time.Date(2024, 10, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestDaySlots(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule model.ProviderSchedule
+		want     string
+	}{
+		{
+			name:     "whole slots",
+			schedule: model.ProviderSchedule{StartTime: "09:00", EndTime: "10:30", SlotMinutes: 30, Capacity: 2},
+			want:     "09:00-09:30 09:30-10:00 10:00-10:30",
+		},
+		{
+			name:     "partial slot dropped",
+			schedule: model.ProviderSchedule{StartTime: "09:00:00", EndTime: "10:15:00", SlotMinutes: 30, Capacity: 1},
+			want:     "09:00-09:30 09:30-10:00",
+		},
+		{
+			name:     "window shorter than a slot",
+			schedule: model.ProviderSchedule{StartTime: "09:00", EndTime: "09:20", SlotMinutes: 30, Capacity: 1},
+		},
+		{
+			name:     "zero slot length",
+			schedule: model.ProviderSchedule{StartTime: "09:00", EndTime: "17:00", Capacity: 1},
+		},
+		{
+			name:     "bad clock",
+			schedule: model.ProviderSchedule{StartTime: "nine", EndTime: "17:00", SlotMinutes: 30, Capacity: 1},
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, slot := range daySlots(tt.schedule, slotTime(14, 0, 0)) {
+			if slot.Capacity != tt.schedule.Capacity || slot.Available != tt.schedule.Capacity {
+				t.Errorf("%s: slot capacity %d/%d, want %d", tt.name, slot.Available, slot.Capacity, tt.schedule.Capacity)
+			}
+			got = append(got, slot.Start.Format("15:04")+"-"+slot.End.Format("15:04"))
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, strings.Join(got, " "), tt.want)
+		}
+	}
+}
+
+func TestGenerateSlots(t *testing.T) {
+	schedules := []model.ProviderSchedule{
+		{Weekday: int(time.Monday), StartTime: "09:00", EndTime: "10:00", SlotMinutes: 60, Capacity: 1},
+		{Weekday: int(time.Wednesday), StartTime: "14:00", EndTime: "15:00", SlotMinutes: 30, Capacity: 1},
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     string
+	}{
+		{"one week", slotTime(14, 0, 0), slotTime(21, 0, 0), "14 09:00,16 14:00,16 14:30"},
+		{"from mid-day still covers that day", slotTime(14, 12, 0), slotTime(15, 0, 0), "14 09:00"},
+		{"end is exclusive", slotTime(15, 0, 0), slotTime(16, 0, 0), ""},
+		{"two mondays", slotTime(14, 0, 0), slotTime(22, 0, 0), "14 09:00,16 14:00,16 14:30,21 09:00"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, slot := range generateSlots(schedules, tt.from, tt.to) {
+			got = append(got, slot.Start.Format("02 15:04"))
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, strings.Join(got, ","), tt.want)
+		}
+	}
+}
+
+func TestSlotAt(t *testing.T) {
+	schedules := []model.ProviderSchedule{
+		{Weekday: int(time.Monday), StartTime: "09:00", EndTime: "11:00", SlotMinutes: 30, Capacity: 3},
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"slot start", slotTime(14, 9, 30), true},
+		{"last slot", slotTime(14, 10, 30), true},
+		{"inside a slot", slotTime(14, 9, 45), false},
+		{"after hours", slotTime(14, 11, 0), false},
+		{"other weekday", slotTime(15, 9, 30), false},
+	}
+	for _, tt := range tests {
+		slot, ok := slotAt(schedules, tt.at)
+		if ok != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, ok, tt.want)
+			continue
+		}
+		if ok && (!slot.Start.Equal(tt.at) || slot.Capacity != 3) {
+			t.Errorf("%s: got slot %+v", tt.name, slot)
+		}
+	}
+}
+
+func TestValidateSlotProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider model.SlotProvider
+		wantErr  bool
+	}{
+		{"doctor", model.SlotProvider{Type: model.ProviderDoctor, ID: 4}, false},
+		{"hospital lab", model.SlotProvider{Type: model.ProviderHospitalLab, ID: 2}, false},
+		{"ivf clinic", model.SlotProvider{Type: model.ProviderIVFClinic, ID: model.IVFClinicID}, false},
+		{"unknown ivf clinic", model.SlotProvider{Type: model.ProviderIVFClinic, ID: model.IVFClinicID + 1}, true},
+		{"unknown type", model.SlotProvider{Type: "nurse", ID: 1}, true},
+		{"missing id", model.SlotProvider{Type: model.ProviderDoctor}, true},
+	}
+	for _, tt := range tests {
+		message, err := validateSlotProvider(tt.provider)
+		if (err != nil) != tt.wantErr || (message != "") != tt.wantErr {
+			t.Errorf("%s: got %q, %v", tt.name, message, err)
+		}
+	}
+}
+
+func TestParseSlotTime(t *testing.T) {
+	want := slotTime(14, 9, 30)
+	for _, value := range []string{"2024-10-14 09:30", "2024-10-14 09:30:00"} {
+		got, err := parseSlotTime(value)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseSlotTime(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := parseSlotTime("2024-10-14T09:30:00Z"); err == nil {
+		t.Error("expected an error for an RFC 3339 time")
+	}
+}
+
+func TestReleasesSlot(t *testing.T) {
+	tests := []struct {
+		status model.AppointmentStatus
+		want   bool
+	}{
+		{model.StatusPending, false},
+		{model.StatusConfirmed, false},
+		{model.StatusRescheduleOffered, false},
+		{model.StatusCompleted, true},
+		{model.StatusCanceled, true},
+		{model.StatusRejected, true},
+		{model.StatusNoShow, true},
+	}
+	for _, tt := range tests {
+		if got := releasesSlot(tt.status); got != tt.want {
+			t.Errorf("releasesSlot(%s) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
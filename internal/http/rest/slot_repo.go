@@ -0,0 +1,238 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// slotTimeLayout is used for every slot_start written to or read from the
+// database so that wall-clock times never pass through a timezone conversion.
+const slotTimeLayout = "2006-01-02 15:04:05"
+
+// parseSlotTime parses a wall-clock appointment time with or without seconds.
+func parseSlotTime(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(slotTimeLayout, value, time.Local)
+}
+
+func (api *API) GetProviderSchedulesRepo(ctx context.Context, provider model.SlotProvider) ([]model.ProviderSchedule, error) {
+	return getProviderSchedules(ctx, api.Deps.DB, provider)
+}
+
+func getProviderSchedules(ctx context.Context, q sqlx.QueryerContext, provider model.SlotProvider) ([]model.ProviderSchedule, error) {
+	stmt := `SELECT
+		id,
+		provider_type,
+		provider_id,
+		weekday,
+		TIME_FORMAT(start_time, '%H:%i') AS start_time,
+		TIME_FORMAT(end_time, '%H:%i') AS end_time,
+		slot_minutes,
+		capacity
+	FROM provider_schedules
+	WHERE provider_type = ? AND provider_id = ?
+	ORDER BY weekday`
+
+	schedules := []model.ProviderSchedule{}
+	err := sqlx.SelectContext(ctx, q, &schedules, stmt, provider.Type, provider.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Doctors without explicit working hours fall back to their availability window
+	if len(schedules) == 0 && provider.Type == model.ProviderDoctor {
+		var window struct {
+			From sql.NullString `db:"available_from"`
+			To   sql.NullString `db:"available_to"`
+		}
+		stmt := `SELECT TIME_FORMAT(available_from, '%H:%i') AS available_from, TIME_FORMAT(available_to, '%H:%i') AS available_to FROM doctors WHERE id = ?`
+		err := sqlx.GetContext(ctx, q, &window, stmt, provider.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if window.From.Valid && window.To.Valid {
+			for weekday := 0; weekday < 7; weekday++ {
+				schedules = append(schedules, model.ProviderSchedule{
+					ProviderType: provider.Type,
+					ProviderID:   provider.ID,
+					Weekday:      weekday,
+					StartTime:    window.From.String,
+					EndTime:      window.To.String,
+					SlotMinutes:  model.DefaultSlotMinutes,
+					Capacity:     model.DefaultSlotCapacity,
+				})
+			}
+		}
+	}
+
+	return schedules, nil
+}
+
+// ReplaceProviderSchedulesRepo swaps a provider's working hours for the given set.
+func (api *API) ReplaceProviderSchedulesRepo(ctx context.Context, provider model.SlotProvider, schedules []model.ProviderSchedule) error {
+	return api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM provider_schedules WHERE provider_type = ? AND provider_id = ?`, provider.Type, provider.ID)
+		if err != nil {
+			return err
+		}
+
+		stmt := `INSERT INTO provider_schedules (
+			provider_type,
+			provider_id,
+			weekday,
+			start_time,
+			end_time,
+			slot_minutes,
+			capacity
+		) VALUES (?, ?, ?, ?, ?, ?, ?)`
+		for _, s := range schedules {
+			_, err := tx.ExecContext(ctx, stmt, provider.Type, provider.ID, s.Weekday, s.StartTime, s.EndTime, s.SlotMinutes, s.Capacity)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetBookedSlotsRepo returns how many places are taken per slot start in [from, to).
+func (api *API) GetBookedSlotsRepo(ctx context.Context, provider model.SlotProvider, from, to time.Time) (map[string]int, error) {
+	stmt := `SELECT DATE_FORMAT(slot_start, '%Y-%m-%d %H:%i:%s') AS slot_start, booked
+		FROM slot_reservations
+		WHERE provider_type = ? AND provider_id = ? AND slot_start >= ? AND slot_start < ?`
+
+	var rows []struct {
+		SlotStart string `db:"slot_start"`
+		Booked    int    `db:"booked"`
+	}
+	err := api.Deps.DB.SelectContext(ctx, &rows, stmt, provider.Type, provider.ID, from.Format(slotTimeLayout), to.Format(slotTimeLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	booked := make(map[string]int, len(rows))
+	for _, row := range rows {
+		booked[row.SlotStart] = row.Booked
+	}
+	return booked, nil
+}
+
+// appointmentSlotProvider works out whose capacity an appointment consumes.
+// IVF appointments all share the IVF clinic. It returns nil for appointments
+// that are not tied to a provider, such as lab tests collected from home.
+func appointmentSlotProvider(ctx context.Context, tx *sqlx.Tx, appointmentID int) (*model.SlotProvider, error) {
+	stmt := `SELECT
+		a.appointment_type,
+		COALESCE(a.doctor_id, da.doctor_id) AS doctor_id,
+		la.hospital_id
+	FROM appointments a
+	LEFT JOIN doctor_appointment_details da ON a.id = da.appointment_id
+	LEFT JOIN lab_test_appointment_details la ON a.id = la.appointment_id
+	WHERE a.id = ?`
+
+	var row struct {
+		AppointmentType string        `db:"appointment_type"`
+		DoctorID        sql.NullInt64 `db:"doctor_id"`
+		HospitalID      sql.NullInt64 `db:"hospital_id"`
+	}
+	if err := tx.GetContext(ctx, &row, stmt, appointmentID); err != nil {
+		return nil, err
+	}
+
+	switch model.AppointmentType(row.AppointmentType) {
+	case model.TypeDoctor:
+		if row.DoctorID.Valid {
+			return &model.SlotProvider{Type: model.ProviderDoctor, ID: int(row.DoctorID.Int64)}, nil
+		}
+	case model.TypeLab:
+		if row.HospitalID.Valid {
+			return &model.SlotProvider{Type: model.ProviderHospitalLab, ID: int(row.HospitalID.Int64)}, nil
+		}
+	case model.TypeIVF:
+		return &model.SlotProvider{Type: model.ProviderIVFClinic, ID: model.IVFClinicID}, nil
+	}
+	return nil, nil
+}
+
+// reserveAppointmentSlot takes one place in the slot starting at `at` for the
+// appointment. Providers without a schedule are not capacity managed and the
+// call is a no-op. The conditional UPDATE makes the reservation atomic, so two
+// concurrent bookings can never push a slot over its capacity.
+func reserveAppointmentSlot(ctx context.Context, tx *sqlx.Tx, appointmentID int, at time.Time) error {
+	provider, err := appointmentSlotProvider(ctx, tx, appointmentID)
+	if err != nil || provider == nil {
+		return err
+	}
+
+	schedules, err := getProviderSchedules(ctx, tx, *provider)
+	if err != nil {
+		return err
+	}
+	if len(schedules) == 0 {
+		return nil
+	}
+
+	slot, ok := slotAt(schedules, at)
+	if !ok {
+		return model.ErrSlotUnavailable
+	}
+
+	upsert := `INSERT INTO slot_reservations (provider_type, provider_id, slot_start, capacity, booked)
+		VALUES (?, ?, ?, ?, 0)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), capacity = VALUES(capacity)`
+	result, err := tx.ExecContext(ctx, upsert, provider.Type, provider.ID, slot.Start.Format(slotTimeLayout), slot.Capacity)
+	if err != nil {
+		return err
+	}
+	reservationID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	result, err = tx.ExecContext(ctx, `UPDATE slot_reservations SET booked = booked + 1 WHERE id = ? AND booked < capacity`, reservationID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return model.ErrSlotFull
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO appointment_slots (appointment_id, reservation_id) VALUES (?, ?)`, appointmentID, reservationID)
+	return err
+}
+
+// releasesSlot reports whether moving an appointment to status frees the slot
+// it holds. Every final status does, so no-shows stop counting against the
+// slot as well as cancellations and rejections.
+func releasesSlot(status model.AppointmentStatus) bool {
+	return status.IsTerminal()
+}
+
+// releaseAppointmentSlot gives back the place held by an appointment, if any.
+func releaseAppointmentSlot(ctx context.Context, tx *sqlx.Tx, appointmentID int) error {
+	var reservationID int
+	err := tx.GetContext(ctx, &reservationID, `SELECT reservation_id FROM appointment_slots WHERE appointment_id = ? FOR UPDATE`, appointmentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE slot_reservations SET booked = booked - 1 WHERE id = ? AND booked > 0`, reservationID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM appointment_slots WHERE appointment_id = ?`, appointmentID)
+	return err
+}
@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util"
+	"github.com/bwise1/your_care_api/util/apperr"
+	"github.com/bwise1/your_care_api/util/tracing"
+	"github.com/bwise1/your_care_api/util/validate"
+	"github.com/bwise1/your_care_api/util/values"
+)
+
+const (
+	// defaultStatsDays is the range covered when no dates are given, ending today
+	defaultStatsDays = 30
+	maxStatsDays     = 366
+)
+
+func (api *API) GetAdminStatsHandler(_ http.ResponseWriter, r *http.Request) *ServerResponse {
+	tc := r.Context().Value(values.ContextTracingKey).(tracing.Context)
+
+	queryParams := r.URL.Query()
+	filter, err := parseStatsFilter(queryParams.Get("date_from"), queryParams.Get("date_to"))
+	if err != nil {
+		return respondWithError(err, "Invalid date range", values.BadRequestBody, &tc)
+	}
+
+	// Scoped staff only see their own hospital's figures
+	filter.HospitalID = scopedHospitalID(r)
+	if hospitalID := queryParams.Get("hospital_id"); hospitalID != "" && filter.HospitalID == nil {
+		hid, err := strconv.Atoi(hospitalID)
+		if err != nil {
+			return respondWithError(err, "hospital_id must be a number", values.BadRequestBody, &tc)
+		}
+		filter.HospitalID = &hid
+	}
+
+	stats, status, message, err := api.GetAdminStats(filter)
+	if err != nil {
+		return respondWithError(err, message, status, &tc)
+	}
+
+	return &ServerResponse{
+		Message:    message,
+		Status:     status,
+		StatusCode: util.StatusCode(status),
+		Data:       stats,
+	}
+}
+
+// parseStatsFilter reads the booking date range, defaulting to the last
+// defaultStatsDays days in the hospitals' timezone.
+func parseStatsFilter(dateFrom, dateTo string) (model.StatsFilter, error) {
+	today := time.Now().In(time.Local)
+	to := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+	from := to.AddDate(0, 0, 1-defaultStatsDays)
+
+	var fields []apperr.FieldError
+	if dateTo != "" {
+		parsed, err := time.ParseInLocation(validate.DateLayout, dateTo, time.Local)
+		if err != nil {
+			fields = append(fields, apperr.FieldError{Field: "date_to", Message: "must be a date in YYYY-MM-DD format"})
+		} else {
+			to = parsed
+			if dateFrom == "" {
+				from = to.AddDate(0, 0, 1-defaultStatsDays)
+			}
+		}
+	}
+	if dateFrom != "" {
+		parsed, err := time.ParseInLocation(validate.DateLayout, dateFrom, time.Local)
+		if err != nil {
+			fields = append(fields, apperr.FieldError{Field: "date_from", Message: "must be a date in YYYY-MM-DD format"})
+		} else {
+			from = parsed
+		}
+	}
+	if len(fields) == 0 {
+		if from.After(to) {
+			fields = append(fields, apperr.FieldError{Field: "date_from", Message: "must not be after date_to"})
+		} else if to.Sub(from) >= maxStatsDays*24*time.Hour {
+			fields = append(fields, apperr.FieldError{Field: "date_from", Message: fmt.Sprintf("range must not cover more than %d days", maxStatsDays)})
+		}
+	}
+	if len(fields) > 0 {
+		return model.StatsFilter{}, apperr.Validation(fields...)
+	}
+
+	return model.StatsFilter{
+		DateFrom: from.Format(validate.DateLayout),
+		DateTo:   to.Format(validate.DateLayout),
+	}, nil
+}
@@ -0,0 +1,245 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/bwise1/your_care_api/util/validate"
+	"github.com/bwise1/your_care_api/util/values"
+)
+
+const (
+	defaultStatsCacheTTL = time.Minute
+	// topLabTestsLimit is how many lab tests each top list holds
+	topLabTestsLimit = 10
+)
+
+// GetAdminStats returns the dashboard statistics for filter. Results are
+// cached for StatsCacheTTL, so repeated dashboard loads do not rerun the
+// aggregate queries.
+func (api *API) GetAdminStats(filter model.StatsFilter) (model.AdminStats, string, string, error) {
+	ttl, err := time.ParseDuration(api.Config.StatsCacheTTL)
+	if err != nil || ttl < 0 {
+		ttl = defaultStatsCacheTTL
+	}
+
+	stats, err := api.statsCache.load(statsCacheKey(filter), ttl, func() (model.AdminStats, error) {
+		ctx, cancel := context.WithTimeout(api.rootContext(), 10*time.Second)
+		defer cancel()
+		return api.buildAdminStats(ctx, filter)
+	})
+	if err != nil {
+		return model.AdminStats{}, values.Error, fmt.Sprintf("%s [GtAdSt]", values.SystemErr), err
+	}
+	return stats, values.Success, "Statistics fetched successfully", nil
+}
+
+func (api *API) buildAdminStats(ctx context.Context, filter model.StatsFilter) (model.AdminStats, error) {
+	stats := model.AdminStats{
+		DateFrom:    filter.DateFrom,
+		DateTo:      filter.DateTo,
+		ByStatus:    map[string]int{},
+		ByType:      map[string]int{},
+		GeneratedAt: time.Now(),
+	}
+
+	counts, err := api.StatusTypeCountsRepo(ctx, filter)
+	if err != nil {
+		return stats, err
+	}
+	for _, c := range counts {
+		stats.Total += c.Count
+		stats.ByStatus[c.Status] += c.Count
+		stats.ByType[c.AppointmentType] += c.Count
+	}
+
+	days, err := api.DailyBookingsRepo(ctx, filter)
+	if err != nil {
+		return stats, err
+	}
+	stats.DailyBookings = fillDailyCounts(filter.DateFrom, filter.DateTo, days)
+
+	leadTimes, err := api.ConfirmationLeadTimesRepo(ctx, filter)
+	if err != nil {
+		return stats, err
+	}
+	stats.ConfirmationLeadTime = summariseLeadTimes(leadTimes)
+
+	hospitals, err := api.HospitalRatesRepo(ctx, filter)
+	if err != nil {
+		return stats, err
+	}
+	for i := range hospitals {
+		if hospitals[i].Total > 0 {
+			hospitals[i].NoShowRate = roundTo(float64(hospitals[i].NoShows)/float64(hospitals[i].Total), 4)
+			hospitals[i].CancellationRate = roundTo(float64(hospitals[i].Cancellations)/float64(hospitals[i].Total), 4)
+		}
+	}
+	if hospitals == nil {
+		hospitals = []model.HospitalRates{}
+	}
+	stats.Hospitals = hospitals
+
+	tests, err := api.LabTestVolumesRepo(ctx, filter)
+	if err != nil {
+		return stats, err
+	}
+	stats.TopLabTestsByVolume = topLabTests(tests, func(a, b model.LabTestVolume) bool {
+		if a.Appointments != b.Appointments {
+			return a.Appointments > b.Appointments
+		}
+		return a.Revenue > b.Revenue
+	})
+	stats.TopLabTestsByRevenue = topLabTests(tests, func(a, b model.LabTestVolume) bool {
+		if a.Revenue != b.Revenue {
+			return a.Revenue > b.Revenue
+		}
+		return a.Appointments > b.Appointments
+	})
+
+	return stats, nil
+}
+
+// fillDailyCounts returns one entry for every day from dateFrom to dateTo,
+// with zero for days without bookings, so charts need no gap filling.
+func fillDailyCounts(dateFrom, dateTo string, days []model.DailyCount) []model.DailyCount {
+	byDay := make(map[string]int, len(days))
+	for _, d := range days {
+		byDay[d.Date] = d.Count
+	}
+
+	from, err := time.ParseInLocation(validate.DateLayout, dateFrom, time.Local)
+	if err != nil {
+		return days
+	}
+	to, err := time.ParseInLocation(validate.DateLayout, dateTo, time.Local)
+	if err != nil {
+		return days
+	}
+
+	filled := []model.DailyCount{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(validate.DateLayout)
+		filled = append(filled, model.DailyCount{Date: date, Count: byDay[date]})
+	}
+	return filled
+}
+
+func summariseLeadTimes(seconds []int64) model.LeadTime {
+	if len(seconds) == 0 {
+		return model.LeadTime{}
+	}
+
+	sorted := append([]int64(nil), seconds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total int64
+	for _, s := range sorted {
+		total += s
+	}
+	minutes := func(s float64) float64 { return roundTo(s/60, 1) }
+
+	return model.LeadTime{
+		Confirmed:      len(sorted),
+		AverageMinutes: minutes(float64(total) / float64(len(sorted))),
+		MedianMinutes:  minutes(float64(percentile(sorted, 0.5))),
+		P90Minutes:     minutes(float64(percentile(sorted, 0.9))),
+	}
+}
+
+// percentile uses the nearest-rank method on sorted values.
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func topLabTests(tests []model.LabTestVolume, less func(a, b model.LabTestVolume) bool) []model.LabTestVolume {
+	sorted := append([]model.LabTestVolume{}, tests...)
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	if len(sorted) > topLabTestsLimit {
+		sorted = sorted[:topLabTestsLimit]
+	}
+	return sorted
+}
+
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
+
+func statsCacheKey(filter model.StatsFilter) string {
+	hospital := "all"
+	if filter.HospitalID != nil {
+		hospital = strconv.Itoa(*filter.HospitalID)
+	}
+	return filter.DateFrom + "|" + filter.DateTo + "|" + hospital
+}
+
+// statsCache keeps recent statistics in memory. Requests for a key that is
+// already being loaded wait for that load instead of starting their own, so
+// a busy dashboard reaches the database once per key and TTL.
+type statsCache struct {
+	mu      sync.Mutex
+	entries map[string]*statsCacheEntry
+}
+
+type statsCacheEntry struct {
+	ready   chan struct{}
+	loaded  bool
+	expires time.Time
+	stats   model.AdminStats
+	err     error
+}
+
+// load returns the cached statistics for key, calling fetch when they are
+// missing or expired. Failed loads are not cached.
+func (c *statsCache) load(key string, ttl time.Duration, fetch func() (model.AdminStats, error)) (stats model.AdminStats, err error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = map[string]*statsCacheEntry{}
+	}
+	if entry, ok := c.entries[key]; ok && (!entry.loaded || now.Before(entry.expires)) {
+		c.mu.Unlock()
+		<-entry.ready
+		return entry.stats, entry.err
+	}
+
+	// Drop expired entries so ranges nobody asks for again do not pile up
+	for k, entry := range c.entries {
+		if entry.loaded && !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	entry := &statsCacheEntry{ready: make(chan struct{})}
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	// Waiters are released even if fetch panics, with this error
+	err = errors.New("loading statistics did not complete")
+	defer func() {
+		c.mu.Lock()
+		entry.stats, entry.err = stats, err
+		entry.loaded = true
+		entry.expires = time.Now().Add(ttl)
+		if err != nil {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+		close(entry.ready)
+	}()
+
+	stats, err = fetch()
+	return stats, err
+}
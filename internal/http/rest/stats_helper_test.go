@@ -0,0 +1,223 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+)
+
+func TestFillDailyCounts(t *testing.T) {
+	days := []model.DailyCount{{Date: "2024-10-16", Count: 3}, {Date: "2024-10-18", Count: 1}}
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{"gaps filled", "2024-10-15", "2024-10-18", "2024-10-15=0 2024-10-16=3 2024-10-17=0 2024-10-18=1"},
+		{"single day", "2024-10-16", "2024-10-16", "2024-10-16=3"},
+		{"across a month", "2024-10-31", "2024-11-01", "2024-10-31=0 2024-11-01=0"},
+		{"empty range", "2024-10-18", "2024-10-17", ""},
+		{"bad date returns rows as they are", "yesterday", "2024-10-18", "2024-10-16=3 2024-10-18=1"},
+	}
+	for _, tt := range tests {
+		var got string
+		for i, d := range fillDailyCounts(tt.from, tt.to, days) {
+			if i > 0 {
+				got += " "
+			}
+			got += fmt.Sprintf("%s=%d", d.Date, d.Count)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	tests := []struct {
+		p    float64
+		want int64
+	}{
+		{0, 10},
+		{0.1, 10},
+		{0.5, 50},
+		{0.9, 90},
+		{0.95, 100},
+		{1, 100},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestSummariseLeadTimes(t *testing.T) {
+	tests := []struct {
+		name    string
+		seconds []int64
+		want    model.LeadTime
+	}{
+		{"none", nil, model.LeadTime{}},
+		{"one", []int64{90}, model.LeadTime{Confirmed: 1, AverageMinutes: 1.5, MedianMinutes: 1.5, P90Minutes: 1.5}},
+		{
+			name:    "unsorted input",
+			seconds: []int64{600, 60, 3600, 120},
+			want:    model.LeadTime{Confirmed: 4, AverageMinutes: 18.3, MedianMinutes: 2, P90Minutes: 60},
+		},
+	}
+	for _, tt := range tests {
+		if got := summariseLeadTimes(tt.seconds); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	seconds := []int64{30, 10, 20}
+	summariseLeadTimes(seconds)
+	if seconds[0] != 30 {
+		t.Error("summariseLeadTimes sorted its argument in place")
+	}
+}
+
+func TestTopLabTests(t *testing.T) {
+	byVolume := func(a, b model.LabTestVolume) bool { return a.Appointments > b.Appointments }
+
+	var tests []model.LabTestVolume
+	for i := 1; i <= topLabTestsLimit+2; i++ {
+		tests = append(tests, model.LabTestVolume{LabTestID: i, Appointments: i})
+	}
+	top := topLabTests(tests, byVolume)
+	if len(top) != topLabTestsLimit || top[0].LabTestID != topLabTestsLimit+2 || top[len(top)-1].LabTestID != 3 {
+		t.Fatalf("got %+v, want the %d busiest tests, busiest first", top, topLabTestsLimit)
+	}
+	if tests[0].LabTestID != 1 {
+		t.Fatal("topLabTests reordered its argument")
+	}
+
+	ties := []model.LabTestVolume{{LabTestID: 1, Appointments: 5}, {LabTestID: 2, Appointments: 5}}
+	if top := topLabTests(ties, byVolume); top[0].LabTestID != 1 {
+		t.Fatalf("ties were reordered: %+v", top)
+	}
+}
+
+func TestStatsCacheKey(t *testing.T) {
+	hospital := 7
+	tests := []struct {
+		filter model.StatsFilter
+		want   string
+	}{
+		{model.StatsFilter{DateFrom: "2024-10-01", DateTo: "2024-10-31"}, "2024-10-01|2024-10-31|all"},
+		{model.StatsFilter{DateFrom: "2024-10-01", DateTo: "2024-10-31", HospitalID: &hospital}, "2024-10-01|2024-10-31|7"},
+	}
+	for _, tt := range tests {
+		if got := statsCacheKey(tt.filter); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestStatsCacheLoad(t *testing.T) {
+	var cache statsCache
+	var calls atomic.Int32
+	fetch := func() (model.AdminStats, error) {
+		calls.Add(1)
+		return model.AdminStats{Total: 5}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		stats, err := cache.load("key", time.Minute, fetch)
+		if err != nil || stats.Total != 5 {
+			t.Fatalf("load %d: got %+v, %v", i, stats, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("fetched %d times within the TTL, want 1", calls.Load())
+	}
+
+	if _, err := cache.load("expired", 0, fetch); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.load("expired", 0, fetch); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expired entry was not fetched again: %d calls", calls.Load())
+	}
+}
+
+func TestStatsCacheDoesNotKeepFailures(t *testing.T) {
+	var cache statsCache
+	failing := func() (model.AdminStats, error) { return model.AdminStats{}, errors.New("database down") }
+	if _, err := cache.load("key", time.Minute, failing); err == nil {
+		t.Fatal("expected the fetch error")
+	}
+
+	stats, err := cache.load("key", time.Minute, func() (model.AdminStats, error) {
+		return model.AdminStats{Total: 1}, nil
+	})
+	if err != nil || stats.Total != 1 {
+		t.Fatalf("a failed load was cached: %+v, %v", stats, err)
+	}
+}
+
+func TestStatsCacheRecoversFromPanic(t *testing.T) {
+	var cache statsCache
+	func() {
+		defer func() { recover() }()
+		cache.load("key", time.Minute, func() (model.AdminStats, error) { panic("boom") })
+	}()
+
+	stats, err := cache.load("key", time.Minute, func() (model.AdminStats, error) {
+		return model.AdminStats{Total: 2}, nil
+	})
+	if err != nil || stats.Total != 2 {
+		t.Fatalf("got %+v, %v after a panicking load", stats, err)
+	}
+}
+
+func TestStatsCacheCoalescesConcurrentLoads(t *testing.T) {
+	var cache statsCache
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fetch := func() (model.AdminStats, error) {
+		calls.Add(1)
+		<-release
+		return model.AdminStats{Total: 9}, nil
+	}
+
+	const callers = 10
+	var started, done sync.WaitGroup
+	started.Add(callers)
+	done.Add(callers)
+	results := make(chan int, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+			stats, _ := cache.load("key", time.Minute, fetch)
+			results <- stats.Total
+		}()
+	}
+	started.Wait()
+	// Give the callers time to reach the cache before the load completes
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	done.Wait()
+	close(results)
+
+	for total := range results {
+		if total != 9 {
+			t.Fatalf("a caller got total %d, want 9", total)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("fetched %d times for concurrent callers, want 1", calls.Load())
+	}
+}
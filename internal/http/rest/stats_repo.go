@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwise1/your_care_api/internal/model"
+)
+
+// statsFrom joins the details needed to place an appointment in a hospital
+// or lab test. Every statistics query starts from it.
+const statsFrom = `
+		FROM appointments a
+		LEFT JOIN doctor_appointment_details da ON a.id = da.appointment_id AND a.appointment_type = 'doctor'
+		LEFT JOIN lab_test_appointment_details la ON a.id = la.appointment_id AND a.appointment_type = 'lab_test'`
+
+// statsWhere selects the appointments booked in the filter's date range, and
+// at its hospital when one is set.
+func statsWhere(filter model.StatsFilter) (string, []interface{}) {
+	where := `
+		WHERE a.created_at >= ? AND a.created_at < DATE_ADD(?, INTERVAL 1 DAY)`
+	args := []interface{}{filter.DateFrom, filter.DateTo}
+
+	if filter.HospitalID != nil {
+		where += " AND " + appointmentHospitalExpr + " = ?"
+		args = append(args, *filter.HospitalID)
+	}
+	return where, args
+}
+
+type statusTypeCount struct {
+	Status          string `db:"status"`
+	AppointmentType string `db:"appointment_type"`
+	Count           int    `db:"count"`
+}
+
+func (api *API) StatusTypeCountsRepo(ctx context.Context, filter model.StatsFilter) ([]statusTypeCount, error) {
+	where, args := statsWhere(filter)
+	query := `SELECT a.status, a.appointment_type, COUNT(*) AS count` + statsFrom + where + `
+		GROUP BY a.status, a.appointment_type`
+
+	var counts []statusTypeCount
+	if err := api.Deps.DB.SelectContext(ctx, &counts, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to count appointments: %w", err)
+	}
+	return counts, nil
+}
+
+// DailyBookingsRepo counts bookings per day. Days without bookings are left
+// out.
+func (api *API) DailyBookingsRepo(ctx context.Context, filter model.StatsFilter) ([]model.DailyCount, error) {
+	where, args := statsWhere(filter)
+	query := `SELECT DATE_FORMAT(a.created_at, '%Y-%m-%d') AS day, COUNT(*) AS count` + statsFrom + where + `
+		GROUP BY day
+		ORDER BY day`
+
+	var days []model.DailyCount
+	if err := api.Deps.DB.SelectContext(ctx, &days, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to count daily bookings: %w", err)
+	}
+	return days, nil
+}
+
+// ConfirmationLeadTimesRepo returns, in seconds, how long each confirmed
+// appointment waited between booking and its first confirmation.
+func (api *API) ConfirmationLeadTimesRepo(ctx context.Context, filter model.StatsFilter) ([]int64, error) {
+	where, args := statsWhere(filter)
+	query := `SELECT TIMESTAMPDIFF(SECOND, a.created_at, MIN(h.changed_at))` + statsFrom + `
+		JOIN appointment_status_history h ON h.appointment_id = a.id AND h.status = 'confirmed'` + where + `
+		GROUP BY a.id, a.created_at`
+
+	var seconds []int64
+	if err := api.Deps.DB.SelectContext(ctx, &seconds, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to fetch confirmation lead times: %w", err)
+	}
+	return seconds, nil
+}
+
+// HospitalRatesRepo counts the appointments, no-shows and cancellations of
+// each hospital. Rates are left for the caller to work out.
+func (api *API) HospitalRatesRepo(ctx context.Context, filter model.StatsFilter) ([]model.HospitalRates, error) {
+	where, args := statsWhere(filter)
+	query := `SELECT
+			hs.id AS hospital_id,
+			hs.name,
+			COUNT(*) AS total,
+			COALESCE(SUM(a.status = 'no_show'), 0) AS no_shows,
+			COALESCE(SUM(a.status = 'canceled'), 0) AS cancellations` + statsFrom + `
+		JOIN hospitals hs ON hs.id = ` + appointmentHospitalExpr + where + `
+		GROUP BY hs.id, hs.name
+		ORDER BY hs.name`
+
+	var hospitals []model.HospitalRates
+	if err := api.Deps.DB.SelectContext(ctx, &hospitals, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to fetch hospital rates: %w", err)
+	}
+	return hospitals, nil
+}
+
+// LabTestVolumesRepo returns the bookings and revenue of every lab test booked
+// in range. Revenue is the booked hospital's price from hospital_lab_tests, so
+// home pickups, which have no hospital, earn nothing here.
+func (api *API) LabTestVolumesRepo(ctx context.Context, filter model.StatsFilter) ([]model.LabTestVolume, error) {
+	where, args := statsWhere(filter)
+	query := `SELECT
+			lt.id AS lab_test_id,
+			COALESCE(lt.name, '') AS name,
+			COUNT(*) AS appointments,
+			COALESCE(SUM(CASE WHEN a.status IN ('canceled', 'rejected', 'no_show') THEN 0 ELSE hlt.price END), 0) AS revenue` + statsFrom + `
+		JOIN lab_tests lt ON lt.id = la.test_type_id
+		LEFT JOIN hospital_lab_tests hlt ON hlt.hospital_id = la.hospital_id AND hlt.lab_test_id = la.test_type_id` + where + `
+		GROUP BY lt.id, lt.name`
+
+	var tests []model.LabTestVolume
+	if err := api.Deps.DB.SelectContext(ctx, &tests, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to fetch lab test volumes: %w", err)
+	}
+	return tests, nil
+}
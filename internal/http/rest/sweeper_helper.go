@@ -0,0 +1,139 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/model"
+)
+
+// SweepAppointments closes appointments nobody acted on: confirmed visits with
+// no check-in become no-shows, appointments whose reschedule offers all lapsed
+// go back to pending, and pending appointments or unanswered reschedule
+// requests whose times have passed are canceled. The patient is notified of
+// each change.
+func (api *API) SweepAppointments(ctx context.Context) error {
+	sweeps, err := api.appointmentSweeps()
+	if err != nil {
+		return err
+	}
+
+	for _, sweep := range sweeps {
+		ids, err := api.SweepCandidatesRepo(ctx, sweep)
+		if err != nil {
+			return fmt.Errorf("finding %s appointments: %w", sweep.name, err)
+		}
+
+		for _, id := range ids {
+			if _, err := api.SweepAppointmentRepo(ctx, id, sweep); err != nil {
+				api.Deps.Logger.Error("error sweeping appointment", "sweep", sweep.name, "appointment_id", id, "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (api *API) appointmentSweeps() ([]appointmentSweep, error) {
+	noShowGrace, err := time.ParseDuration(api.Config.NoShowGracePeriod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NO_SHOW_GRACE_PERIOD: %w", err)
+	}
+	pendingGrace, err := time.ParseDuration(api.Config.StalePendingGracePeriod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid STALE_PENDING_GRACE_PERIOD: %w", err)
+	}
+
+	return []appointmentSweep{
+		{
+			name:   "no-show",
+			where:  `a.status IN (?, ?, ?) AND a.appointment_datetime <= DATE_SUB(NOW(), INTERVAL ? SECOND)`,
+			args:   []interface{}{model.StatusConfirmed, model.StatusScheduled, model.StatusRescheduleAccepted, int(noShowGrace.Seconds())},
+			to:     model.StatusNoShow,
+			notes:  "Marked as no-show: no check-in by the end of the grace period",
+			notice: noShowNotice(),
+		},
+		{
+			// An offer lapses at its deadline or once its proposed time has
+			// passed. The appointment goes back for review when none is open.
+			name: "expired offer",
+			where: `a.status = ? AND NOT EXISTS (
+				SELECT 1 FROM reschedule_offers ro
+				WHERE ro.appointment_id = a.id
+				AND ro.status = ?
+				AND ro.expires_at > NOW()
+				AND TIMESTAMP(ro.proposed_date, ro.proposed_time) > NOW()
+			)`,
+			args:   []interface{}{model.StatusRescheduleOffered, model.OfferPending},
+			to:     model.StatusPending,
+			notes:  "Returned to pending: the reschedule offers expired without a response",
+			notice: offerExpiredNotice(),
+		},
+		{
+			name:   "stale pending",
+			where:  `a.status = ? AND a.appointment_datetime <= DATE_SUB(NOW(), INTERVAL ? SECOND)`,
+			args:   []interface{}{model.StatusPending, int(pendingGrace.Seconds())},
+			to:     model.StatusCanceled,
+			notes:  "Canceled automatically: the appointment time passed before it was confirmed",
+			notice: autoCancelNotice("The appointment time passed before we could confirm it."),
+		},
+		{
+			// A reschedule request is only closed once neither the original
+			// time nor any of the proposed ones can still be kept.
+			name: "lapsed reschedule request",
+			where: `a.status = ? AND a.appointment_datetime <= DATE_SUB(NOW(), INTERVAL ? SECOND) AND NOT EXISTS (
+				SELECT 1 FROM reschedule_requests rr
+				WHERE rr.appointment_id = a.id
+				AND rr.status = ?
+				AND TIMESTAMP(rr.proposed_date, rr.proposed_time) > DATE_SUB(NOW(), INTERVAL ? SECOND)
+			)`,
+			args:   []interface{}{model.StatusRescheduleRequested, int(pendingGrace.Seconds()), model.RequestPending, int(pendingGrace.Seconds())},
+			to:     model.StatusCanceled,
+			notes:  "Canceled automatically: the original and requested times passed before the reschedule request was answered",
+			notice: autoCancelNotice("The original and requested times passed before we could answer your reschedule request."),
+		},
+	}, nil
+}
+
+func noShowNotice() *appointmentNotice {
+	return &appointmentNotice{
+		template: "appointmentNoShow.tmpl",
+		data: func(appointment AppointmentEmailData) map[string]interface{} {
+			return map[string]interface{}{
+				"PatientName":     appointment.PatientName,
+				"AppointmentType": appointment.AppointmentType,
+				"AppointmentDate": appointment.AppointmentDate,
+				"AppointmentTime": appointment.AppointmentTime,
+			}
+		},
+	}
+}
+
+func offerExpiredNotice() *appointmentNotice {
+	return &appointmentNotice{
+		template: "rescheduleOfferExpired.tmpl",
+		data: func(appointment AppointmentEmailData) map[string]interface{} {
+			return map[string]interface{}{
+				"PatientName":     appointment.PatientName,
+				"AppointmentType": appointment.AppointmentType,
+				"AppointmentDate": appointment.AppointmentDate,
+				"AppointmentTime": appointment.AppointmentTime,
+			}
+		},
+	}
+}
+
+func autoCancelNotice(reason string) *appointmentNotice {
+	return &appointmentNotice{
+		template: "appointmentCanceled.tmpl",
+		data: func(appointment AppointmentEmailData) map[string]interface{} {
+			return map[string]interface{}{
+				"PatientName":     appointment.PatientName,
+				"AppointmentType": appointment.AppointmentType,
+				"AppointmentDate": appointment.AppointmentDate,
+				"AppointmentTime": appointment.AppointmentTime,
+				"Reason":          reason,
+			}
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/bwise1/your_care_api/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+const sweepBatchSize = 100
+
+// appointmentSweep moves appointments matching where to a new status on
+// behalf of the system. where is a condition on appointments aliased as a and
+// is checked again under the row lock, so an appointment that changed after
+// it was picked up is left alone.
+type appointmentSweep struct {
+	name   string
+	where  string
+	args   []interface{}
+	to     model.AppointmentStatus
+	notes  string
+	notice *appointmentNotice
+}
+
+// SweepCandidatesRepo returns the appointments the sweep applies to.
+func (api *API) SweepCandidatesRepo(ctx context.Context, sweep appointmentSweep) ([]int, error) {
+	stmt := `SELECT a.id FROM appointments a WHERE ` + sweep.where + ` ORDER BY a.appointment_datetime LIMIT ?`
+	args := append(append([]interface{}{}, sweep.args...), sweepBatchSize)
+
+	ids := []int{}
+	err := api.Deps.DB.SelectContext(ctx, &ids, stmt, args...)
+	return ids, err
+}
+
+// SweepAppointmentRepo applies the sweep to one appointment. It reports false
+// when the appointment no longer matches.
+func (api *API) SweepAppointmentRepo(ctx context.Context, appointmentID int, sweep appointmentSweep) (bool, error) {
+	var swept bool
+	var current lockedAppointment
+	err := api.Deps.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		stmt := `SELECT a.status, a.appointment_type FROM appointments a WHERE a.id = ? AND ` + sweep.where + ` FOR UPDATE`
+		args := append([]interface{}{appointmentID}, sweep.args...)
+		if err := tx.GetContext(ctx, &current, stmt, args...); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		if err := model.ValidateTransition(current.Status, sweep.to); err != nil {
+			return err
+		}
+
+		if releasesSlot(sweep.to) {
+			if err := releaseAppointmentSlot(ctx, tx, appointmentID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE appointments SET status = ?, updated_at = NOW() WHERE id = ?`, string(sweep.to), appointmentID); err != nil {
+			return err
+		}
+
+		if current.Status == model.StatusRescheduleOffered {
+			stmt = `UPDATE reschedule_offers SET status = ?, updated_at = NOW() WHERE appointment_id = ? AND status = ?`
+			if _, err := tx.ExecContext(ctx, stmt, model.OfferExpired, appointmentID, model.OfferPending); err != nil {
+				return err
+			}
+		}
+		if current.Status == model.StatusRescheduleRequested {
+			stmt = `UPDATE reschedule_requests SET status = ?, updated_at = NOW() WHERE appointment_id = ? AND status = ?`
+			if _, err := tx.ExecContext(ctx, stmt, model.RequestExpired, appointmentID, model.RequestPending); err != nil {
+				return err
+			}
+		}
+
+		stmt = `
+			INSERT INTO appointment_status_history (appointment_id, status, notes, changed_by_system)
+			VALUES (?, ?, ?, 1)`
+		if _, err := tx.ExecContext(ctx, stmt, appointmentID, string(sweep.to), sweep.notes); err != nil {
+			return err
+		}
+
+		swept = true
+		return api.enqueueAppointmentNotice(ctx, tx, appointmentID, sweep.notice)
+	})
+	if swept && err == nil {
+		api.countTransition(current.AppointmentType, sweep.to)
+	}
+	return swept, err
+}
@@ -1,24 +1,24 @@
 package rest
 
 import (
-	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"github.com/bwise1/your_care_api/internal/model"
 	"github.com/bwise1/your_care_api/util"
+	"github.com/bwise1/your_care_api/util/apperr"
 	"github.com/bwise1/your_care_api/util/values"
+	"github.com/lucsky/cuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 func (api *API) RegisterUser(req model.UserRequest) (model.User, string, string, error) {
 
 	var err error
-	var ctx = context.TODO()
+	var ctx = api.rootContext()
 
 	req.Email = strings.Trim(req.Email, " ")
 	req.FirstName = strings.Trim(req.FirstName, " ")
@@ -34,7 +34,7 @@ func (api *API) RegisterUser(req model.UserRequest) (model.User, string, string,
 		return model.User{}, values.Error, fmt.Sprintf("%s [EmCh]", values.SystemErr), err
 	}
 	if exists {
-		return model.User{}, values.Conflict, "User already exists. Please login", errors.New(values.Conflict)
+		return model.User{}, values.Conflict, "User already exists. Please login", apperr.New(apperr.CodeAccountExists, "User already exists. Please login")
 	}
 
 	passHash, err := util.HashPassword([]byte(req.Password))
@@ -46,25 +46,12 @@ func (api *API) RegisterUser(req model.UserRequest) (model.User, string, string,
 
 	verificationCode := util.RandomString(6, values.Numbers)
 
-	verificationCodeExpires := time.Now().Add(time.Minute * 10)
+	verificationCodeExpires := time.Now().Add(emailVerificationTTL)
 
 	req.EmailVerificationCode = verificationCode
 	req.EmailVerificationCodeExpires = verificationCodeExpires
 
-	data := struct {
-		Name            string
-		VerificationURL string
-	}{
-		Name:            req.FirstName,
-		VerificationURL: "http://localhost:3000?token=" + verificationCode,
-	}
-	patterns := []string{"verifyEmail.tmpl"}
-	err = api.Deps.Mailer.Send(req.Email, data, patterns...)
-	if err != nil {
-		log.Println(err)
-	}
-
-	err = api.CreateUserRepo(context.TODO(), req)
+	err = api.CreateUserRepo(ctx, req, api.verificationNotice(req.FirstName, req.Email, verificationCode))
 	if err != nil {
 		return model.User{}, values.Error, fmt.Sprintf("%s [CrUs]", values.SystemErr), err
 	}
@@ -80,7 +67,7 @@ func (api *API) RegisterUser(req model.UserRequest) (model.User, string, string,
 func (api *API) LoginUser(req model.UserLoginReq) (model.LoginResponse, string, string, error) {
 
 	var err error
-	var ctx = context.TODO()
+	var ctx = api.rootContext()
 
 	req.Email = strings.Trim(req.Email, " ")
 
@@ -94,36 +81,36 @@ func (api *API) LoginUser(req model.UserLoginReq) (model.LoginResponse, string,
 		return model.LoginResponse{}, values.Error, fmt.Sprintf("%s [EmCh]", values.SystemErr), err
 	}
 	if !exists {
-		return model.LoginResponse{}, values.NotFound, "User does not exist. Please register", errors.New(values.NotFound)
+		return model.LoginResponse{}, values.NotFound, "User does not exist. Please register", apperr.New(apperr.CodeAccountNotFound, "User does not exist. Please register")
 	}
 
 	user, err := api.GetUserByEmail(ctx, req.Email)
 	if err != nil {
-		fmt.Println(err)
 		if errors.Is(err, sql.ErrNoRows) {
-			return model.LoginResponse{}, values.NotFound, "User does not exist", err
+			return model.LoginResponse{}, values.NotFound, "User does not exist", apperr.Wrap(err, apperr.CodeAccountNotFound, "User does not exist")
 		}
 		return model.LoginResponse{}, values.Error, fmt.Sprintf("%s [GtUs]", values.SystemErr), err
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
-		return model.LoginResponse{}, values.NotAuthorised, "Invalid password provided", err
+		return model.LoginResponse{}, values.NotAuthorised, "Invalid password provided", apperr.Wrap(err, apperr.CodeInvalidCredentials, "Invalid password provided")
 	}
 
-	token, token_expires, err := api.createToken(user.ID, user.Role)
+	// Every login starts a new session so each device keeps its own refresh token
+	sessionID := cuid.New()
+
+	token, token_expires, err := api.createToken(user.ID, user.Role, sessionID)
 	if err != nil {
 		return model.LoginResponse{}, values.Error, fmt.Sprintf("%s [CrTk]", values.SystemErr), err
 	}
 
-	refresh, refresh_expires, err := api.createRefreshToken(user.ID)
+	refresh, refresh_expires, err := api.createRefreshToken(user.ID, sessionID)
 	if err != nil {
-		log.Println(err)
 		return model.LoginResponse{}, values.Error, fmt.Sprintf("%s [CrRF]", values.SystemErr), err
 	}
 
-	err = api.StoreRefreshToken(ctx, user.ID, refresh, refresh_expires)
-	log.Println("user id", user.ID)
+	err = api.CreateSession(ctx, user.ID, sessionID, util.HashToken(refresh), req.UserAgent, req.IPAddress, refresh_expires)
 	if err != nil {
 		return model.LoginResponse{}, values.Error, fmt.Sprintf("%s [StRF]", values.SystemErr), err
 	}
@@ -143,29 +130,46 @@ func (api *API) LoginUser(req model.UserLoginReq) (model.LoginResponse, string,
 func (api *API) RefreshToken(req model.RefreshTokenReq) (model.TokenInfo, string, string, error) {
 
 	var err error
-	var ctx = context.TODO()
+	var ctx = api.rootContext()
 
 	tokenClaims, err := api.verifyToken(req.RefreshToken, true)
 
 	if err != nil {
-		return model.TokenInfo{}, values.NotAuthorised, "Invalid refresh token", err
+		return model.TokenInfo{}, values.NotAuthorised, "Invalid refresh token", apperr.Wrap(err, apperr.CodeInvalidRefreshToken, "Invalid refresh token")
+	}
+	if tokenClaims.SessionID == "" {
+		return model.TokenInfo{}, values.NotAuthorised, "Session expired, please login again", apperr.New(apperr.CodeSessionExpired, "Session expired, please login again")
 	}
 
-	newAccessToken, accessTokenExpiry, err := api.createToken(tokenClaims.UserID, tokenClaims.Role)
+	// Look the user up again so a role change takes effect on the next refresh
+	user, err := api.GetUserByID(ctx, tokenClaims.UserID)
 	if err != nil {
-		return model.TokenInfo{}, values.Error, "Failed to create new access token", err
+		return model.TokenInfo{}, values.NotAuthorised, "Invalid refresh token", apperr.Wrap(err, apperr.CodeInvalidRefreshToken, "Invalid refresh token")
 	}
 
-	newRefreshToken, refreshTokenExpiry, err := api.createRefreshToken(tokenClaims.UserID)
+	newRefreshToken, refreshTokenExpiry, err := api.createRefreshToken(user.ID, tokenClaims.SessionID)
 	if err != nil {
 		return model.TokenInfo{}, values.Error, "Failed to create new refresh token", err
 	}
 
-	err = api.StoreRefreshToken(ctx, tokenClaims.UserID, newRefreshToken, refreshTokenExpiry)
+	err = api.RotateSession(ctx, tokenClaims.SessionID, user.ID,
+		util.HashToken(req.RefreshToken), util.HashToken(newRefreshToken),
+		req.UserAgent, req.IPAddress, refreshTokenExpiry)
 	if err != nil {
+		if errors.Is(err, model.ErrRefreshTokenReused) {
+			api.Deps.Logger.Warn("refresh token reuse detected, session revoked", "session_id", tokenClaims.SessionID, "user_id", tokenClaims.UserID)
+		}
+		if isSessionError(err) {
+			return model.TokenInfo{}, values.NotAuthorised, "Session expired, please login again", apperr.Wrap(err, apperr.CodeSessionExpired, "Session expired, please login again")
+		}
 		return model.TokenInfo{}, values.Error, "Failed to store new refresh token", err
 	}
 
+	newAccessToken, accessTokenExpiry, err := api.createToken(user.ID, user.Role, tokenClaims.SessionID)
+	if err != nil {
+		return model.TokenInfo{}, values.Error, "Failed to create new access token", err
+	}
+
 	return model.TokenInfo{
 		AccessToken:        newAccessToken,
 		AccessTokenExpiry:  accessTokenExpiry,
@@ -176,17 +180,17 @@ func (api *API) RefreshToken(req model.RefreshTokenReq) (model.TokenInfo, string
 
 func (api *API) ChangeUserPassword(userID int, req model.ChangePasswordReq) (string, string, error) {
 	// Get current user
-	user, err := api.GetUserByID(context.Background(), userID)
+	user, err := api.GetUserByID(api.rootContext(), userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return values.NotFound, "user not found", err
+			return values.NotFound, "user not found", apperr.Wrap(err, apperr.CodeAccountNotFound, "user not found")
 		}
 		return values.Error, "error getting user", err
 	}
 
 	// Verify old password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.OldPassword)); err != nil {
-		return values.BadRequestBody, "invalid old password", err
+		return values.BadRequestBody, "invalid old password", apperr.Wrap(err, apperr.CodeIncorrectPassword, "invalid old password")
 	}
 
 	// Hash new password
@@ -196,7 +200,7 @@ func (api *API) ChangeUserPassword(userID int, req model.ChangePasswordReq) (str
 	}
 
 	// Update password in database
-	err = api.UpdateUserPassword(context.Background(), userID, string(hashedPassword))
+	err = api.UpdateUserPassword(api.rootContext(), userID, string(hashedPassword))
 	if err != nil {
 		return values.Error, "error updating password", err
 	}
@@ -211,7 +215,7 @@ func (api *API) CheckEmail(req model.EmailReq) (string, string, error) {
 	}
 
 	// Check if email exists
-	exists, err := api.EmailExists(context.Background(), req.Email)
+	exists, err := api.EmailExists(api.rootContext(), req.Email)
 	if err != nil {
 		return values.Error, "error checking email", err
 	}
@@ -225,44 +229,3 @@ func (api *API) CheckEmail(req model.EmailReq) (string, string, error) {
 func (api *API) googleLogin() {
 
 }
-
-func (api *API) RegisterAdminUser(req model.UserRequest) (model.User, string, string, error) {
-	var err error
-	var ctx = context.TODO()
-
-	req.Email = strings.Trim(req.Email, " ")
-	req.FirstName = strings.Trim(req.FirstName, " ")
-	req.LastName = strings.Trim(req.LastName, " ")
-
-	err = util.ValidEmail(req.Email)
-	if err != nil {
-		return model.User{}, values.NotAllowed, "Invalid email address provided", err
-	}
-
-	exists, err := api.EmailExists(ctx, req.Email)
-	if err != nil {
-		return model.User{}, values.Error, fmt.Sprintf("%s [EmCh]", values.SystemErr), err
-	}
-	if exists {
-		return model.User{}, values.Conflict, "User already exists. Please login", errors.New(values.Conflict)
-	}
-
-	passHash, err := util.HashPassword([]byte(req.Password))
-	if err != nil {
-		return model.User{}, values.Error, fmt.Sprintf("%s [HsPw]", values.SystemErr), err
-	}
-
-	req.Password = passHash
-
-	err = api.CreateAdminUserRepo(context.TODO(), req)
-	if err != nil {
-		return model.User{}, values.Error, fmt.Sprintf("%s [CrUs]", values.SystemErr), err
-	}
-
-	user := model.User{
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Email:     req.Email,
-	}
-	return user, values.Created, "Admin registration completed successfully", nil
-}
@@ -0,0 +1,112 @@
+// Package lifecycle tracks the background work of the process, such as the
+// outbox worker, the scheduler and emails sent outside a request, so shutdown
+// can stop it and wait for it to finish before the database is closed.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// ErrStopping is returned by Go once Stop has been called.
+var ErrStopping = errors.New("lifecycle is stopping")
+
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	logger *slog.Logger
+
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	running  map[string]int
+	stopping bool
+}
+
+func New(logger *slog.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		ctx:     ctx,
+		cancel:  cancel,
+		logger:  logger.With("component", "lifecycle"),
+		running: map[string]int{},
+	}
+}
+
+// Context is canceled when Stop is called. Long running work should return
+// soon after.
+func (m *Manager) Context() context.Context {
+	return m.ctx
+}
+
+// Go runs fn in a goroutine that Stop waits for. name identifies the work in
+// shutdown logs. A panic in fn is logged instead of crashing the process.
+// Once Stop has been called fn is not run and ErrStopping is returned.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) error {
+	// Adding to wg under mu keeps it from racing with the Wait in Stop
+	m.mu.Lock()
+	if m.stopping {
+		m.mu.Unlock()
+		m.logger.Warn("background task rejected during shutdown", "task", name)
+		return ErrStopping
+	}
+	m.running[name]++
+	m.wg.Add(1)
+	m.mu.Unlock()
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				m.logger.Error("background task panicked", "task", name, "panic", fmt.Sprint(p))
+			}
+			m.mu.Lock()
+			m.running[name]--
+			if m.running[name] == 0 {
+				delete(m.running, name)
+			}
+			m.mu.Unlock()
+			m.wg.Done()
+		}()
+		fn(m.ctx)
+	}()
+	return nil
+}
+
+// Stop rejects new background work, cancels the context handed to the work
+// already running and waits for it to return, or for ctx to end. On timeout
+// the work still running is logged.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	m.stopping = true
+	m.mu.Unlock()
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		m.logger.Warn("background tasks still running at shutdown deadline", "tasks", m.pending())
+		return fmt.Errorf("waiting for background tasks: %w", ctx.Err())
+	}
+}
+
+func (m *Manager) pending() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.running))
+	for name := range m.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
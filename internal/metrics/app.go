@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// Metrics are the application metrics exposed on /metrics.
+type Metrics struct {
+	Registry *Registry
+
+	httpRequests           *Counter
+	httpDuration           *Histogram
+	appointmentsCreated    *Counter
+	appointmentTransitions *Counter
+	mailerSends            *Counter
+}
+
+func New() *Metrics {
+	r := NewRegistry()
+	return &Metrics{
+		Registry: r,
+		httpRequests: r.NewCounter("http_requests_total",
+			"HTTP requests handled, by route pattern and status.", "method", "route", "status"),
+		httpDuration: r.NewHistogram("http_request_duration_seconds",
+			"HTTP request latency, by route pattern and status.", DefaultBuckets, "method", "route", "status"),
+		appointmentsCreated: r.NewCounter("appointments_created_total",
+			"Appointments booked, by appointment type.", "type"),
+		appointmentTransitions: r.NewCounter("appointment_status_transitions_total",
+			"Appointment status changes, by appointment type and the status moved to.", "type", "status"),
+		mailerSends: r.NewCounter("mailer_sends_total",
+			"Emails handed to the SMTP server, by result.", "result"),
+	}
+}
+
+// RegisterDBStats exposes the connection pool statistics returned by stats,
+// usually the Stats method of the database handle.
+func (m *Metrics) RegisterDBStats(stats func() sql.DBStats) {
+	m.Registry.NewGaugeFunc("db_open_connections", "Open database connections, in use or idle.", func() float64 {
+		return float64(stats().OpenConnections)
+	})
+	m.Registry.NewGaugeFunc("db_in_use_connections", "Database connections currently in use.", func() float64 {
+		return float64(stats().InUse)
+	})
+	m.Registry.NewGaugeFunc("db_idle_connections", "Idle database connections.", func() float64 {
+		return float64(stats().Idle)
+	})
+	m.Registry.NewGaugeFunc("db_max_open_connections", "Maximum number of open database connections.", func() float64 {
+		return float64(stats().MaxOpenConnections)
+	})
+	m.Registry.NewCounterFunc("db_wait_count_total", "Times a query waited for a free database connection.", func() float64 {
+		return float64(stats().WaitCount)
+	})
+	m.Registry.NewCounterFunc("db_wait_duration_seconds_total", "Time spent waiting for a free database connection.", func() float64 {
+		return stats().WaitDuration.Seconds()
+	})
+}
+
+// ObserveRequest records a handled HTTP request. route is the chi route
+// pattern, never the raw path, so the number of series stays bounded.
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.Inc(method, route, code)
+	m.httpDuration.Observe(duration.Seconds(), method, route, code)
+}
+
+func (m *Metrics) AppointmentCreated(appointmentType string) {
+	m.appointmentsCreated.Inc(appointmentType)
+}
+
+func (m *Metrics) AppointmentTransitioned(appointmentType, status string) {
+	m.appointmentTransitions.Inc(appointmentType, status)
+}
+
+// ObserveEmail counts an email send attempt as a success or failure.
+func (m *Metrics) ObserveEmail(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.mailerSends.Inc(result)
+}
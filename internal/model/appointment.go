@@ -1,23 +1,139 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Appointment status constants
 type AppointmentStatus string
 
 const (
-	StatusPending          AppointmentStatus = "pending"
-	StatusConfirmed        AppointmentStatus = "confirmed"
-	StatusScheduled        AppointmentStatus = "scheduled"
-	StatusRescheduleOffered AppointmentStatus = "reschedule_offered"
-	StatusRescheduleAccepted AppointmentStatus = "reschedule_accepted"
-	StatusInProgress       AppointmentStatus = "in_progress"
-	StatusCompleted        AppointmentStatus = "completed"
-	StatusCanceled         AppointmentStatus = "canceled"
-	StatusRejected         AppointmentStatus = "rejected"
-	StatusNoShow           AppointmentStatus = "no_show"
+	StatusPending             AppointmentStatus = "pending"
+	StatusConfirmed           AppointmentStatus = "confirmed"
+	StatusScheduled           AppointmentStatus = "scheduled"
+	StatusRescheduleOffered   AppointmentStatus = "reschedule_offered"
+	StatusRescheduleAccepted  AppointmentStatus = "reschedule_accepted"
+	StatusRescheduleRequested AppointmentStatus = "reschedule_requested"
+	StatusInProgress          AppointmentStatus = "in_progress"
+	StatusCompleted           AppointmentStatus = "completed"
+	StatusCanceled            AppointmentStatus = "canceled"
+	StatusRejected            AppointmentStatus = "rejected"
+	StatusNoShow              AppointmentStatus = "no_show"
 )
 
+// appointmentTransitions is the authoritative table of legal status changes.
+// Every repository write that changes an appointment status is checked against it.
+var appointmentTransitions = map[AppointmentStatus][]AppointmentStatus{
+	StatusPending:            {StatusConfirmed, StatusRejected, StatusRescheduleOffered, StatusRescheduleRequested, StatusCanceled},
+	StatusConfirmed:          {StatusRescheduleOffered, StatusRescheduleRequested, StatusCanceled, StatusInProgress, StatusNoShow},
+	StatusScheduled:          {StatusRescheduleOffered, StatusRescheduleRequested, StatusCanceled, StatusInProgress, StatusNoShow},
+	StatusRescheduleOffered:  {StatusRescheduleOffered, StatusRescheduleAccepted, StatusPending, StatusCanceled},
+	StatusRescheduleAccepted: {StatusConfirmed, StatusRescheduleRequested, StatusCanceled, StatusInProgress, StatusNoShow},
+	// A patient's request is approved by confirming the new time, or answered
+	// with an offer. The patient may replace it with a new request.
+	StatusRescheduleRequested: {StatusRescheduleRequested, StatusConfirmed, StatusRescheduleOffered, StatusCanceled},
+	StatusInProgress:          {StatusCompleted, StatusNoShow},
+	StatusCompleted:           {},
+	StatusCanceled:            {},
+	StatusRejected:            {},
+	StatusNoShow:              {},
+}
+
+// NextStatuses returns the statuses an appointment may move to from s.
+func (s AppointmentStatus) NextStatuses() []AppointmentStatus {
+	next := appointmentTransitions[s]
+	out := make([]AppointmentStatus, len(next))
+	copy(out, next)
+	return out
+}
+
+// IsTerminal reports whether s is a final status that no transition leaves.
+func (s AppointmentStatus) IsTerminal() bool {
+	next, ok := appointmentTransitions[s]
+	return ok && len(next) == 0
+}
+
+// CanTransitionTo reports whether moving from s to next is allowed.
+func (s AppointmentStatus) CanTransitionTo(next AppointmentStatus) bool {
+	for _, allowed := range appointmentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition returns a *TransitionError when from -> to is not allowed.
+func ValidateTransition(from, to AppointmentStatus) error {
+	if from.CanTransitionTo(to) {
+		return nil
+	}
+	return &TransitionError{From: from, To: to, Allowed: from.NextStatuses()}
+}
+
+// TransitionError describes an illegal appointment status change.
+type TransitionError struct {
+	From    AppointmentStatus
+	To      AppointmentStatus
+	Allowed []AppointmentStatus
+}
+
+func (e *TransitionError) Error() string {
+	allowed := make([]string, len(e.Allowed))
+	for i, status := range e.Allowed {
+		allowed[i] = string(status)
+	}
+	if len(allowed) == 0 {
+		return fmt.Sprintf("appointment cannot move from %s to %s; %s is a final state", e.From, e.To, e.From)
+	}
+	return fmt.Sprintf("appointment cannot move from %s to %s; allowed next states: %s", e.From, e.To, strings.Join(allowed, ", "))
+}
+
+// AppointmentAction is a named action exposed to clients and the status it leads to.
+// When From is set the action is only offered from those statuses.
+type AppointmentAction struct {
+	Name   string
+	Target AppointmentStatus
+	From   []AppointmentStatus
+}
+
+// AvailableFrom reports whether the action can be taken on an appointment in status.
+func (a AppointmentAction) AvailableFrom(status AppointmentStatus) bool {
+	if !status.CanTransitionTo(a.Target) {
+		return false
+	}
+	if len(a.From) == 0 {
+		return true
+	}
+	for _, from := range a.From {
+		if from == status {
+			return true
+		}
+	}
+	return false
+}
+
+var AdminAppointmentActions = []AppointmentAction{
+	{Name: "confirm", Target: StatusConfirmed},
+	{Name: "reject", Target: StatusRejected},
+	{Name: "reschedule", Target: StatusRescheduleOffered, From: []AppointmentStatus{StatusPending, StatusConfirmed, StatusScheduled, StatusRescheduleRequested}},
+	{Name: "approve_reschedule_request", Target: StatusConfirmed, From: []AppointmentStatus{StatusRescheduleRequested}},
+	{Name: "cancel", Target: StatusCanceled},
+	{Name: "offer_new_reschedule", Target: StatusRescheduleOffered, From: []AppointmentStatus{StatusRescheduleOffered}},
+	{Name: "mark_in_progress", Target: StatusInProgress},
+	{Name: "mark_completed", Target: StatusCompleted},
+	{Name: "mark_no_show", Target: StatusNoShow},
+}
+
+var UserAppointmentActions = []AppointmentAction{
+	{Name: "accept_reschedule", Target: StatusRescheduleAccepted},
+	{Name: "reject_reschedule", Target: StatusPending, From: []AppointmentStatus{StatusRescheduleOffered}},
+	{Name: "request_reschedule", Target: StatusRescheduleRequested},
+	{Name: "cancel", Target: StatusCanceled},
+}
+
 // Appointment type constants
 type AppointmentType string
 
@@ -50,15 +166,18 @@ type Appointment struct {
 }
 
 type AppointmentDetails struct {
-	ID                  int                 `db:"id" json:"id"`
-	UserID              int                 `db:"user_id" json:"user_id"`
-	AppointmentType     string              `db:"appointment_type" json:"appointment_type"`
-	AppointmentDatetime *time.Time          `db:"appointment_datetime" json:"appointment_datetime"`
-	Status              string              `db:"status" json:"status"`
-	CreatedAt           *time.Time          `db:"created_at,omitempty" json:"created_at,omitempty"`
-	UpdatedAt           *time.Time          `db:"updated_at,omitempty" json:"updated_at,omitempty"`
-	DoctorDetails       *DoctorAppointment  `db:"doctor_details,omitempty" json:"doctor_details,omitempty"`
-	LabTestDetails      *LabTestAppointment `db:"lab_test_details,omitempty" json:"lab_test_details,omitempty"`
+	ID                  int                    `db:"id" json:"id"`
+	UserID              int                    `db:"user_id" json:"user_id"`
+	AppointmentType     string                 `db:"appointment_type" json:"appointment_type"`
+	AppointmentDatetime *time.Time             `db:"appointment_datetime" json:"appointment_datetime"`
+	Status              string                 `db:"status" json:"status"`
+	CreatedAt           *time.Time             `db:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt           *time.Time             `db:"updated_at,omitempty" json:"updated_at,omitempty"`
+	DoctorDetails       *DoctorAppointment     `db:"doctor_details,omitempty" json:"doctor_details,omitempty"`
+	LabTestDetails      *LabTestAppointment    `db:"lab_test_details,omitempty" json:"lab_test_details,omitempty"`
+	IVFDetails          *IVFAppointmentDetails `db:"ivf_details,omitempty" json:"ivf_details,omitempty"`
+	RescheduleRequests  []RescheduleRequest    `db:"-" json:"reschedule_requests,omitempty"`
+	Highlights          []SearchHighlight      `db:"-" json:"highlights,omitempty"`
 }
 
 type AppointmentRow struct {
@@ -71,6 +190,7 @@ type AppointmentRow struct {
 	UpdatedAt           *time.Time `db:"updated_at"`
 	DoctorDetailsJSON   []byte     `db:"doctor_details"`
 	LabTestDetailsJSON  []byte     `db:"lab_test_details"`
+	IVFDetailsJSON      []byte     `db:"ivf_details"`
 }
 
 type DoctorAppointment struct {
@@ -114,11 +234,11 @@ type LabAppointmentReq struct {
 	UserID                 int     `json:"user"`
 	DoctorID               *int    `json:"doctor,omitempty"`   // Pointer to handle nullability
 	HospitalID             *int    `json:"hospital,omitempty"` // Pointer to handle nullability
-	LabTestID              int     `json:"lab_test"`
-	AppointmentDate        string  `json:"appointment_date"`
-	AppointmentTime        string  `json:"appointment_time"`
-	PickupType             string  `json:"pickup_type"`
-	HomeLocation           *string `json:"home_location,omitempty"` // Pointer to handle nullability
+	LabTestID              int     `json:"lab_test" validate:"required"`
+	AppointmentDate        string  `json:"appointment_date" validate:"required,date,notpast"`
+	AppointmentTime        string  `json:"appointment_time" validate:"required,time"`
+	PickupType             string  `json:"pickup_type" validate:"required,oneof=home hospital"`
+	HomeLocation           *string `json:"home_location,omitempty" validate:"omitempty,max=500"` // Pointer to handle nullability
 	TestTypeID             int     `json:"test_type"`
 	AdditionalInstructions *string `json:"additional_instructions,omitempty"` // Pointer to handle nullability
 }
@@ -139,52 +259,134 @@ type DoctorAppointmentReq struct {
 // Request structs for API
 type CreateDoctorAppointmentRequest struct {
 	UserID          int    `json:"user_id"`
-	AppointmentDate string `json:"appointment_date"` // Format: "2024-10-17"
-	AppointmentTime string `json:"appointment_time"` // Format: "14:30"
-	DoctorID        int    `json:"doctor_id"`
+	AppointmentDate string `json:"appointment_date" validate:"required,date,notpast"` // Format: "2024-10-17"
+	AppointmentTime string `json:"appointment_time" validate:"required,time"`         // Format: "14:30"
+	DoctorID        int    `json:"doctor_id" validate:"required"`
 	ReasonForVisit  string `json:"reason_for_visit"`
 	Symptoms        string `json:"symptoms"`
 	AdditionalNotes string `json:"additional_notes,omitempty"`
 }
 
+type CreateIVFAppointmentRequest struct {
+	UserID              int    `json:"user_id"`
+	AppointmentDate     string `json:"appointment_date" validate:"required,date,notpast"` // Format: "2024-10-17"
+	AppointmentTime     string `json:"appointment_time" validate:"required,time"`         // Format: "14:30"
+	TreatmentType       string `json:"treatment_type" validate:"required"`
+	CycleDay            *int   `json:"cycle_day,omitempty"`
+	SpecialInstructions string `json:"special_instructions,omitempty"`
+	PreparationNotes    string `json:"preparation_notes,omitempty"`
+}
+
 type CreateLabTestAppointmentRequest struct {
 	UserID                 int     `json:"user"`
-	AppointmentDate        string  `json:"appointment_date"`
-	TestTypeID             int     `json:"test_type"`
-	PickupType             string  `json:"pickup_type"`
-	HomeLocation           *string `json:"home_location,omitempty"`
+	AppointmentDate        string  `json:"appointment_date" validate:"required,future"`
+	TestTypeID             int     `json:"test_type" validate:"required"`
+	PickupType             string  `json:"pickup_type" validate:"required,oneof=home hospital"`
+	HomeLocation           *string `json:"home_location,omitempty" validate:"omitempty,max=500"`
 	AdditionalInstructions *string `json:"additional_instructions,omitempty"`
 	HospitalID             *int    `json:"hospital,omitempty"`
 }
 
 type AppointmentFilter struct {
-	UserID   int    `json:"user_id"`
-	Date     string `json:"date"`
-	Upcoming bool   `json:"upcoming"`
-	History  bool   `json:"history"`
+	UserID          int      `json:"user_id"`
+	Date            string   `json:"date"`
+	Upcoming        bool     `json:"upcoming"`
+	History         bool     `json:"history"`
+	Status          []string `json:"status,omitempty"`
+	AppointmentType []string `json:"appointment_type,omitempty"`
+	HospitalID      *int     `json:"hospital_id,omitempty"`
+	TestTypeID      *int     `json:"test_type_id,omitempty"`
+	Page            PageRequest
+}
+
+// Sort keys accepted by appointment lists
+const (
+	SortAppointmentDatetime = "appointment_datetime"
+	SortCreatedAt           = "created_at"
+)
+
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// PageRequest selects one page of a list ordered by Sort, with the row id
+// breaking ties. After is nil for the first page.
+type PageRequest struct {
+	Limit int
+	Sort  string
+	Order string
+	After *PageCursor
+}
+
+// PageCursor is the position of the last row of the previous page.
+type PageCursor struct {
+	Value string
+	ID    int
+}
+
+type Pagination struct {
+	Limit      int     `json:"limit"`
+	NextCursor *string `json:"next_cursor"`
+	HasMore    bool    `json:"has_more"`
+}
+
+type AppointmentPage struct {
+	Appointments []AppointmentDetails `json:"appointments"`
+	Pagination   Pagination           `json:"pagination"`
 }
 
 // New structs for enhanced appointment system
 type RescheduleOffer struct {
-	ID              int                `json:"id" db:"id"`
-	AppointmentID   int                `json:"appointment_id" db:"appointment_id"`
-	ProposedDate    string             `json:"proposed_date" db:"proposed_date"`
-	ProposedTime    string             `json:"proposed_time" db:"proposed_time"`
-	AdminNotes      *string            `json:"admin_notes,omitempty" db:"admin_notes"`
-	Status          string             `json:"status" db:"status"`
-	CreatedAt       *time.Time         `json:"created_at" db:"created_at"`
-	UpdatedAt       *time.Time         `json:"updated_at" db:"updated_at"`
+	ID            int        `json:"id" db:"id"`
+	AppointmentID int        `json:"appointment_id" db:"appointment_id"`
+	ProposedDate  string     `json:"proposed_date" db:"proposed_date"`
+	ProposedTime  string     `json:"proposed_time" db:"proposed_time"`
+	AdminNotes    *string    `json:"admin_notes,omitempty" db:"admin_notes"`
+	Status        string     `json:"status" db:"status"`
+	ExpiresAt     *time.Time `json:"expires_at" db:"expires_at"`
+	// RemainingSeconds is how long a pending offer stays open, 0 otherwise
+	RemainingSeconds int64      `json:"remaining_seconds" db:"remaining_seconds"`
+	CreatedAt        *time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        *time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type AppointmentStatusLog struct {
-	ID              int       `json:"id" db:"id"`
-	AppointmentID   int       `json:"appointment_id" db:"appointment_id"`
-	Status          string    `json:"status" db:"status"`
-	Notes           *string   `json:"notes,omitempty" db:"notes"`
-	ChangedByUserID *int      `json:"changed_by_user_id,omitempty" db:"changed_by_user_id"`
+	ID              int        `json:"id" db:"id"`
+	AppointmentID   int        `json:"appointment_id" db:"appointment_id"`
+	Status          string     `json:"status" db:"status"`
+	Notes           *string    `json:"notes,omitempty" db:"notes"`
+	ChangedByUserID *int       `json:"changed_by_user_id,omitempty" db:"changed_by_user_id"`
+	ChangedBySystem bool       `json:"changed_by_system" db:"changed_by_system"`
 	ChangedAt       *time.Time `json:"changed_at" db:"changed_at"`
 }
 
+// IVF treatment types accepted when booking an IVF appointment
+const (
+	IVFConsultation         = "consultation"
+	IVFStimulationMonitor   = "stimulation_monitoring"
+	IVFEggRetrieval         = "egg_retrieval"
+	IVFEmbryoTransfer       = "embryo_transfer"
+	IVFFrozenEmbryoTransfer = "frozen_embryo_transfer"
+	IVFIntrauterineInsem    = "iui"
+	IVFICSI                 = "icsi"
+	IVFEggFreezing          = "egg_freezing"
+)
+
+var IVFTreatmentTypes = []string{
+	IVFConsultation,
+	IVFStimulationMonitor,
+	IVFEggRetrieval,
+	IVFEmbryoTransfer,
+	IVFFrozenEmbryoTransfer,
+	IVFIntrauterineInsem,
+	IVFICSI,
+	IVFEggFreezing,
+}
+
+// MaxIVFCycleDay is the last menstrual cycle day an IVF appointment can be tied to.
+const MaxIVFCycleDay = 40
+
 type IVFAppointmentDetails struct {
 	ID                  int     `json:"id" db:"id"`
 	AppointmentID       int     `json:"appointment_id" db:"appointment_id"`
@@ -196,10 +398,16 @@ type IVFAppointmentDetails struct {
 
 // Admin request structs
 type AdminAppointmentAction struct {
-	Notes           *string    `json:"notes,omitempty"`
-	RejectionReason *string    `json:"rejection_reason,omitempty"`
-	ProposedDate    *string    `json:"proposed_date,omitempty"`
-	ProposedTime    *string    `json:"proposed_time,omitempty"`
+	Notes           *string `json:"notes,omitempty"`
+	RejectionReason *string `json:"rejection_reason,omitempty" validate:"omitempty,max=500"`
+	ProposedDate    *string `json:"proposed_date,omitempty"`
+	ProposedTime    *string `json:"proposed_time,omitempty"`
+	// ProposedSlots offers several alternatives at once, alongside or instead
+	// of ProposedDate and ProposedTime
+	ProposedSlots []RescheduleSlot `json:"proposed_slots,omitempty" validate:"max=5"`
+	// OfferDeadline is when the offers lapse, as YYYY-MM-DDTHH:MM. It defaults
+	// to RESCHEDULE_OFFER_TTL from now.
+	OfferDeadline *string `json:"offer_deadline,omitempty"`
 }
 
 type AdminStatusUpdateRequest struct {
@@ -216,36 +424,40 @@ type AdminAppointmentFilter struct {
 	DateFrom        *string  `json:"date_from,omitempty"`
 	DateTo          *string  `json:"date_to,omitempty"`
 	ProviderID      *int     `json:"provider_id,omitempty"`
-	Page            int      `json:"page"`
-	Limit           int      `json:"limit"`
+	HospitalID      *int     `json:"hospital_id,omitempty"`
+	// SearchTerms must all match, each in any of the searchable fields
+	SearchTerms []string `json:"search,omitempty"`
+	Page        PageRequest
 }
 
 // Detailed appointment structures for get by ID
 type DetailedAppointment struct {
-	ID                  int                        `json:"id"`
-	UserID              int                        `json:"user_id"`
-	AppointmentType     string                     `json:"appointment_type"`
-	AppointmentDatetime *time.Time                 `json:"appointment_datetime"`
-	Status              string                     `json:"status"`
-	CreatedAt           *time.Time                 `json:"created_at"`
-	UpdatedAt           *time.Time                 `json:"updated_at"`
-	
+	ID                  int        `json:"id"`
+	UserID              int        `json:"user_id"`
+	AppointmentType     string     `json:"appointment_type"`
+	AppointmentDatetime *time.Time `json:"appointment_datetime"`
+	Status              string     `json:"status"`
+	CreatedAt           *time.Time `json:"created_at"`
+	UpdatedAt           *time.Time `json:"updated_at"`
+
 	// User details
-	User                *UserInfo                  `json:"user"`
-	
+	User *UserInfo `json:"user"`
+
 	// Appointment specific details
-	DoctorDetails       *DoctorAppointmentDetails  `json:"doctor_details,omitempty"`
-	LabTestDetails      *LabTestAppointmentDetails `json:"lab_test_details,omitempty"`
-	
+	DoctorDetails  *DoctorAppointmentDetails  `json:"doctor_details,omitempty"`
+	LabTestDetails *LabTestAppointmentDetails `json:"lab_test_details,omitempty"`
+	IVFDetails     *IVFAppointmentDetails     `json:"ivf_details,omitempty"`
+
 	// Related information
-	Hospital            *HospitalInfo              `json:"hospital,omitempty"`
-	TestType            *TestTypeInfo              `json:"test_type,omitempty"`
-	Doctor              *DoctorInfo                `json:"doctor,omitempty"`
-	
+	Hospital *HospitalInfo `json:"hospital,omitempty"`
+	TestType *TestTypeInfo `json:"test_type,omitempty"`
+	Doctor   *DoctorInfo   `json:"doctor,omitempty"`
+
 	// Status and actions
-	StatusHistory       []AppointmentStatusLog     `json:"status_history"`
-	RescheduleOffers    []RescheduleOffer          `json:"reschedule_offers"`
-	NextActions         []string                   `json:"next_actions"`
+	StatusHistory      []AppointmentStatusLog `json:"status_history"`
+	RescheduleOffers   []RescheduleOffer      `json:"reschedule_offers"`
+	RescheduleRequests []RescheduleRequest    `json:"reschedule_requests"`
+	NextActions        []string               `json:"next_actions"`
 }
 
 type UserInfo struct {
@@ -281,12 +493,12 @@ type DoctorInfo struct {
 	Phone          string `json:"phone"`
 }
 
-// User response structs  
+// User response structs
 type UserAppointmentResponse struct {
-	Appointment   AppointmentDetails      `json:"appointment"`
-	StatusHistory []AppointmentStatusLog  `json:"status_history"`
-	NextActions   []string                `json:"next_actions"`
-	EstimatedTime *time.Time              `json:"estimated_time,omitempty"`
+	Appointment   AppointmentDetails     `json:"appointment"`
+	StatusHistory []AppointmentStatusLog `json:"status_history"`
+	NextActions   []string               `json:"next_actions"`
+	EstimatedTime *time.Time             `json:"estimated_time,omitempty"`
 }
 
 // Enhanced detailed response for get by ID
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var allStatuses = []AppointmentStatus{
+	StatusPending,
+	StatusConfirmed,
+	StatusScheduled,
+	StatusRescheduleOffered,
+	StatusRescheduleAccepted,
+	StatusRescheduleRequested,
+	StatusInProgress,
+	StatusCompleted,
+	StatusCanceled,
+	StatusRejected,
+	StatusNoShow,
+}
+
+func TestCanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to AppointmentStatus
+		want     bool
+	}{
+		{StatusPending, StatusConfirmed, true},
+		{StatusPending, StatusRejected, true},
+		{StatusPending, StatusInProgress, false},
+		{StatusConfirmed, StatusInProgress, true},
+		{StatusConfirmed, StatusPending, false},
+		{StatusRescheduleOffered, StatusRescheduleOffered, true},
+		{StatusRescheduleOffered, StatusPending, true},
+		{StatusRescheduleRequested, StatusConfirmed, true},
+		{StatusRescheduleRequested, StatusInProgress, false},
+		{StatusInProgress, StatusCompleted, true},
+		{StatusInProgress, StatusCanceled, false},
+		{StatusCompleted, StatusCanceled, false},
+		{StatusCanceled, StatusPending, false},
+		{StatusNoShow, StatusConfirmed, false},
+		{"unknown", StatusConfirmed, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%s -> %s: got %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestTransitionTableCoversEveryStatus(t *testing.T) {
+	for _, status := range allStatuses {
+		next, ok := appointmentTransitions[status]
+		if !ok {
+			t.Errorf("%s has no entry in the transition table", status)
+		}
+		for _, to := range next {
+			if !slices.Contains(allStatuses, to) {
+				t.Errorf("%s -> %s leads to an unknown status", status, to)
+			}
+		}
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	terminal := []AppointmentStatus{StatusCompleted, StatusCanceled, StatusRejected, StatusNoShow}
+	for _, status := range allStatuses {
+		if got, want := status.IsTerminal(), slices.Contains(terminal, status); got != want {
+			t.Errorf("%s: IsTerminal() = %v, want %v", status, got, want)
+		}
+	}
+	if AppointmentStatus("unknown").IsTerminal() {
+		t.Error("an unknown status must not be terminal")
+	}
+}
+
+func TestNextStatusesReturnsCopy(t *testing.T) {
+	next := StatusPending.NextStatuses()
+	next[0] = StatusNoShow
+	if StatusPending.NextStatuses()[0] == StatusNoShow {
+		t.Fatal("changing the returned slice changed the transition table")
+	}
+}
+
+func TestValidateTransition(t *testing.T) {
+	if err := ValidateTransition(StatusPending, StatusConfirmed); err != nil {
+		t.Fatalf("pending -> confirmed: unexpected error %v", err)
+	}
+
+	tests := []struct {
+		from, to AppointmentStatus
+		message  string
+	}{
+		{StatusPending, StatusCompleted, "allowed next states: confirmed, rejected"},
+		{StatusCanceled, StatusConfirmed, "canceled is a final state"},
+	}
+	for _, tt := range tests {
+		err := ValidateTransition(tt.from, tt.to)
+		var transitionErr *TransitionError
+		if !errors.As(err, &transitionErr) {
+			t.Fatalf("%s -> %s: got %v, want a *TransitionError", tt.from, tt.to, err)
+		}
+		if transitionErr.From != tt.from || transitionErr.To != tt.to {
+			t.Errorf("%s -> %s: error records %s -> %s", tt.from, tt.to, transitionErr.From, transitionErr.To)
+		}
+		if !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s -> %s: message %q does not contain %q", tt.from, tt.to, err.Error(), tt.message)
+		}
+	}
+}
+
+func TestActionAvailableFrom(t *testing.T) {
+	actions := map[string]AppointmentAction{}
+	for _, action := range AdminAppointmentActions {
+		actions[action.Name] = action
+	}
+
+	tests := []struct {
+		action string
+		status AppointmentStatus
+		want   bool
+	}{
+		{"confirm", StatusPending, true},
+		{"confirm", StatusCompleted, false},
+		{"reschedule", StatusPending, true},
+		{"reschedule", StatusRescheduleOffered, false},
+		{"offer_new_reschedule", StatusRescheduleOffered, true},
+		{"offer_new_reschedule", StatusPending, false},
+		{"approve_reschedule_request", StatusRescheduleRequested, true},
+		{"approve_reschedule_request", StatusPending, false},
+	}
+	for _, tt := range tests {
+		if got := actions[tt.action].AvailableFrom(tt.status); got != tt.want {
+			t.Errorf("%s from %s: got %v, want %v", tt.action, tt.status, got, tt.want)
+		}
+	}
+}
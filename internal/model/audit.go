@@ -0,0 +1,20 @@
+package model
+
+// Audit actions
+const (
+	AuditInviteCreated    = "admin_invite.created"
+	AuditInviteAccepted   = "admin_invite.accepted"
+	AuditAdminBootstrap   = "admin.bootstrapped"
+	AuditUserRoleAssigned = "user.role_assigned"
+)
+
+// AuditEntry is one row of the audit log. ActorID is nil for actions run
+// outside an authenticated request, such as the bootstrap command.
+type AuditEntry struct {
+	ActorID    *int
+	Action     string
+	TargetType string
+	TargetID   string
+	Metadata   map[string]interface{}
+	IPAddress  string
+}
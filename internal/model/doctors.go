@@ -10,3 +10,8 @@ type Doctor struct {
 	AvailableFrom  string `json:"available_from" db:"available_from"`
 	AvailableTo    string `json:"available_to" db:"available_to"`
 }
+
+type DoctorFilter struct {
+	HospitalID     *int   `json:"hospital_id,omitempty"`
+	Specialization string `json:"specialization,omitempty"`
+}
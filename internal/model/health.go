@@ -0,0 +1,20 @@
+package model
+
+// Component health states
+const (
+	HealthUp       = "up"
+	HealthDown     = "down"
+	HealthDraining = "draining"
+)
+
+type ComponentHealth struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// HealthReport is the readiness of the API and each dependency it checked.
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInviteInvalid    = errors.New("invite is invalid or has already been used")
+	ErrInviteExpired    = errors.New("invite has expired")
+	ErrSuperAdminExists = errors.New("a super admin account already exists")
+)
+
+type AdminInvite struct {
+	ID         int       `json:"id" db:"id"`
+	Email      string    `json:"email" db:"email"`
+	Role       string    `json:"role" db:"role"`
+	HospitalID *int      `json:"hospital_id,omitempty" db:"hospital_id"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+}
+
+type CreateInviteRequest struct {
+	Email      string `json:"email" validate:"required,email"`
+	Role       string `json:"role"`
+	HospitalID *int   `json:"hospital_id,omitempty"`
+	InvitedBy  int    `json:"-"`
+	IPAddress  string `json:"-"`
+}
+
+type AcceptInviteRequest struct {
+	Token       string `json:"token" validate:"required"`
+	FirstName   string `json:"first_name" validate:"required,max=100"`
+	LastName    string `json:"last_name" validate:"required,max=100"`
+	Password    string `json:"password" validate:"required,min=8"`
+	DateOfBirth string `json:"date_of_birth" validate:"required,date"`
+	Sex         string `json:"sex" validate:"required,oneof=Male Female Other"`
+	IPAddress   string `json:"-"`
+}
+
+type BootstrapAdminRequest struct {
+	Email       string
+	FirstName   string
+	LastName    string
+	Password    string
+	DateOfBirth string
+	Sex         string
+}
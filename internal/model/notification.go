@@ -0,0 +1,31 @@
+package model
+
+// Push platforms accepted when registering a device
+const (
+	PushPlatformIOS     = "ios"
+	PushPlatformAndroid = "android"
+	PushPlatformWeb     = "web"
+)
+
+// NotificationPreferences are the channels a user receives appointment
+// updates on. SMS needs a phone number on the profile and push needs at least
+// one registered device.
+type NotificationPreferences struct {
+	Email bool    `json:"email" db:"notify_email"`
+	SMS   bool    `json:"sms" db:"notify_sms"`
+	Push  bool    `json:"push" db:"notify_push"`
+	Phone *string `json:"phone,omitempty" db:"phone"`
+}
+
+// UpdateNotificationPreferencesReq only changes the fields that are set.
+type UpdateNotificationPreferencesReq struct {
+	Email *bool   `json:"email,omitempty"`
+	SMS   *bool   `json:"sms,omitempty"`
+	Push  *bool   `json:"push,omitempty"`
+	Phone *string `json:"phone,omitempty" validate:"omitempty,e164"`
+}
+
+type PushTokenReq struct {
+	Token    string `json:"token" validate:"required,max=512"`
+	Platform string `json:"platform" validate:"required,oneof=ios android web"`
+}
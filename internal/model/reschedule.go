@@ -0,0 +1,70 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// Reschedule offer statuses. An admin may propose several slots at once; when
+// the patient accepts one the rest are superseded, and offers left unanswered
+// past their deadline expire.
+const (
+	OfferPending    = "pending"
+	OfferAccepted   = "accepted"
+	OfferRejected   = "rejected"
+	OfferExpired    = "expired"
+	OfferSuperseded = "superseded"
+)
+
+// MaxRescheduleSlots caps how many alternative slots one offer can propose
+const MaxRescheduleSlots = 5
+
+var (
+	ErrOfferUnavailable = errors.New("reschedule offer is no longer available")
+	ErrOfferExpired     = errors.New("reschedule offer has expired")
+)
+
+// RescheduleSlot is one alternative date and time proposed to the patient
+type RescheduleSlot struct {
+	Date string `json:"date" validate:"required,date"`
+	Time string `json:"time" validate:"required"`
+}
+
+// Reschedule request statuses. A patient's request is withdrawn when they
+// cancel, superseded by a newer request or by approving another option, and
+// declined when an admin answers in any other way. Requests nobody answered
+// before their times passed expire.
+const (
+	RequestPending    = "pending"
+	RequestApproved   = "approved"
+	RequestDeclined   = "declined"
+	RequestWithdrawn  = "withdrawn"
+	RequestSuperseded = "superseded"
+	RequestExpired    = "expired"
+)
+
+var ErrRequestUnavailable = errors.New("reschedule request is no longer available")
+
+// RescheduleRequest is one new time proposed by the patient
+type RescheduleRequest struct {
+	ID                int        `json:"id" db:"id"`
+	AppointmentID     int        `json:"appointment_id" db:"appointment_id"`
+	RequestedByUserID int        `json:"requested_by_user_id" db:"requested_by_user_id"`
+	ProposedDate      string     `json:"proposed_date" db:"proposed_date"`
+	ProposedTime      string     `json:"proposed_time" db:"proposed_time"`
+	Reason            string     `json:"reason" db:"reason"`
+	Status            string     `json:"status" db:"status"`
+	ReviewedByUserID  *int       `json:"reviewed_by_user_id,omitempty" db:"reviewed_by_user_id"`
+	CreatedAt         *time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type RescheduleRequestReq struct {
+	Options []RescheduleSlot `json:"options" validate:"required,max=5"`
+	Reason  string           `json:"reason" validate:"max=500"`
+}
+
+type ApproveRescheduleRequestReq struct {
+	RequestID int     `json:"request_id" validate:"required"`
+	Notes     *string `json:"notes,omitempty"`
+}
@@ -0,0 +1,51 @@
+package model
+
+// Role names seeded by the migrations
+const (
+	RoleUser          = "user"
+	RoleAdmin         = "admin"
+	RoleDoctor        = "doctor"
+	RoleSuperAdmin    = "super_admin"
+	RoleHospitalStaff = "hospital_staff"
+	RoleLabTechnician = "lab_technician"
+)
+
+// Permissions checked by RequirePermission
+const (
+	PermAppointmentsRead         = "appointments:read"
+	PermAppointmentsConfirm      = "appointments:confirm"
+	PermAppointmentsReject       = "appointments:reject"
+	PermAppointmentsReschedule   = "appointments:reschedule"
+	PermAppointmentsCancel       = "appointments:cancel"
+	PermAppointmentsUpdateStatus = "appointments:update_status"
+	PermAppointmentsNotes        = "appointments:notes"
+	PermHospitalsWrite           = "hospitals:write"
+	PermLabTestsWrite            = "lab-tests:write"
+	PermLabTestsPrice            = "lab-tests:price"
+	PermDoctorsWrite             = "doctors:write"
+	PermSchedulesWrite           = "schedules:write"
+	PermRolesAssign              = "roles:assign"
+	PermNotificationsManage      = "notifications:manage"
+)
+
+// PermissionSet holds the permissions granted to the signed-in user.
+type PermissionSet map[string]bool
+
+func (p PermissionSet) Has(permission string) bool {
+	return p[permission]
+}
+
+type Role struct {
+	ID             int      `json:"id" db:"id"`
+	Name           string   `json:"name" db:"name"`
+	Description    *string  `json:"description,omitempty" db:"description"`
+	HospitalScoped bool     `json:"hospital_scoped" db:"hospital_scoped"`
+	Permissions    []string `json:"permissions" db:"-"`
+}
+
+type AssignRoleRequest struct {
+	UserID     int    `json:"-"`
+	IPAddress  string `json:"-"`
+	Role       string `json:"role" validate:"required"`
+	HospitalID *int   `json:"hospital_id,omitempty"`
+}
@@ -0,0 +1,22 @@
+package model
+
+// SearchHighlight is a field of an appointment that matched a search, with
+// the matching terms wrapped in <mark> tags. The rest of the snippet is HTML
+// escaped, so clients can render it as is.
+type SearchHighlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+// AppointmentSearchText is the text an admin search matches an appointment
+// against.
+type AppointmentSearchText struct {
+	AppointmentID int     `db:"id"`
+	FirstName     string  `db:"first_name"`
+	LastName      string  `db:"last_name"`
+	Email         string  `db:"email"`
+	HospitalName  *string `db:"hospital_name"`
+	LabTestName   *string `db:"lab_test_name"`
+	AdminNotes    *string `db:"admin_notes"`
+	UserNotes     *string `db:"user_notes"`
+}
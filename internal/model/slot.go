@@ -0,0 +1,60 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+type ProviderType string
+
+const (
+	ProviderDoctor      ProviderType = "doctor"
+	ProviderHospitalLab ProviderType = "hospital_lab"
+	ProviderIVFClinic   ProviderType = "ivf_clinic"
+)
+
+// IVFClinicID is the provider ID of the IVF clinic. There is one clinic, so
+// its schedule is managed under /admin/schedules/ivf_clinic/1.
+const IVFClinicID = 1
+
+// Defaults used for doctors who have no explicit schedule. Their availability
+// window from the doctors table is split into slots of this length.
+const (
+	DefaultSlotMinutes  = 30
+	DefaultSlotCapacity = 1
+)
+
+var (
+	ErrSlotUnavailable = errors.New("the selected time is not a bookable slot")
+	ErrSlotFull        = errors.New("the selected slot is fully booked")
+)
+
+// SlotProvider identifies whose capacity an appointment consumes.
+type SlotProvider struct {
+	Type ProviderType `json:"provider_type"`
+	ID   int          `json:"provider_id"`
+}
+
+type ProviderSchedule struct {
+	ID           int          `json:"id" db:"id"`
+	ProviderType ProviderType `json:"provider_type" db:"provider_type"`
+	ProviderID   int          `json:"provider_id" db:"provider_id"`
+	Weekday      int          `json:"weekday" db:"weekday"`
+	StartTime    string       `json:"start_time" db:"start_time"`
+	EndTime      string       `json:"end_time" db:"end_time"`
+	SlotMinutes  int          `json:"slot_minutes" db:"slot_minutes"`
+	Capacity     int          `json:"capacity" db:"capacity"`
+}
+
+type Slot struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Capacity  int       `json:"capacity"`
+	Available int       `json:"available"`
+}
+
+type SlotQuery struct {
+	Provider SlotProvider
+	From     time.Time
+	To       time.Time
+}
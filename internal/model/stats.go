@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// StatsFilter selects the appointments counted by the admin statistics.
+// Appointments are counted by the day they were booked, from DateFrom to
+// DateTo inclusive.
+type StatsFilter struct {
+	DateFrom   string
+	DateTo     string
+	HospitalID *int
+}
+
+// AdminStats is the admin dashboard overview for a date range.
+type AdminStats struct {
+	DateFrom             string          `json:"date_from"`
+	DateTo               string          `json:"date_to"`
+	Total                int             `json:"total"`
+	ByStatus             map[string]int  `json:"by_status"`
+	ByType               map[string]int  `json:"by_type"`
+	DailyBookings        []DailyCount    `json:"daily_bookings"`
+	ConfirmationLeadTime LeadTime        `json:"confirmation_lead_time"`
+	Hospitals            []HospitalRates `json:"hospitals"`
+	TopLabTestsByVolume  []LabTestVolume `json:"top_lab_tests_by_volume"`
+	TopLabTestsByRevenue []LabTestVolume `json:"top_lab_tests_by_revenue"`
+	GeneratedAt          time.Time       `json:"generated_at"`
+}
+
+type DailyCount struct {
+	Date  string `json:"date" db:"day"`
+	Count int    `json:"count" db:"count"`
+}
+
+// LeadTime summarises how long appointments waited between booking and their
+// first confirmation, in minutes.
+type LeadTime struct {
+	Confirmed      int     `json:"confirmed"`
+	AverageMinutes float64 `json:"average_minutes"`
+	MedianMinutes  float64 `json:"median_minutes"`
+	P90Minutes     float64 `json:"p90_minutes"`
+}
+
+// HospitalRates are the no-show and cancellation rates of a hospital, as a
+// fraction of the appointments booked there.
+type HospitalRates struct {
+	HospitalID       int     `json:"hospital_id" db:"hospital_id"`
+	Name             string  `json:"name" db:"name"`
+	Total            int     `json:"total" db:"total"`
+	NoShows          int     `json:"no_shows" db:"no_shows"`
+	Cancellations    int     `json:"cancellations" db:"cancellations"`
+	NoShowRate       float64 `json:"no_show_rate" db:"-"`
+	CancellationRate float64 `json:"cancellation_rate" db:"-"`
+}
+
+// LabTestVolume is how often a lab test was booked and the revenue from
+// those bookings at the booked hospital's price. Canceled, rejected and
+// missed appointments earn nothing.
+type LabTestVolume struct {
+	LabTestID    int     `json:"lab_test_id" db:"lab_test_id"`
+	Name         string  `json:"name" db:"name"`
+	Appointments int     `json:"appointments" db:"appointments"`
+	Revenue      float64 `json:"revenue" db:"revenue"`
+}
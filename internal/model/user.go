@@ -1,6 +1,7 @@
 package model
 
 import (
+	"errors"
 	"time"
 )
 
@@ -37,6 +38,8 @@ type User struct {
 	Password                      string     `json:"-"`
 	RoleID                        int        `json:"roleId"`
 	Role                          string     `json:"role"`
+	HospitalID                    *int       `json:"hospitalId,omitempty"`
+	HospitalScoped                bool       `json:"-"`
 	IsActive                      bool       `json:"isActive"`
 	LastLogin                     *time.Time `json:"lastLogin,omitempty"`
 	RefreshToken                  *string    `json:"-"`
@@ -48,19 +51,33 @@ type User struct {
 	UpdatedAt                     time.Time  `json:"updatedAt"`
 }
 type UserRequest struct {
-	FirstName                    string    `json:"first_name"`
-	LastName                     string    `json:"last_name"`
-	Email                        string    `json:"email"`
-	Password                     string    `json:"password"`
-	DateOfBirth                  string    `json:"date_of_birth"`
-	Sex                          string    `json:"sex"`
+	FirstName                    string    `json:"first_name" validate:"required,max=100"`
+	LastName                     string    `json:"last_name" validate:"required,max=100"`
+	Email                        string    `json:"email" validate:"required,email"`
+	Password                     string    `json:"password" validate:"required,min=8"`
+	DateOfBirth                  string    `json:"date_of_birth" validate:"required,date"`
+	Sex                          string    `json:"sex" validate:"required,oneof=Male Female Other"`
 	EmailVerificationCode        string    `json:"-"`
 	EmailVerificationCodeExpires time.Time `json:"-"`
 }
 
+var (
+	ErrVerificationCodeInvalid = errors.New("verification code is invalid")
+	ErrVerificationCodeExpired = errors.New("verification code has expired")
+	ErrVerificationLocked      = errors.New("too many verification attempts")
+	ErrEmailAlreadyVerified    = errors.New("email already verified")
+	ErrVerificationResendLimit = errors.New("verification code resent too often")
+
+	ErrSessionRevoked     = errors.New("session has been revoked")
+	ErrSessionExpired     = errors.New("session has expired")
+	ErrRefreshTokenReused = errors.New("refresh token was already used")
+)
+
 type UserLoginReq struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email     string `json:"email" validate:"required,email"`
+	Password  string `json:"password" validate:"required"`
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
 }
 
 type TokenInfo struct {
@@ -86,7 +103,21 @@ type LogotRequest struct {
 }
 
 type RefreshTokenReq struct {
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string `json:"refresh_token" validate:"required"`
+	UserAgent    string `json:"-"`
+	IPAddress    string `json:"-"`
+}
+
+// Session is a signed-in device. ID is the token family shared by every
+// refresh token issued to that device.
+type Session struct {
+	ID         string    `json:"id" db:"family_id"`
+	UserAgent  *string   `json:"user_agent,omitempty" db:"user_agent"`
+	IPAddress  *string   `json:"ip_address,omitempty" db:"ip_address"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	Current    bool      `json:"current" db:"-"`
 }
 
 type ResendVerificationReq struct {
@@ -100,6 +131,15 @@ type ChangePasswordReq struct {
 	NewPassword string `json:"new_password" validate:"required,min=8"`
 }
 
+type ForgotPasswordReq struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordReq struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
+}
+
 type EmailReq struct {
 	Email string `json:"email" validate:"required,email"`
 }
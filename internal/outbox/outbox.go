@@ -0,0 +1,160 @@
+// Package outbox stores notifications in the database alongside the change
+// that triggers them and delivers them in the background, so a notification is
+// never lost because SMTP was down or the process restarted.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/db"
+	"github.com/jmoiron/sqlx"
+)
+
+// Channels a notification can be delivered through
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelPush  = "push"
+)
+
+// Message statuses. Messages stay pending, with a growing delay between
+// attempts, until they are sent or run out of attempts and go dead.
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusDead    = "dead"
+)
+
+// ErrNotDead is returned by Replay for messages that are not dead-lettered.
+var ErrNotDead = errors.New("only dead notifications can be replayed")
+
+type Message struct {
+	ID            int64           `json:"id" db:"id"`
+	Channel       string          `json:"channel" db:"channel"`
+	Recipient     string          `json:"recipient" db:"recipient"`
+	Template      string          `json:"template" db:"template"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Status        string          `json:"status" db:"status"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	LastError     *string         `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	SentAt        *time.Time      `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
+
+// Enqueue writes a notification to the outbox. Pass the transaction of the
+// change being notified about so both commit or roll back together.
+func Enqueue(ctx context.Context, exec sqlx.ExecerContext, channel, recipient, template string, data map[string]interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO notification_outbox (channel, recipient, template, payload) VALUES (?, ?, ?, ?)`
+	_, err = exec.ExecContext(ctx, stmt, channel, recipient, template, payload)
+	return err
+}
+
+// Store reads and updates outbox messages.
+type Store struct {
+	DB *db.DB
+}
+
+func NewStore(database *db.DB) *Store {
+	return &Store{DB: database}
+}
+
+const messageColumns = `id, channel, recipient, template, payload, status, attempts, last_error, next_attempt_at, sent_at, created_at`
+
+// List returns the most recent messages in the given status, newest first.
+func (s *Store) List(ctx context.Context, status string, limit int) ([]Message, error) {
+	messages := []Message{}
+	stmt := `SELECT ` + messageColumns + ` FROM notification_outbox WHERE status = ? ORDER BY id DESC LIMIT ?`
+	err := s.DB.SelectContext(ctx, &messages, stmt, status, limit)
+	return messages, err
+}
+
+func (s *Store) Get(ctx context.Context, id int64) (Message, error) {
+	var message Message
+	err := s.DB.GetContext(ctx, &message, `SELECT `+messageColumns+` FROM notification_outbox WHERE id = ?`, id)
+	return message, err
+}
+
+// Replay puts a dead message back in the queue with a fresh set of attempts.
+func (s *Store) Replay(ctx context.Context, id int64) error {
+	message, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if message.Status != StatusDead {
+		return ErrNotDead
+	}
+
+	stmt := `UPDATE notification_outbox SET status = ?, attempts = 0, next_attempt_at = NOW() WHERE id = ? AND status = ?`
+	result, err := s.DB.ExecContext(ctx, stmt, StatusPending, id, StatusDead)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotDead
+	}
+	return nil
+}
+
+// claim picks up to limit due messages and pushes their next attempt out by
+// lease, so other workers skip them while they are being delivered. If this
+// worker dies mid-delivery the messages become due again once the lease ends.
+func (s *Store) claim(ctx context.Context, limit int, lease time.Duration) ([]Message, error) {
+	var messages []Message
+	err := s.DB.RunInTx(ctx, func(tx *sqlx.Tx) error {
+		stmt := `SELECT ` + messageColumns + ` FROM notification_outbox
+			WHERE status = ? AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED`
+		if err := tx.SelectContext(ctx, &messages, stmt, StatusPending, limit); err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		ids := make([]interface{}, 0, len(messages)+1)
+		ids = append(ids, int(lease.Seconds()))
+		for _, m := range messages {
+			ids = append(ids, m.ID)
+		}
+		stmt = `UPDATE notification_outbox SET next_attempt_at = DATE_ADD(NOW(), INTERVAL ? SECOND)
+			WHERE id IN (?` + strings.Repeat(",?", len(messages)-1) + `)`
+		_, err := tx.ExecContext(ctx, stmt, ids...)
+		return err
+	})
+	return messages, err
+}
+
+func (s *Store) markSent(ctx context.Context, id int64) error {
+	stmt := `UPDATE notification_outbox SET status = ?, attempts = attempts + 1, last_error = NULL, sent_at = NOW() WHERE id = ?`
+	_, err := s.DB.ExecContext(ctx, stmt, StatusSent, id)
+	return err
+}
+
+// markFailed records a failed attempt. The message is retried after retryIn,
+// or dead-lettered when dead is set.
+func (s *Store) markFailed(ctx context.Context, id int64, cause error, retryIn time.Duration, dead bool) error {
+	status := StatusPending
+	if dead {
+		status = StatusDead
+	}
+
+	stmt := `UPDATE notification_outbox
+		SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = DATE_ADD(NOW(), INTERVAL ? SECOND)
+		WHERE id = ?`
+	_, err := s.DB.ExecContext(ctx, stmt, status, cause.Error(), int(retryIn.Seconds()), id)
+	return err
+}
@@ -0,0 +1,131 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Notifier delivers a rendered notification through one channel. The SMTP
+// mailer implements it for email.
+type Notifier interface {
+	Notify(ctx context.Context, recipient, template string, data map[string]interface{}) error
+}
+
+const (
+	defaultBatchSize = 20
+	deliveryTimeout  = 30 * time.Second
+	// deliveryLease must outlast a full batch of deliveries, each bounded by
+	// deliveryTimeout, or another replica could claim and send them again.
+	// The margin covers recording each outcome.
+	deliveryLease = defaultBatchSize*deliveryTimeout + 2*time.Minute
+	baseBackoff   = 30 * time.Second
+	maxBackoff    = 6 * time.Hour
+)
+
+// Worker polls the outbox and hands due messages to the notifier for their channel.
+type Worker struct {
+	store        *Store
+	notifiers    map[string]Notifier
+	pollInterval time.Duration
+	maxAttempts  int
+	logger       *slog.Logger
+}
+
+func NewWorker(store *Store, notifiers map[string]Notifier, pollInterval time.Duration, maxAttempts int, logger *slog.Logger) *Worker {
+	return &Worker{
+		store:        store,
+		notifiers:    notifiers,
+		pollInterval: pollInterval,
+		maxAttempts:  maxAttempts,
+		logger:       logger.With("component", "outbox"),
+	}
+}
+
+// Run delivers messages until ctx is canceled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		messages, err := w.store.claim(ctx, defaultBatchSize, deliveryLease)
+		if err != nil {
+			w.logger.Error("error claiming notifications", "error", err)
+			return
+		}
+		for _, m := range messages {
+			w.deliver(ctx, m)
+		}
+		if len(messages) < defaultBatchSize {
+			return
+		}
+	}
+}
+
+func (w *Worker) deliver(ctx context.Context, m Message) {
+	err := w.send(ctx, m)
+
+	// The outcome is recorded even if the worker is stopping, otherwise a sent
+	// message would be delivered again once its lease runs out
+	ctx = context.WithoutCancel(ctx)
+	if err == nil {
+		if err := w.store.markSent(ctx, m.ID); err != nil {
+			w.logger.Error("error marking notification as sent", "notification_id", m.ID, "error", err)
+		}
+		return
+	}
+
+	attempts := m.Attempts + 1
+	dead := attempts >= w.maxAttempts
+	if dead {
+		w.logger.Error("notification dead", "notification_id", m.ID, "channel", m.Channel, "attempts", attempts, "error", err)
+	} else {
+		w.logger.Warn("notification failed", "notification_id", m.ID, "channel", m.Channel, "attempts", attempts, "error", err)
+	}
+	if err := w.store.markFailed(ctx, m.ID, err, Backoff(attempts), dead); err != nil {
+		w.logger.Error("error recording notification failure", "notification_id", m.ID, "error", err)
+	}
+}
+
+func (w *Worker) send(ctx context.Context, m Message) error {
+	notifier, ok := w.notifiers[m.Channel]
+	if !ok {
+		return fmt.Errorf("no notifier for channel %q", m.Channel)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(m.Payload, &data); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+	return notifier.Notify(ctx, m.Recipient, m.Template, data)
+}
+
+// Backoff returns how long to wait before the next attempt after the given
+// number of failed attempts: 30s, 1m, 2m, ... capped at 6 hours.
+func Backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
@@ -0,0 +1,33 @@
+package outbox
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{6, 16 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, maxBackoff},
+		{1000, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDeliveryLeaseCoversBatch(t *testing.T) {
+	if deliveryLease <= defaultBatchSize*deliveryTimeout {
+		t.Fatalf("lease %v is shorter than a full batch of %d deliveries", deliveryLease, defaultBatchSize)
+	}
+}
@@ -0,0 +1,113 @@
+// Package scheduler runs periodic background jobs. Every replica runs the
+// scheduler, but a job only runs on the replica holding its lease in the
+// database, so work such as sending reminders is never done twice.
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwise1/your_care_api/internal/db"
+	"github.com/lucsky/cuid"
+)
+
+// leaseIntervals is how many run intervals a lease lasts. A replica that
+// stops renewing its lease loses it after this many missed runs.
+const leaseIntervals = 3
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	db     *db.DB
+	holder string
+	jobs   []job
+	logger *slog.Logger
+}
+
+func New(database *db.DB, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		db:     database,
+		holder: cuid.New(),
+		logger: logger.With("component", "scheduler"),
+	}
+}
+
+// Add registers a job to run every interval. name identifies its lease, so it
+// must be the same on every replica.
+func (s *Scheduler) Add(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+}
+
+// Run runs the registered jobs until ctx is canceled and waits for any job
+// still running to return.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, j := range s.jobs {
+		wg.Add(1)
+		go func(j job) {
+			defer wg.Done()
+			s.loop(ctx, j)
+		}(j)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, j job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, j)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, j job) {
+	held, err := s.acquire(ctx, j.name, leaseIntervals*j.interval)
+	if err != nil {
+		s.logger.Error("error acquiring lease", "job", j.name, "error", err)
+		return
+	}
+	if !held {
+		return
+	}
+
+	start := time.Now()
+	if err := j.run(ctx); err != nil {
+		s.logger.Error("job failed", "job", j.name, "error", err)
+		return
+	}
+	s.logger.Debug("job finished", "job", j.name, "duration_ms", time.Since(start).Milliseconds())
+}
+
+// acquire takes or renews the lease on name for ttl and reports whether this
+// scheduler holds it.
+func (s *Scheduler) acquire(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	_, err := s.db.ExecContext(ctx, `INSERT IGNORE INTO scheduler_leases (name, holder, expires_at) VALUES (?, '', NOW())`, name)
+	if err != nil {
+		return false, err
+	}
+
+	stmt := `UPDATE scheduler_leases SET holder = ?, expires_at = DATE_ADD(NOW(), INTERVAL ? SECOND)
+		WHERE name = ? AND (holder = ? OR expires_at <= NOW())`
+	if _, err := s.db.ExecContext(ctx, stmt, s.holder, int(ttl.Seconds()), name, s.holder); err != nil {
+		return false, err
+	}
+
+	var holder string
+	if err := s.db.GetContext(ctx, &holder, `SELECT holder FROM scheduler_leases WHERE name = ?`, name); err != nil {
+		return false, err
+	}
+	return holder == s.holder, nil
+}
@@ -7,9 +7,12 @@ import (
 	"math/rand"
 	"net/http"
 	"net/mail"
+	"strings"
 	"time"
 
+	"github.com/bwise1/your_care_api/util/apperr"
 	"github.com/bwise1/your_care_api/util/tracing"
+	"github.com/bwise1/your_care_api/util/validate"
 	"github.com/bwise1/your_care_api/util/values"
 	"github.com/pkg/errors"
 )
@@ -37,6 +40,10 @@ func StatusCode(status string) int {
 		return http.StatusUnauthorized
 	case values.ActiveLogin:
 		return http.StatusForbidden
+	case values.TooManyRequests:
+		return http.StatusTooManyRequests
+	case values.TooLarge:
+		return http.StatusRequestEntityTooLarge
 	default:
 		return http.StatusOK
 	}
@@ -45,21 +52,49 @@ func StatusCode(status string) int {
 const UserAuth = "user-auth"
 const AdminAuth = "admin-auth"
 
-// DecodeJSONBody ...
+// MaxBodyBytes is the largest JSON request body DecodeJSONBody accepts
+const MaxBodyBytes = 1 << 20
+
+// DecodeJSONBody decodes a single JSON object into target and validates it
+// against its validate tags. Unknown fields and bodies over MaxBodyBytes are
+// rejected. Validation problems are returned as an apperr.Error listing the
+// offending fields.
 func DecodeJSONBody(tc *tracing.Context, body io.ReadCloser, target interface{}) error {
+	if body == nil {
+		return fmt.Errorf("missing request body for request: %v", tc)
+	}
 	defer func() {
 		_ = body.Close()
 	}()
 
-	if body == nil {
-		return fmt.Errorf("missing request body for request: %v", tc)
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, body, MaxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&target); err != nil {
+		return decodeError(tc, err)
+	}
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		return apperr.New(apperr.CodeBadRequest, "request body must contain a single JSON object")
+	}
+
+	return validate.Struct(target)
+}
+
+func decodeError(tc *tracing.Context, err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		message := fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit)
+		return apperr.Wrap(err, apperr.CodeTooLarge, message)
 	}
 
-	if err := json.NewDecoder(body).Decode(&target); err != nil {
-		return errors.Wrapf(err, "Error parsing json body for request: %v", tc)
+	// encoding/json has no typed error for unknown fields
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		validation := apperr.Validation(apperr.FieldError{Field: strings.Trim(field, `"`), Message: "is not a recognised field"})
+		validation.Err = err
+		return validation
 	}
 
-	return nil
+	return errors.Wrapf(err, "Error parsing json body for request: %v", tc)
 }
 
 func ValidEmail(email string) error {
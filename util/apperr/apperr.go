@@ -0,0 +1,218 @@
+// Package apperr defines typed API errors. Each error carries a stable,
+// machine-readable code that clients can branch on, the response status it
+// maps to through util.StatusCode, and optional field-level details.
+package apperr
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/bwise1/your_care_api/util/values"
+	"github.com/go-sql-driver/mysql"
+)
+
+type Code string
+
+// Error codes are part of the API contract. Add new ones rather than renaming.
+const (
+	CodeInternal          Code = "internal_error"
+	CodeBadRequest        Code = "bad_request"
+	CodeValidation        Code = "validation_failed"
+	CodeNotFound          Code = "not_found"
+	CodeConflict          Code = "conflict"
+	CodeUnauthorized      Code = "unauthorized"
+	CodeTokenExpired      Code = "token_expired"
+	CodeForbidden         Code = "forbidden"
+	CodeUnprocessable     Code = "unprocessable"
+	CodeTooManyRequests   Code = "too_many_requests"
+	CodeInvalidTransition Code = "invalid_transition"
+	CodeTooLarge          Code = "payload_too_large"
+	CodeInUse             Code = "resource_in_use"
+
+	// Accounts and sign in
+	CodeAccountExists             Code = "account_exists"
+	CodeAccountNotFound           Code = "account_not_found"
+	CodeInvalidCredentials        Code = "invalid_credentials"
+	CodeIncorrectPassword         Code = "incorrect_password"
+	CodeInvalidRefreshToken       Code = "invalid_refresh_token"
+	CodeSessionExpired            Code = "session_expired"
+	CodeEmailAlreadyVerified      Code = "email_already_verified"
+	CodeVerificationCodeInvalid   Code = "verification_code_invalid"
+	CodeVerificationCodeExpired   Code = "verification_code_expired"
+	CodeVerificationLocked        Code = "verification_locked"
+	CodeVerificationResendLimited Code = "verification_resend_limited"
+	CodeResetTokenInvalid         Code = "reset_token_invalid"
+
+	// Appointments
+	CodeSlotFull           Code = "slot_full"
+	CodeSlotUnavailable    Code = "slot_unavailable"
+	CodeOfferUnavailable   Code = "reschedule_offer_unavailable"
+	CodeOfferExpired       Code = "reschedule_offer_expired"
+	CodeRequestUnavailable Code = "reschedule_request_unavailable"
+)
+
+// MySQL error numbers for a unique key violation and for deleting or updating
+// a row that other rows still reference
+const (
+	mysqlDuplicateEntry = 1062
+	mysqlRowReferenced  = 1451
+)
+
+var codeStatus = map[Code]string{
+	CodeInternal:          values.Error,
+	CodeBadRequest:        values.BadRequestBody,
+	CodeValidation:        values.BadRequestBody,
+	CodeNotFound:          values.NotFound,
+	CodeConflict:          values.Conflict,
+	CodeUnauthorized:      values.NotAuthorised,
+	CodeTokenExpired:      values.TokenExpired,
+	CodeForbidden:         values.NotAllowed,
+	CodeUnprocessable:     values.Unprocessable,
+	CodeTooManyRequests:   values.TooManyRequests,
+	CodeInvalidTransition: values.Conflict,
+	CodeTooLarge:          values.TooLarge,
+	CodeInUse:             values.Conflict,
+
+	CodeAccountExists:             values.Conflict,
+	CodeAccountNotFound:           values.NotFound,
+	CodeInvalidCredentials:        values.NotAuthorised,
+	CodeIncorrectPassword:         values.BadRequestBody,
+	CodeInvalidRefreshToken:       values.NotAuthorised,
+	CodeSessionExpired:            values.NotAuthorised,
+	CodeEmailAlreadyVerified:      values.BadRequestBody,
+	CodeVerificationCodeInvalid:   values.BadRequestBody,
+	CodeVerificationCodeExpired:   values.BadRequestBody,
+	CodeVerificationLocked:        values.TooManyRequests,
+	CodeVerificationResendLimited: values.TooManyRequests,
+	CodeResetTokenInvalid:         values.BadRequestBody,
+
+	CodeSlotFull:           values.Conflict,
+	CodeSlotUnavailable:    values.Unprocessable,
+	CodeOfferUnavailable:   values.Conflict,
+	CodeOfferExpired:       values.Conflict,
+	CodeRequestUnavailable: values.Conflict,
+}
+
+var statusCode = map[string]Code{
+	values.Error:           CodeInternal,
+	values.BadRequestBody:  CodeBadRequest,
+	values.NotFound:        CodeNotFound,
+	values.Conflict:        CodeConflict,
+	values.NotAuthorised:   CodeUnauthorized,
+	values.TokenExpired:    CodeTokenExpired,
+	values.NotAllowed:      CodeForbidden,
+	values.ActiveLogin:     CodeForbidden,
+	values.Unprocessable:   CodeUnprocessable,
+	values.TooManyRequests: CodeTooManyRequests,
+	values.TooLarge:        CodeTooLarge,
+}
+
+// FieldError describes a problem with one request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the response status for the error, as understood by
+// util.StatusCode.
+func (e *Error) Status() string {
+	if status, ok := codeStatus[e.Code]; ok {
+		return status
+	}
+	return values.Error
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap attaches a code and client-facing message to err. err itself is kept
+// for logging and is never sent to the client.
+func Wrap(err error, code Code, message string) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// Validation reports one or more invalid request fields.
+func Validation(fields ...FieldError) *Error {
+	return &Error{Code: CodeValidation, Message: "request validation failed", Fields: fields}
+}
+
+func NotFound(message string) *Error {
+	return New(CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(CodeConflict, message)
+}
+
+// IsRowReferenced reports whether err is MySQL refusing to delete or update a
+// row because a foreign key in another table still points at it.
+func IsRowReferenced(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlRowReferenced
+}
+
+// CodeForStatus returns the code matching a values status string, for
+// responses built from a status rather than a typed error.
+func CodeForStatus(status string) Code {
+	if code, ok := statusCode[status]; ok {
+		return code
+	}
+	return CodeInternal
+}
+
+// From classifies err. Typed errors are returned as they are. Missing rows
+// become not found, unique key violations and deletes of rows still
+// referenced elsewhere become conflicts, and malformed JSON fields become
+// validation errors. ok is false for anything else.
+func From(err error) (*Error, bool) {
+	if err == nil {
+		return nil, false
+	}
+
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return Wrap(err, CodeNotFound, "the requested resource was not found"), true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return Wrap(err, CodeConflict, "a record with the same details already exists"), true
+	}
+	if IsRowReferenced(err) {
+		return Wrap(err, CodeInUse, "the record is still in use and cannot be removed"), true
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		validation := Validation(FieldError{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()})
+		validation.Err = err
+		return validation, true
+	}
+
+	return nil, false
+}
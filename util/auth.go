@@ -1,6 +1,10 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -11,3 +15,19 @@ func HashPassword(passwordByte []byte) (string, error) {
 	}
 	return string(hash), nil
 }
+
+// RandomToken returns a hex encoded token made of n bytes from crypto/rand.
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the hex encoded SHA-256 of a token. Tokens that are sent
+// to users are only ever stored in this form.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
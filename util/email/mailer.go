@@ -2,22 +2,28 @@ package smtp
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"fmt"
 	"html/template"
-	"log"
+	"net"
 	"net/smtp"
+	"time"
 
 	"github.com/bwise1/your_care_api/util"
 	"github.com/bwise1/your_care_api/util/assets"
 )
 
+// defaultSendTimeout bounds a send whose context has no deadline
+const defaultSendTimeout = time.Minute
+
 type Mailer struct {
 	smtpHost     string
 	smtpPort     string
 	smtpUser     string
 	smtpPassword string
 	smtpFrom     string
+	onSend       func(err error)
 }
 
 func NewMailer(host string, port int, username, password, from string) *Mailer {
@@ -30,24 +36,80 @@ func NewMailer(host string, port int, username, password, from string) *Mailer {
 	}
 }
 
+// OnSend registers fn to be called with the result of every send, so delivery
+// can be counted without wrapping the mailer.
+func (m *Mailer) OnSend(fn func(err error)) {
+	m.onSend = fn
+}
+
 func (m *Mailer) Send(recipient string, data interface{}, patterns ...string) error {
-	log.Println("Here", m)
+	return m.SendContext(context.Background(), recipient, data, patterns...)
+}
+
+// SendContext is Send bounded by ctx. The SMTP conversation is abandoned
+// when ctx ends, and limited to defaultSendTimeout when ctx has no deadline,
+// so a hung server cannot block the caller.
+func (m *Mailer) SendContext(ctx context.Context, recipient string, data interface{}, patterns ...string) error {
+	err := m.send(ctx, recipient, data, patterns...)
+	if m.onSend != nil {
+		m.onSend(err)
+	}
+	return err
+}
+
+func (m *Mailer) send(ctx context.Context, recipient string, data interface{}, patterns ...string) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultSendTimeout)
+		defer cancel()
+	}
+
 	for i := range patterns {
 		patterns[i] = "emails/" + patterns[i]
 
 	}
 
 	// Create an email message
-	msg := composeEmail(recipient, m.smtpFrom, patterns, data)
+	msg, err := composeEmail(recipient, m.smtpFrom, patterns, data)
+	if err != nil {
+		return err
+	}
 
 	// Establish an SMTP connection and send the email
 	auth := smtp.PlainAuth("", m.smtpUser, m.smtpPassword, m.smtpHost)
-	err := sendEmail(m.smtpHost, m.smtpPort, auth, m.smtpFrom, recipient, msg)
-	log.Println(err)
-	return err
+	return sendEmail(ctx, m.smtpHost, m.smtpPort, auth, m.smtpFrom, recipient, msg)
+}
+
+// Notify sends the email template to recipient. It lets the mailer deliver
+// notifications queued in the outbox.
+func (m *Mailer) Notify(ctx context.Context, recipient, template string, data map[string]interface{}) error {
+	return m.SendContext(ctx, recipient, data, template)
+}
+
+// Check connects to the SMTP server and waits for its greeting, to confirm it
+// is reachable without sending anything.
+func (m *Mailer) Check(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.smtpHost, m.smtpPort))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	client, err := smtp.NewClient(conn, m.smtpHost)
+	if err != nil {
+		return err
+	}
+	return client.Quit()
 }
 
-func composeEmail(recipient, sender string, patterns []string, data interface{}) []byte {
+func composeEmail(recipient, sender string, patterns []string, data interface{}) ([]byte, error) {
 	// Create a new buffer to store the email message
 	var buf bytes.Buffer
 
@@ -56,9 +118,15 @@ func composeEmail(recipient, sender string, patterns []string, data interface{})
 	fmt.Fprintf(&buf, "From: %s\r\n", sender)
 
 	// Load and execute templates for subject, plain text, and HTML
-	subjectTemplate, plainBodyTemplate, htmlBodyTemplate := loadTemplates(patterns)
+	ts, err := template.New("").Funcs(util.TemplateFuncs).ParseFS(assets.EmbeddedFiles, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing email template: %w", err)
+	}
 
-	subject := executeTemplate(subjectTemplate, data)
+	subject, err := executeTemplate(ts.Lookup("subject"), data)
+	if err != nil {
+		return nil, err
+	}
 	fmt.Fprintf(&buf, "Subject: %s\r\n", subject)
 
 	// Start the MIME structure for a multipart/alternative email
@@ -67,14 +135,20 @@ func composeEmail(recipient, sender string, patterns []string, data interface{})
 	fmt.Fprintf(&buf, "\r\n")
 	fmt.Fprintf(&buf, "--boundary-string\r\n")
 
-	plainBody := executeTemplate(plainBodyTemplate, data)
+	plainBody, err := executeTemplate(ts.Lookup("plainBody"), data)
+	if err != nil {
+		return nil, err
+	}
 	fmt.Fprintf(&buf, "Content-Type: text/plain; charset=\"utf-8\"\r\n")
 	fmt.Fprintf(&buf, "Content-Transfer-Encoding: quoted-printable\r\n")
 	fmt.Fprintf(&buf, "\r\n")
 	fmt.Fprintf(&buf, "%s\r\n", plainBody)
 
-	if htmlBodyTemplate != nil {
-		htmlBody := executeTemplate(htmlBodyTemplate, data)
+	if htmlBodyTemplate := ts.Lookup("htmlBody"); htmlBodyTemplate != nil {
+		htmlBody, err := executeTemplate(htmlBodyTemplate, data)
+		if err != nil {
+			return nil, err
+		}
 		fmt.Fprintf(&buf, "--boundary-string\r\n")
 		fmt.Fprintf(&buf, "Content-Type: text/html; charset=\"utf-8\"\r\n")
 		fmt.Fprintf(&buf, "Content-Transfer-Encoding: quoted-printable\r\n")
@@ -86,51 +160,58 @@ func composeEmail(recipient, sender string, patterns []string, data interface{})
 	fmt.Fprintf(&buf, "--boundary-string--\r\n")
 
 	// Convert the buffer to a byte slice and return it
-	return buf.Bytes()
+	return buf.Bytes(), nil
 }
 
-func loadTemplates(patterns []string) (*template.Template, *template.Template, *template.Template) {
-	ts := template.New("")
-
-	subjectTemplate := loadTemplate(ts, patterns, "subject")
-	plainBodyTemplate := loadTemplate(ts, patterns, "plainBody")
-	htmlBodyTemplate := loadTemplate(ts, patterns, "htmlBody")
-
-	return subjectTemplate, plainBodyTemplate, htmlBodyTemplate
+func executeTemplate(tmpl *template.Template, data interface{}) (string, error) {
+	var buffer bytes.Buffer
+	if tmpl != nil {
+		if err := tmpl.Execute(&buffer, data); err != nil {
+			return "", fmt.Errorf("executing email template %s: %w", tmpl.Name(), err)
+		}
+	}
+	return buffer.String(), nil
 }
 
-func loadTemplate(ts *template.Template, patterns []string, name string) *template.Template {
-	ts, err := ts.Funcs(util.TemplateFuncs).ParseFS(assets.EmbeddedFiles, patterns...)
+// dialSMTP connects to the server with the connection bound to ctx: its
+// deadline becomes the connection deadline, and canceling ctx closes it. The
+// returned function must be called once the conversation is over.
+func dialSMTP(ctx context.Context, smtpHost, smtpPort string) (*smtp.Client, func(), error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(smtpHost, smtpPort))
 	if err != nil {
-		// Handle the error appropriately
-		fmt.Println("Error parsing template:", err)
-		return nil
+		return nil, nil, err
 	}
-
-	return ts.Lookup(name)
-}
-
-func executeTemplate(tmpl *template.Template, data interface{}) string {
-	var buffer bytes.Buffer
-	if tmpl != nil {
-		if err := tmpl.Execute(&buffer, data); err != nil {
-			// Handle the error appropriately
-			fmt.Println("Error executing template:", err)
-			return ""
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, nil, err
 		}
 	}
-	return buffer.String()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	client, err := smtp.NewClient(conn, smtpHost)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, nil, err
+	}
+	return client, func() {
+		stop()
+		conn.Close()
+	}, nil
 }
 
-func sendEmail(smtpHost, smtpPort string, auth smtp.Auth, from, recipient string, msg []byte) error {
+func sendEmail(ctx context.Context, smtpHost, smtpPort string, auth smtp.Auth, from, recipient string, msg []byte) error {
 	for i := 1; i <= 3; i++ {
 		tlsConfig := &tls.Config{
 			InsecureSkipVerify: true, // Set to true to skip server certificate verification (not recommended in production)
 		}
-		client, err := smtp.Dial(smtpHost + ":" + smtpPort)
+		client, done, err := dialSMTP(ctx, smtpHost, smtpPort)
 		if err != nil {
 			return err
 		}
+		defer done()
 
 		err = client.StartTLS(tlsConfig)
 		if err != nil {
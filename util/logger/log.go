@@ -1 +1,91 @@
+// Package logger builds the structured JSON logger shared by the API and its
+// background workers. Sensitive values are redacted before they are written,
+// so callers can log requests and errors without scrubbing them first.
 package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+type contextKey struct{}
+
+const redacted = "[REDACTED]"
+
+// Attributes whose name contains one of sensitiveKeys, or is one of
+// sensitiveNames, are never logged
+var (
+	sensitiveKeys  = []string{"password", "token", "secret", "authorization", "api_key"}
+	sensitiveNames = map[string]bool{"code": true, "verification_code": true, "otp": true}
+)
+
+var (
+	rgxEmail = regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+	rgxJWT   = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+)
+
+// New returns a JSON logger writing to stdout at the given level: debug, info,
+// warn or error. Unknown levels fall back to info.
+func New(level string) *slog.Logger {
+	return NewWithWriter(os.Stdout, level)
+}
+
+func NewWithWriter(w io.Writer, level string) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       parseLevel(level),
+		ReplaceAttr: redact,
+	}))
+}
+
+func parseLevel(level string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// WithContext returns a copy of ctx carrying l.
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger stored in ctx, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// Redact masks email addresses and bearer tokens in s.
+func Redact(s string) string {
+	s = rgxJWT.ReplaceAllString(s, redacted)
+	return rgxEmail.ReplaceAllString(s, "$1***@$2")
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	if sensitiveNames[key] {
+		return slog.String(a.Key, redacted)
+	}
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return a
+}
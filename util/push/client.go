@@ -0,0 +1,96 @@
+// Package push sends mobile push notifications through an HTTP push provider.
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bwise1/your_care_api/util"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Client posts notifications as JSON to the provider endpoint:
+//
+//	POST <endpoint>
+//	Authorization: Bearer <apiKey>
+//	{"token": "<device token>", "title": "...", "body": "...", "data": {...}}
+//
+// Any 2xx response counts as accepted.
+type Client struct {
+	endpoint   string
+	apiKey     string
+	httpClient *http.Client
+}
+
+type Notification struct {
+	Token string            `json:"token"`
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+func NewClient(endpoint, apiKey string) *Client {
+	return &Client{
+		endpoint:   endpoint,
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+func (c *Client) Send(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push provider returned %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// Notify renders the "pushTitle" and "pushBody" blocks of template and sends
+// them to the device token in recipient. It lets the client deliver
+// notifications queued in the outbox.
+func (c *Client) Notify(ctx context.Context, recipient, template string, data map[string]interface{}) error {
+	title, err := util.RenderText(template, "pushTitle", data)
+	if err != nil {
+		return err
+	}
+	body, err := util.RenderText(template, "pushBody", data)
+	if err != nil {
+		return err
+	}
+
+	notification := Notification{
+		Token: recipient,
+		Title: title,
+		Body:  body,
+	}
+	if id, ok := data["AppointmentID"]; ok {
+		notification.Data = map[string]string{"appointment_id": fmt.Sprint(id)}
+	}
+	return c.Send(ctx, notification)
+}
@@ -0,0 +1,81 @@
+// Package sms sends text messages through an HTTP SMS provider.
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bwise1/your_care_api/util"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Client posts messages as JSON to the provider endpoint:
+//
+//	POST <endpoint>
+//	Authorization: Bearer <apiKey>
+//	{"to": "+2348000000000", "from": "YourCare", "message": "..."}
+//
+// Any 2xx response counts as accepted.
+type Client struct {
+	endpoint   string
+	apiKey     string
+	sender     string
+	httpClient *http.Client
+}
+
+func NewClient(endpoint, apiKey, sender string) *Client {
+	return &Client{
+		endpoint:   endpoint,
+		apiKey:     apiKey,
+		sender:     sender,
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+func (c *Client) Send(ctx context.Context, to, message string) error {
+	body, err := json.Marshal(map[string]string{
+		"to":      to,
+		"from":    c.sender,
+		"message": message,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sms provider returned %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// Notify renders the "sms" block of template and texts it to recipient. It
+// lets the client deliver notifications queued in the outbox.
+func (c *Client) Notify(ctx context.Context, recipient, template string, data map[string]interface{}) error {
+	message, err := util.RenderText(template, "sms", data)
+	if err != nil {
+		return err
+	}
+	return c.Send(ctx, recipient, message)
+}
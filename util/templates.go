@@ -0,0 +1,31 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/bwise1/your_care_api/util/assets"
+)
+
+// RenderText executes one block of an email template as plain text, without
+// HTML escaping. SMS and push notifications reuse the email templates this way,
+// each channel defining its own blocks such as "sms" or "pushBody".
+func RenderText(file, block string, data interface{}) (string, error) {
+	ts, err := template.New("").Funcs(template.FuncMap(TemplateFuncs)).ParseFS(assets.EmbeddedFiles, "emails/"+file)
+	if err != nil {
+		return "", fmt.Errorf("parsing template %s: %w", file, err)
+	}
+
+	tmpl := ts.Lookup(block)
+	if tmpl == nil {
+		return "", fmt.Errorf("template %s has no %q block", file, block)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("executing template %s: %w", file, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -5,6 +5,7 @@ import (
 	"html/template"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -43,6 +44,17 @@ func formatTime(format string, t time.Time) string {
 	return t.Format(format)
 }
 
+// title turns an identifier such as "lab_test" into "Lab Test".
+func title(s string) string {
+	words := strings.Fields(strings.ReplaceAll(s, "_", " "))
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
 func slugify(s string) string {
 	var buf bytes.Buffer
 
@@ -62,6 +74,23 @@ func slugify(s string) string {
 	return buf.String()
 }
 
+// HumanDuration renders a duration the way it reads in an email, e.g. "1 hour"
+// or "10 minutes". Anything that is not a whole number of hours or days is
+// shown in minutes.
+func HumanDuration(d time.Duration) string {
+	unit, n := "minute", int(d/time.Minute)
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		unit, n = "day", int(d/(24*time.Hour))
+	case d >= time.Hour && d%time.Hour == 0:
+		unit, n = "hour", int(d/time.Hour)
+	}
+	if n == 1 {
+		return "1 " + unit
+	}
+	return strconv.Itoa(n) + " " + unit + "s"
+}
+
 var TemplateFuncs = template.FuncMap{
 	// Time functions
 	"now":        time.Now,
@@ -72,6 +101,7 @@ var TemplateFuncs = template.FuncMap{
 	// String functions
 	"uppercase": strings.ToUpper,
 	"lowercase": strings.ToLower,
+	"title":     title,
 	"slugify":   slugify,
 	"safeHTML":  safeHTML,
 
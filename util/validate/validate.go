@@ -0,0 +1,319 @@
+// Package validate checks request structs against their `validate` tags and
+// reports every invalid field at once, keyed by its JSON name.
+//
+// A tag is a comma separated list of rules, checked in order until one fails:
+//
+//	Email string `json:"email" validate:"required,email"`
+//
+// omitempty skips the remaining rules when the field is empty. Pointer fields
+// are checked through the pointer, and nested structs and slices of structs
+// are validated recursively. Further rules can be added with Register.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwise1/your_care_api/util/apperr"
+)
+
+// Layouts of the date and time strings accepted by the API. Dates and times
+// are wall-clock times in the hospitals' timezone, which is time.Local.
+const (
+	DateLayout     = "2006-01-02"
+	TimeLayout     = "15:04"
+	DateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// Rule checks a non-empty field value, already dereferenced if it was a
+// pointer, and returns a message describing the problem, or "" if it is valid.
+type Rule func(v reflect.Value, param string) string
+
+var (
+	mu    sync.RWMutex
+	rules = map[string]Rule{
+		"email":   email,
+		"min":     minimum,
+		"max":     maximum,
+		"oneof":   oneOf,
+		"e164":    e164,
+		"date":    layout(DateLayout, "must be a date in YYYY-MM-DD format"),
+		"time":    layout(TimeLayout, "must be a time in HH:MM format"),
+		"notpast": notPast,
+		"future":  future,
+	}
+)
+
+// rgxE164 matches phone numbers in E.164 format, which SMS providers expect
+var rgxE164 = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// Register adds or replaces a rule. It is meant to be called during start up.
+func Register(name string, rule Rule) {
+	mu.Lock()
+	defer mu.Unlock()
+	rules[name] = rule
+}
+
+// Struct validates v, a struct or a pointer to one. It returns an
+// apperr.Error listing every invalid field, or nil. Values that are not
+// structs are not checked.
+func Struct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []apperr.FieldError
+	walkStruct(rv, "", &fields)
+	if len(fields) > 0 {
+		return apperr.Validation(fields...)
+	}
+	return nil
+}
+
+func walkStruct(rv reflect.Value, prefix string, fields *[]apperr.FieldError) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+		path := prefix + name
+
+		value := rv.Field(i)
+		if tag := field.Tag.Get("validate"); tag != "" {
+			if message := checkField(value, tag); message != "" {
+				*fields = append(*fields, apperr.FieldError{Field: path, Message: message})
+				continue
+			}
+		}
+		walkValue(value, path, fields)
+	}
+}
+
+// walkValue descends into nested structs and slices of structs.
+func walkValue(value reflect.Value, path string, fields *[]apperr.FieldError) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if _, ok := value.Interface().(time.Time); ok {
+			return
+		}
+		walkStruct(value, path+".", fields)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			walkValue(value.Index(i), fmt.Sprintf("%s[%d]", path, i), fields)
+		}
+	}
+}
+
+func checkField(value reflect.Value, tag string) string {
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "":
+			continue
+		case "required":
+			if isEmpty(value) {
+				return "is required"
+			}
+			continue
+		case "omitempty":
+			if isEmpty(value) {
+				return ""
+			}
+			continue
+		}
+
+		// Rules other than required only apply to values that are present
+		if isEmpty(value) {
+			return ""
+		}
+		v := value
+		for v.Kind() == reflect.Pointer {
+			v = v.Elem()
+		}
+
+		mu.RLock()
+		check, ok := rules[name]
+		mu.RUnlock()
+		if !ok {
+			panic(fmt.Sprintf("validate: unknown rule %q", name))
+		}
+		if message := check(v, param); message != "" {
+			return message
+		}
+	}
+	return ""
+}
+
+// isEmpty reports whether value is nil, the zero value, blank text or an
+// empty collection.
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return value.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func email(v reflect.Value, _ string) string {
+	value := strings.TrimSpace(v.String())
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Address != value {
+		return "must be a valid email address"
+	}
+	return ""
+}
+
+func minimum(v reflect.Value, param string) string {
+	limit := mustFloat(param)
+	switch v.Kind() {
+	case reflect.String:
+		if float64(len([]rune(v.String()))) < limit {
+			return fmt.Sprintf("must be at least %s characters", param)
+		}
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if float64(v.Len()) < limit {
+			return fmt.Sprintf("must have at least %s items", param)
+		}
+	default:
+		if number, ok := toFloat(v); ok && number < limit {
+			return fmt.Sprintf("must be at least %s", param)
+		}
+	}
+	return ""
+}
+
+func maximum(v reflect.Value, param string) string {
+	limit := mustFloat(param)
+	switch v.Kind() {
+	case reflect.String:
+		if float64(len([]rune(v.String()))) > limit {
+			return fmt.Sprintf("must be at most %s characters", param)
+		}
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if float64(v.Len()) > limit {
+			return fmt.Sprintf("must have at most %s items", param)
+		}
+	default:
+		if number, ok := toFloat(v); ok && number > limit {
+			return fmt.Sprintf("must be at most %s", param)
+		}
+	}
+	return ""
+}
+
+func oneOf(v reflect.Value, param string) string {
+	options := strings.Fields(param)
+	value := fmt.Sprint(v.Interface())
+	for _, option := range options {
+		if value == option {
+			return ""
+		}
+	}
+	return "must be one of: " + strings.Join(options, ", ")
+}
+
+// e164 ignores spaces, which clients often use to group digits
+func e164(v reflect.Value, _ string) string {
+	if !rgxE164.MatchString(strings.ReplaceAll(v.String(), " ", "")) {
+		return "must be a phone number in international format, such as +2348012345678"
+	}
+	return ""
+}
+
+func layout(layout, message string) Rule {
+	return func(v reflect.Value, _ string) string {
+		if _, err := time.ParseInLocation(layout, v.String(), time.Local); err != nil {
+			return message
+		}
+		return ""
+	}
+}
+
+// notPast accepts a date of today or later, in the hospitals' timezone. It
+// only looks at the day, so a booking for today may still be at a time that
+// has passed; callers pairing the date with a time check the two together.
+func notPast(v reflect.Value, _ string) string {
+	day, err := time.ParseInLocation(DateLayout, v.String(), time.Local)
+	if err != nil {
+		return "must be a date in YYYY-MM-DD format"
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if day.Before(today) {
+		return "must not be in the past"
+	}
+	return ""
+}
+
+// future accepts a date and time after now, read in the hospitals' timezone.
+func future(v reflect.Value, _ string) string {
+	now := time.Now()
+	for _, l := range []string{DateTimeLayout, DateLayout + " " + TimeLayout} {
+		if moment, err := time.ParseInLocation(l, v.String(), time.Local); err == nil {
+			if !moment.After(now) {
+				return "must be in the future"
+			}
+			return ""
+		}
+	}
+	return "must be a date and time in YYYY-MM-DD HH:MM:SS format"
+}
+
+func toFloat(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// mustFloat parses a rule parameter. Parameters are fixed in struct tags, so
+// a bad one is a programming error.
+func mustFloat(param string) float64 {
+	f, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validate: invalid numeric parameter %q", param))
+	}
+	return f
+}
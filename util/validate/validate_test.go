@@ -0,0 +1,172 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwise1/your_care_api/util/apperr"
+)
+
+func TestRules(t *testing.T) {
+	now := time.Now()
+	today := now.Format(DateLayout)
+	yesterday := now.AddDate(0, 0, -1).Format(DateLayout)
+	nextHour := now.Add(time.Hour).Format(DateTimeLayout)
+	lastHour := now.Add(-time.Hour).Format(DateLayout + " " + TimeLayout)
+	tomorrow := now.AddDate(0, 0, 1).Format(DateLayout + " " + TimeLayout)
+	blank := "  "
+	name := "Jane"
+
+	tests := []struct {
+		name  string
+		value interface{}
+		tag   string
+		want  string
+	}{
+		{"required missing", "", "required", "is required"},
+		{"required blank", blank, "required", "is required"},
+		{"required nil pointer", (*string)(nil), "required", "is required"},
+		{"required present", "x", "required", ""},
+		{"omitempty skips rules", "", "omitempty,email", ""},
+		{"rules skip empty values", "", "email", ""},
+		{"pointer dereferenced", &name, "required,min=5", "must be at least 5 characters"},
+		{"email", "jane@example.com", "email", ""},
+		{"email with name", "Jane <jane@example.com>", "email", "must be a valid email address"},
+		{"email invalid", "jane", "email", "must be a valid email address"},
+		{"min runes", "ñé", "min=2", ""},
+		{"min string", "a", "min=2", "must be at least 2 characters"},
+		{"min number", -1, "min=1", "must be at least 1"},
+		{"zero number is empty", 0, "min=1", ""},
+		{"min slice", []int{1}, "min=2", "must have at least 2 items"},
+		{"max string", "abc", "max=2", "must be at most 2 characters"},
+		{"max number", 11.5, "max=10", "must be at most 10"},
+		{"max slice", []int{1, 2}, "max=2", ""},
+		{"oneof match", "male", "oneof=male female", ""},
+		{"oneof number", 2, "oneof=1 2", ""},
+		{"oneof miss", "other", "oneof=male female", "must be one of: male, female"},
+		{"e164", "+234 801 234 5678", "e164", ""},
+		{"e164 missing plus", "08012345678", "e164", "must be a phone number in international format, such as +2348012345678"},
+		{"date", "2024-10-17", "date", ""},
+		{"date invalid", "17/10/2024", "date", "must be a date in YYYY-MM-DD format"},
+		{"time", "14:30", "time", ""},
+		{"time invalid", "2pm", "time", "must be a time in HH:MM format"},
+		{"notpast today", today, "notpast", ""},
+		{"notpast yesterday", yesterday, "notpast", "must not be in the past"},
+		{"notpast invalid", "soon", "notpast", "must be a date in YYYY-MM-DD format"},
+		{"future next hour", nextHour, "future", ""},
+		{"future without seconds", tomorrow, "future", ""},
+		{"future last hour", lastHour, "future", "must be in the future"},
+		{"future date only", today, "future", "must be a date and time in YYYY-MM-DD HH:MM:SS format"},
+		{"first failure wins", "a", "min=2,email", "must be at least 2 characters"},
+	}
+	for _, tt := range tests {
+		if got := checkField(reflect.ValueOf(tt.value), tt.tag); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownRulePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for an unknown rule")
+		}
+	}()
+	checkField(reflect.ValueOf("x"), "nosuchrule")
+}
+
+type testItem struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type testAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type testRequest struct {
+	Email   string       `json:"email" validate:"required,email"`
+	Age     int          `json:"age" validate:"omitempty,min=18"`
+	Address *testAddress `json:"address"`
+	Items   []testItem   `json:"items" validate:"max=3"`
+	Created time.Time    `json:"created"`
+	NoTag   string
+	Skipped string `json:"-" validate:"required"`
+	hidden  string `validate:"required"`
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      interface{}
+		wantFields []string
+	}{
+		{
+			name:  "valid",
+			value: testRequest{Email: "jane@example.com", Address: &testAddress{City: "Lagos"}, Items: []testItem{{Name: "x"}}},
+		},
+		{
+			name:  "pointer to valid",
+			value: &testRequest{Email: "jane@example.com"},
+		},
+		{name: "nil pointer", value: (*testRequest)(nil)},
+		{name: "not a struct", value: "text"},
+		{
+			name:       "every invalid field reported",
+			value:      testRequest{Email: "jane", Age: 12},
+			wantFields: []string{"email", "age"},
+		},
+		{
+			name:       "nested fields use JSON paths",
+			value:      testRequest{Email: "jane@example.com", Address: &testAddress{}, Items: []testItem{{Name: "x"}, {}}},
+			wantFields: []string{"address.city", "items[1].name"},
+		},
+		{
+			name:       "failed slice rule skips its elements",
+			value:      testRequest{Email: "jane@example.com", Items: make([]testItem, 4)},
+			wantFields: []string{"items"},
+		},
+	}
+	for _, tt := range tests {
+		err := Struct(tt.value)
+		if tt.wantFields == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+
+		var appErr *apperr.Error
+		if !errors.As(err, &appErr) || appErr.Code != apperr.CodeValidation {
+			t.Errorf("%s: got %v, want a validation error", tt.name, err)
+			continue
+		}
+		var fields []string
+		for _, f := range appErr.Fields {
+			fields = append(fields, f.Field)
+		}
+		if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+			t.Errorf("%s: invalid fields %v, want %v", tt.name, fields, tt.wantFields)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("even", func(v reflect.Value, _ string) string {
+		if v.Int()%2 != 0 {
+			return "must be even"
+		}
+		return ""
+	})
+	defer func() {
+		mu.Lock()
+		delete(rules, "even")
+		mu.Unlock()
+	}()
+
+	if got := checkField(reflect.ValueOf(3), "even"); got != "must be even" {
+		t.Fatalf("got %q, want the registered rule's message", got)
+	}
+}
@@ -15,5 +15,7 @@ const Conflict = "conflict"
 const NotFound = "not-found"
 const NotAuthorised = "not-authorised"
 const TokenExpired = "token-expired"
+const TooManyRequests = "too-many-requests"
+const TooLarge = "too-large"
 
 const SystemErr = "Unable to complete this request. Please try again"